@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/capture"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+)
+
+// attachCapture returns the frontend wrapped to take screenshots and record videos with the hotkeys. The video
+// given with --record-video is recorded from the start, and the --screenshot is taken, on exit.
+func attachCapture(machine *goboy.Machine, f frontend.Frontend, logger log.Logger) frontend.Frontend {
+	session, err := capture.New(machine, capture.Options{
+		Dir:    *captureDir,
+		Scale:  *captureScale,
+		Format: *captureFormat,
+	}, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if len(*recordVideo) > 0 {
+		if err := session.StartRecording(*recordVideo); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	atExit = append(atExit, func() {
+		if err := session.StopRecording(); err != nil {
+			fmt.Printf("error saving video: %s\n", err)
+		}
+		if len(*screenshotPath) > 0 {
+			if err := session.Screenshot(*screenshotPath); err != nil {
+				fmt.Printf("error saving screenshot: %s\n", err)
+			}
+		}
+	})
+	return session.Wrap(f)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/disasm"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// runDisasm implements "goboy disasm rom.gb [--bank N] [--from 0x150] [--count N] [--sym rom.sym]". Bank 0 is
+// disassembled at 0x0000-0x3FFF and any other bank at 0x4000-0x7FFF.
+func runDisasm(args []string) {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	bank := flags.Int("bank", 0, "ROM bank to disassemble")
+	from := flags.String("from", "", "Address to start at (default start of the bank, 0x100 for bank 0)")
+	count := flags.Int("count", 32, "Number of instructions to disassemble, 0 for the whole bank")
+	symPath := flags.String("sym", "", "RGBDS symbol file (default the ROM path with a .sym extension, if it exists)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goboy disasm rom.gb [flags]")
+		flags.PrintDefaults()
+	}
+
+	// The ROM path is allowed before the flags, as in "goboy disasm rom.gb --bank 1"
+	var romPath string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		romPath, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if len(romPath) == 0 {
+		romPath = flags.Arg(0)
+	}
+	if len(romPath) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	rom, err := os.ReadFile(romPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if *bank < 0 || *bank >= disasm.RomBanks(rom) {
+		fmt.Printf("bank %d out of range, the ROM has %d banks\n", *bank, disasm.RomBanks(rom))
+		os.Exit(-1)
+	}
+
+	start, end := uint16(0x0000), disasm.RomxStart-1
+	if *bank > 0 {
+		start, end = disasm.RomxStart, disasm.RomxEnd
+	}
+
+	address := start
+	if *bank == 0 {
+		address = 0x0100
+	}
+	if len(*from) > 0 {
+		value, err := strconv.ParseUint(*from, 0, 16)
+		if err != nil || uint16(value) < start || uint16(value) > end {
+			fmt.Printf("invalid address %s for bank %d, expected %04X-%04X\n", *from, *bank, start, end)
+			os.Exit(-1)
+		}
+		address = uint16(value)
+	}
+
+	symbolTable, err := loadDisasmSymbols(romPath, *symPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	disassembler := disasm.New(symbolTable)
+	read := disasm.RomReader(rom, *bank)
+
+	for i := 0; *count == 0 || i < *count; i++ {
+		instruction := disassembler.Decode(read, address, *bank)
+		if label, ok := disassembler.Label(address, *bank); ok {
+			fmt.Printf("%s:\n", label)
+		}
+		fmt.Printf("%02X:%04X  %-9s %s\n", *bank, address, hexBytes(instruction.Bytes), instruction.Text)
+
+		next := uint32(address) + uint32(instruction.Length())
+		if next > uint32(end) {
+			break
+		}
+		address = uint16(next)
+	}
+}
+
+// loadDisasmSymbols loads the symbol file given by the user, or the one next to the ROM if there's any.
+func loadDisasmSymbols(romPath, symPath string) (*symbols.Table, error) {
+	if len(symPath) > 0 {
+		return symbols.Load(symPath)
+	}
+
+	return symbols.LoadForRom(romPath)
+}
+
+func hexBytes(bytes []byte) string {
+	fields := make([]string, len(bytes))
+	for i, b := range bytes {
+		fields[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/terminal"
+	"github.com/mikeletux/goboy/pkg/web"
+	"os"
+	"sort"
+	"strings"
+)
+
+// frontendConstructor builds a frontend for a machine.
+type frontendConstructor func(machine *goboy.Machine, logger log.Logger) (frontend.Frontend, error)
+
+// frontends are the frontends available in this build by name. The ones that need C libraries, like SDL, are
+// added by the files built with their build tag.
+var frontends = map[string]frontendConstructor{
+	"headless": func(machine *goboy.Machine, logger log.Logger) (frontend.Frontend, error) {
+		return frontend.NewHeadless(), nil
+	},
+	"terminal": func(machine *goboy.Machine, logger log.Logger) (frontend.Frontend, error) {
+		return terminal.New(os.Stdin, os.Stdout, *keyHold)
+	},
+	"web": func(machine *goboy.Machine, logger log.Logger) (frontend.Frontend, error) {
+		server, err := web.New(*listenAddress, machine.SampleRate())
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Serving GoBoy on http://%s\n", server.Addr())
+		return server, nil
+	},
+}
+
+// defaultFrontend is used when --frontend is not set.
+var defaultFrontend = "headless"
+
+// createFrontend returns the frontend selected with --frontend.
+func createFrontend(machine *goboy.Machine, logger log.Logger) frontend.Frontend {
+	name := *frontendName
+	if len(name) == 0 {
+		name = defaultFrontend
+	}
+
+	constructor, ok := frontends[name]
+	if !ok {
+		var names []string
+		for available := range frontends {
+			names = append(names, available)
+		}
+		sort.Strings(names)
+		fmt.Printf("frontend %q is not available, use one of: %s\n", name, strings.Join(names, ", "))
+		os.Exit(-1)
+	}
+
+	f, err := constructor(machine, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return f
+}
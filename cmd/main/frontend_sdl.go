@@ -0,0 +1,22 @@
+//go:build sdl
+
+package main
+
+import (
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/lcd"
+	"github.com/mikeletux/goboy/pkg/log"
+	"runtime"
+)
+
+func init() {
+	// SDL has to be used from the main thread
+	runtime.LockOSThread()
+
+	frontends["sdl"] = func(machine *goboy.Machine, logger log.Logger) (frontend.Frontend, error) {
+		_, width, height := machine.Screen()
+		return lcd.NewGameboyScreen(logger, machine.Bus(), width, height, machine.SampleRate())
+	}
+	defaultFrontend = "sdl"
+}
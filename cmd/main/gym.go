@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/gym"
+	"net"
+	"os"
+)
+
+// runGym implements "goboy gym [flags] rom.gb". It serves reinforcement learning environments running the ROM
+// over JSON over TCP, see pkg/gym for the protocol.
+func runGym(args []string) {
+	flags := flag.NewFlagSet("gym", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:7000", "Address to serve the environments on")
+	envs := flags.Int("envs", 1, "Number of independent environments")
+	ram := flags.String("ram", "", "RAM addresses to observe, e.g. C000-C00F,D000")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goboy gym [flags] rom.gb")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	rom, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	addresses, err := gym.ParseAddresses(*ram)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	batch, err := gym.NewBatch(rom, gym.Config{Ram: addresses}, *envs)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+	fmt.Printf("Serving %d environments on %s\n", batch.Len(), listener.Addr())
+
+	if err := gym.NewServer(batch).Serve(listener); err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+}
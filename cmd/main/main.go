@@ -4,18 +4,72 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/mikeletux/goboy"
 	"github.com/mikeletux/goboy/pkg/bus"
-	"github.com/mikeletux/goboy/pkg/cart"
 	"github.com/mikeletux/goboy/pkg/config"
 	"github.com/mikeletux/goboy/pkg/cpu"
-	"github.com/mikeletux/goboy/pkg/lcd"
+	"github.com/mikeletux/goboy/pkg/debugger"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/gdb"
+	"github.com/mikeletux/goboy/pkg/link"
 	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/printer"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"github.com/mikeletux/goboy/pkg/terminal"
+	"github.com/mikeletux/goboy/pkg/trace"
 	"os"
-	"sync"
-	"time"
+	"os/signal"
+	"syscall"
 )
 
+var (
+	configFilePath = flag.String("configFilePath", "", "Path to the GoBoy config path")
+	linkListen     = flag.String("link-listen", "", "Address to wait for a link cable peer on, e.g. :8765")
+	linkConnect    = flag.String("link-connect", "", "Address of a link cable peer to connect to, e.g. localhost:8765")
+	debug          = flag.Bool("debug", false, "Start paused in the interactive debugger")
+	gdbPort        = flag.Int("gdb-port", 0, "Expose the CPU through the GDB remote protocol on this localhost port")
+	serialStdout   = flag.Bool("serial-stdout", false, "Print the bytes sent through the serial port on stdout")
+	tracePath      = flag.String("trace", "", "Write an execution trace to this file")
+	traceFormat    = flag.String("trace-format", "doctor", "Execution trace format: doctor, bgb or json")
+	traceStart     = flag.Uint64("trace-start", 0, "Number of instructions to execute before tracing")
+	traceCount     = flag.Uint64("trace-count", 0, "Number of instructions to trace, 0 for no limit")
+	tracePC        = flag.String("trace-pc", "", "Only trace instructions in this PC range, e.g. 0150-01FF")
+	frontendName   = flag.String("frontend", "", "Frontend to use: sdl, terminal, web or headless. sdl is the default if GoBoy was built with it")
+	listenAddress  = flag.String("listen", "localhost:8080", "Address the web frontend listens on")
+	keyHold        = flag.Duration("key-hold", terminal.DefaultHoldDuration, "How long the terminal frontend holds a button after its key is pressed")
+	scriptPath     = flag.String("script", "", "Lua script to run alongside the game")
+	recordMovie    = flag.String("record-movie", "", "Record the input to this movie file")
+	playMovie      = flag.String("play-movie", "", "Play the input recorded in this movie file")
+	movieState     = flag.String("movie-state", "", "Save state to start recording the movie from, instead of power on")
+	movieReadWrite = flag.Bool("movie-read-write", false, "Record the played movie again from the frame where a button is pressed")
+	screenshotPath = flag.String("screenshot", "", "Save the screen as a PNG image to this file on exit")
+	recordVideo    = flag.String("record-video", "", "Record every frame to this animated .gif or .png (APNG) file")
+	captureDir     = flag.String("capture-dir", ".", "Folder where the screenshots and videos taken with the hotkeys are saved")
+	captureScale   = flag.Int("capture-scale", 1, "Times each pixel is repeated horizontally and vertically in screenshots and videos")
+	captureFormat  = flag.String("capture-format", "apng", "Format of the videos recorded with the hotkey: gif or apng")
+)
+
+// atExit holds the functions run by exit, like flushing the trace.
+var atExit []func()
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "disasm":
+			runDisasm(os.Args[2:])
+			return
+		case "tracediff":
+			runTraceDiff(os.Args[2:])
+			return
+		case "testrom":
+			runTestRom(os.Args[2:])
+			return
+		case "gym":
+			runGym(os.Args[2:])
+			return
+		}
+	}
+
 	configValues := configureEmulator()
 
 	// Build stdout logger
@@ -24,45 +78,143 @@ func main() {
 		panic(err)
 	}
 
-	// Build cartridge
-	cartridge, err := cart.NewCartridge(configValues.RomPath, logger)
+	// Build the Game Boy with the cartridge inserted
+	romData, err := os.ReadFile(configValues.RomPath)
+	if err != nil {
+		logger.Fatalf("error while loading ROM cartridges - %v", err)
+	}
+
+	machine, err := goboy.New(romData, goboy.Options{Logger: logger})
 	if err != nil {
 		logger.Fatal(err)
 	}
+	memoryBus := machine.Bus()
+	gbCpu := machine.CPU()
 
-	// Build memory bus
-	memoryBus := bus.NewBus(cartridge, logger)
+	// Load the RGBDS symbols next to the ROM, if any
+	symbolTable, err := symbols.LoadForRom(configValues.RomPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
-	// Build CPU
-	gbCpu := cpu.Init(memoryBus, logger)
+	// Build tracer if requested
+	if tracer := createTracer(memoryBus, logger); tracer != nil {
+		tracer.SetSymbols(symbolTable)
+		gbCpu.SetTracer(tracer)
+		atExit = append(atExit, func() {
+			if err := tracer.Close(); err != nil {
+				fmt.Printf("error writing trace: %s\n", err)
+			}
+		})
+	}
 
-	die := make(chan bool)
-	var wg sync.WaitGroup
+	if *serialStdout {
+		memoryBus.SetSerialOutput(os.Stdout)
+	}
 
-	wg.Add(1)
-	go func(die <-chan bool) {
-		defer wg.Done()
-		for {
-			select {
-			case <-die:
-				return
-			default:
-				gbCpu.Step()
-			}
-		}
-	}(die)
+	// Plug link cable or printer if requested
+	if cable := connectLinkCable(logger); cable != nil {
+		defer cable.Close()
+		memoryBus.SetSerialDevice(cable)
+	} else if configValues.PrinterEnable {
+		memoryBus.SetSerialDevice(printer.New(configValues.PrinterOutputPath, logger))
+	}
 
 	// Build UI
-	gbScreen := lcd.NewGameboyScreen(logger, memoryBus)
+	gbFrontend := createFrontend(machine, logger)
+	gbFrontend = attachCapture(machine, gbFrontend, logger)
+
+	switch {
+	case *debug && *gdbPort > 0:
+		fmt.Println("--debug and --gdb-port cannot be used at the same time")
+		os.Exit(-1)
+	case (len(*scriptPath) > 0 || len(*recordMovie) > 0 || len(*playMovie) > 0) && (*debug || *gdbPort > 0):
+		fmt.Println("--script and movies cannot be used with --debug or --gdb-port")
+		os.Exit(-1)
+	case *debug:
+		go exitOnSignal(syscall.SIGTERM) // Ctrl-C is used by the debugger
+		go runDebugger(gbCpu, memoryBus, symbolTable, logger)
+		err = frontend.Present(machine, gbFrontend)
+	case *gdbPort > 0:
+		go exitOnSignal(os.Interrupt, syscall.SIGTERM)
+		go runGdbServer(gbCpu, memoryBus, logger)
+		err = frontend.Present(machine, gbFrontend)
+	default:
+		if len(*scriptPath) > 0 {
+			gbFrontend = attachScript(machine, gbFrontend, logger)
+		}
+		if len(*recordMovie) > 0 || len(*playMovie) > 0 {
+			gbFrontend = attachMovie(machine, gbFrontend, logger)
+		}
+		go exitOnSignal(os.Interrupt, syscall.SIGTERM)
+		err = frontend.Run(machine, gbFrontend, frontend.Options{
+			Speed:  configValues.Speed,
+			Paused: configValues.StartPaused,
+		})
+	}
+
+	gbFrontend.Close()
+	if err != nil {
+		fatal(err)
+	}
+	exit(0)
+}
+
+// runDebugger hands the CPU over to the interactive debugger. Ctrl-C stops the CPU and goes back to the
+// debugger prompt instead of killing the emulator.
+func runDebugger(gbCpu *cpu.CPU, memoryBus *bus.Bus, symbolTable *symbols.Table, logger log.Logger) {
+	defer exitOnFatal()
+
+	gbDebugger := debugger.New(gbCpu, memoryBus, os.Stdin, os.Stdout)
+	gbDebugger.SetSymbols(symbolTable)
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		for range interrupts {
+			gbDebugger.Interrupt()
+		}
+	}()
+
+	gbDebugger.Run()
+	exit(0)
+}
+
+// exit runs the atExit functions and exits.
+func exit(code int) {
+	for _, fn := range atExit {
+		fn()
+	}
+	os.Exit(code)
+}
 
-	for {
-		time.Sleep(1000 * time.Microsecond)
-		gbScreen.UpdateUI()
+// exitOnFatal prints the errors raised by the emulator when the CPU is driven outside of goboy.Machine, like
+// by the debugger, and exits. It must be deferred.
+func exitOnFatal() {
+	if r := recover(); r != nil {
+		if fatalErr, ok := r.(log.FatalError); ok {
+			fatal(fatalErr)
+		}
+		panic(r)
 	}
 }
 
+// fatal prints an error stopping the emulator and exits, running the atExit functions so that the movie, trace
+// and video recorded up to the error are saved.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exit(1)
+}
+
+// exitOnSignal exits cleanly when any of the given signals is received.
+func exitOnSignal(signals ...os.Signal) {
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	<-received
+	exit(0)
+}
+
 func configureEmulator() *config.Config {
-	configFilePath := flag.String("configFilePath", "", "Path to the GoBoy config path")
 	flag.Parse()
 
 	configParser, err := config.NewConfigParser(*configFilePath)
@@ -80,8 +232,10 @@ func configureEmulator() *config.Config {
 	if err != nil {
 		var parseInfoErr *config.ParsingError
 		var missingConfigValuesErr *config.MissingConfigValuesError
+		var speedErr *config.SpeedError
 		var romNotFoundErr *config.RomNotFoundError
 		var dirWriteErr *config.LogWriteError
+		var printerWriteErr *config.PrinterWriteError
 
 		if errors.As(err, &parseInfoErr) {
 			fmt.Printf("%s", parseInfoErr) // probably in the future show a window?
@@ -89,12 +243,18 @@ func configureEmulator() *config.Config {
 		} else if errors.As(err, &missingConfigValuesErr) {
 			fmt.Printf("%s", missingConfigValuesErr) // probably in the future show a window?
 			os.Exit(-1)
+		} else if errors.As(err, &speedErr) {
+			fmt.Printf("%s", speedErr)
+			os.Exit(-1)
 		} else if errors.As(err, &romNotFoundErr) {
 			fmt.Printf("%s", romNotFoundErr) // probably in the future show a window?
 			os.Exit(-1)
 		} else if errors.As(err, &dirWriteErr) {
 			fmt.Printf("%s", dirWriteErr)
 			os.Exit(-1)
+		} else if errors.As(err, &printerWriteErr) {
+			fmt.Printf("%s", printerWriteErr)
+			os.Exit(-1)
 		} else {
 			panic(err)
 		}
@@ -102,3 +262,66 @@ func configureEmulator() *config.Config {
 
 	return configValues
 }
+
+// runGdbServer runs the CPU under the GDB server, which stops it whilst a client is attached.
+func runGdbServer(gbCpu *cpu.CPU, memoryBus *bus.Bus, logger log.Logger) {
+	defer exitOnFatal()
+
+	server := gdb.New(gbCpu, memoryBus, logger)
+	if err := server.ListenAndServe(fmt.Sprintf("localhost:%d", *gdbPort)); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// createTracer returns the tracer set up by the trace flags, or nil if tracing is disabled.
+func createTracer(memoryBus *bus.Bus, logger log.Logger) *trace.Tracer {
+	if len(*tracePath) == 0 {
+		return nil
+	}
+
+	format, err := trace.ParseFormat(*traceFormat)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	window := trace.FullWindow
+	window.Start = *traceStart
+	window.Count = *traceCount
+	if len(*tracePC) > 0 {
+		if window.FromPC, window.ToPC, err = trace.ParsePCRange(*tracePC); err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+	}
+
+	tracer, err := trace.Create(*tracePath, format, window, memoryBus)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return tracer
+}
+
+func connectLinkCable(logger log.Logger) *link.Cable {
+	var cable *link.Cable
+	var err error
+
+	switch {
+	case len(*linkListen) > 0 && len(*linkConnect) > 0:
+		fmt.Println("--link-listen and --link-connect cannot be used at the same time")
+		os.Exit(-1)
+	case len(*linkListen) > 0:
+		fmt.Printf("Waiting for link cable peer on %s\n", *linkListen)
+		cable, err = link.Listen(*linkListen, logger)
+	case len(*linkConnect) > 0:
+		cable, err = link.Dial(*linkConnect, logger)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	return cable
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/movie"
+	"os"
+)
+
+// attachMovie starts recording or playing the movie given with --record-movie or --play-movie, and returns the
+// frontend wrapped to do it. The movie is saved on exit when it is recorded.
+func attachMovie(machine *goboy.Machine, f frontend.Frontend, logger log.Logger) frontend.Frontend {
+	if len(*recordMovie) > 0 && len(*playMovie) > 0 {
+		fmt.Println("--record-movie and --play-movie cannot be used at the same time")
+		os.Exit(-1)
+	}
+
+	var session *movie.Session
+	path := *recordMovie
+	if len(*recordMovie) > 0 {
+		if len(*movieState) > 0 {
+			loadStateFile(machine, *movieState, logger)
+		}
+
+		m, err := movie.New(machine, len(*movieState) > 0)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if session, err = movie.Record(machine, m); err != nil {
+			logger.Fatal(err)
+		}
+	} else {
+		m, err := movie.Load(*playMovie)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if err := m.CheckRom(machine); err != nil {
+			fmt.Printf("warning: %s\n", err)
+		}
+		if session, err = movie.Play(machine, m, !*movieReadWrite); err != nil {
+			logger.Fatal(err)
+		}
+		path = *playMovie
+	}
+
+	if len(*recordMovie) > 0 || *movieReadWrite {
+		atExit = append(atExit, func() {
+			if err := session.Save(path); err != nil {
+				fmt.Printf("error saving movie: %s\n", err)
+			}
+		})
+	}
+	return session.Wrap(f)
+}
+
+// loadStateFile loads a save state written by goboy.Machine.SaveState.
+func loadStateFile(machine *goboy.Machine, path string, logger log.Logger) {
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := machine.LoadState(file); err != nil {
+		logger.Fatal(err)
+	}
+}
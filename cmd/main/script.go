@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/script"
+	"os"
+	"path/filepath"
+)
+
+// attachScript loads the --script Lua script and returns the frontend wrapped to run it between frames.
+func attachScript(machine *goboy.Machine, f frontend.Frontend, logger log.Logger) frontend.Frontend {
+	file, err := os.Open(*scriptPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer file.Close()
+
+	s, err := script.New(machine, filepath.Base(*scriptPath), file, os.Stdout)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	return s.Wrap(f)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/terminal"
+	"github.com/mikeletux/goboy/pkg/testrom"
+	"os"
+)
+
+// runTestRom implements "goboy testrom [flags] rom.gb|dir...". It runs test ROMs without a screen and exits
+// with status 1 if any of them doesn't pass. With --screenshots, ROMs pass when their screen matches the
+// reference PNG next to them.
+func runTestRom(args []string) {
+	flags := flag.NewFlagSet("testrom", flag.ExitOnError)
+	frames := flags.Uint64("frames", testrom.DefaultOptions.MaxFrames, "Maximum number of frames to run each ROM, 0 for no limit")
+	cycles := flags.Uint64("cycles", 0, "Maximum number of T-cycles to run each ROM, 0 for no limit")
+	jsonOutput := flags.Bool("json", false, "Print a JSON object per ROM")
+	screenshots := flags.Bool("screenshots", false, "Run until LD B,B and compare the screen with the rom.png reference next to each ROM")
+	screenshotOnExit := flags.Bool("screenshot-on-exit", false, "Run until LD B,B and save the screen as the rom.png reference next to each ROM")
+	showScreen := flags.Bool("show-screen", false, "Print the last screen of each ROM with ANSI colours")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goboy testrom [flags] rom.gb|dir...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var roms []string
+	for _, path := range flags.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		if !info.IsDir() {
+			roms = append(roms, path)
+			continue
+		}
+
+		found, err := testrom.FindRoms(path)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		roms = append(roms, found...)
+	}
+
+	options := testrom.Options{MaxFrames: *frames, MaxCycles: *cycles}
+	if *screenshots || *screenshotOnExit {
+		options.StopAtBreakpoint = true
+		if !isFlagSet(flags, "frames") {
+			options.MaxFrames = testrom.ScreenshotOptions.MaxFrames
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	allPassed := true
+
+	for _, rom := range roms {
+		result, err := testrom.Run(rom, options)
+		if err == nil && *screenshotOnExit {
+			// The screen of a ROM that didn't finish is not a reference
+			if result.Status == testrom.Timeout {
+				result.Output = "the ROM didn't finish, the reference was not written"
+			} else if err = testrom.SavePNG(testrom.ReferencePath(rom), result.Screen); err == nil &&
+				result.Status == testrom.Breakpoint {
+				result.Status = testrom.Passed
+			}
+		} else if err == nil && *screenshots {
+			result.Status = testrom.Passed
+			if mismatch := testrom.CheckScreenshot(rom, result); mismatch != nil {
+				result.Status = testrom.Failed
+				result.Output = mismatch.Error()
+			}
+		}
+
+		if err != nil || result.Status != testrom.Passed {
+			allPassed = false
+		}
+
+		if *jsonOutput {
+			line := struct {
+				Rom   string `json:"rom"`
+				Error string `json:"error,omitempty"`
+				testrom.Result
+			}{Rom: rom, Result: result}
+			if err != nil {
+				line.Error = err.Error()
+			}
+			encoder.Encode(line)
+			continue
+		}
+
+		if err != nil {
+			fmt.Printf("%-7s %s: %s\n", "error", rom, err)
+			continue
+		}
+		fmt.Printf("%-7s %s\n", result.Status, rom)
+		if result.Status != testrom.Passed && len(result.Output) > 0 {
+			fmt.Println(result.Output)
+		}
+		if *showScreen {
+			terminal.WriteImage(os.Stdout, result.Screen)
+		}
+	}
+
+	if !allPassed {
+		os.Exit(1)
+	}
+}
+
+// isFlagSet returns whether a flag was given in the command line.
+func isFlagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
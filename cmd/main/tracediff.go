@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cart"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/trace"
+	"os"
+)
+
+// doctorLy is the value Gameboy Doctor expects LY to read, since the logs are made without a PPU.
+const doctorLy byte = 0x90
+
+// runTraceDiff implements "goboy tracediff rom.gb reference.log[.gz]". The ROM runs without a screen until its
+// state differs from the reference log, which is in the Gameboy Doctor format.
+func runTraceDiff(args []string) {
+	flags := flag.NewFlagSet("tracediff", flag.ExitOnError)
+	contextLines := flags.Int("context", 5, "Number of matching lines to show before the divergence")
+	serial := flags.Bool("serial", false, "Print the bytes sent through the serial port, like test ROM results")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goboy tracediff [flags] rom.gb reference.log[.gz]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	logger, err := log.NewBuiltinStdoutLogger(false, false, "")
+	if err != nil {
+		panic(err)
+	}
+
+	cartridge, err := cart.NewCartridge(flags.Arg(0), logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	reference, err := trace.OpenReference(flags.Arg(1))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	defer reference.Close()
+
+	memoryBus := bus.NewBus(cartridge, logger)
+	memoryBus.SetLcdLy(doctorLy)
+	if *serial {
+		memoryBus.SetSerialOutput(os.Stdout)
+	}
+
+	differ := trace.NewDiffer(reference, memoryBus, *contextLines)
+	gbCpu := cpu.Init(memoryBus, logger)
+	gbCpu.SetTracer(differ)
+
+	for !differ.Done() {
+		gbCpu.Step()
+	}
+
+	divergence, lines, err := differ.Result()
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if divergence == nil {
+		fmt.Printf("\nNo divergence in %d lines\n", lines)
+		return
+	}
+
+	fmt.Printf("\nDivergence at line %d of the reference log\n\n", divergence.Line)
+	for _, line := range divergence.Context {
+		fmt.Printf("   %s\n", line)
+	}
+	fmt.Printf("-  %s\n", divergence.Expected)
+	fmt.Printf("+  %s\n\n", divergence.Got)
+
+	for _, field := range divergence.Fields {
+		fmt.Printf("%-6s expected %-12s got %s\n", field.Name, field.Expected, field.Got)
+	}
+	fmt.Printf("\nInstruction: %s\n", divergence.Disassembly)
+	os.Exit(1)
+}
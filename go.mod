@@ -2,9 +2,9 @@ module github.com/mikeletux/goboy
 
 go 1.19
 
-require github.com/veandco/go-sdl2 v0.4.33
-
 require (
-	golang.org/x/sys v0.14.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	github.com/veandco/go-sdl2 v0.4.33
+	github.com/yuin/gopher-lua v1.1.1
+	golang.org/x/sys v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,298 @@
+// Package goboy is the API to embed the GoBoy emulator in other programs, like bots, tests and tools. A Machine
+// is a whole Game Boy without a window nor sound output, driven a frame or an instruction at a time.
+package goboy
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/apu"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cart"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"github.com/mikeletux/goboy/pkg/sgb"
+	"io"
+	"strings"
+)
+
+const (
+	ScreenWidth  = ppu.ScreenWidth
+	ScreenHeight = ppu.ScreenHeight
+
+	// FrameCycles is the number of T-cycles the PPU takes to draw a frame at normal speed.
+	FrameCycles = ppu.FrameCycles
+)
+
+// Buttons is a bitmask of the pressed buttons.
+type Buttons byte
+
+const (
+	ButtonRight  = Buttons(bus.JoypadRight)
+	ButtonLeft   = Buttons(bus.JoypadLeft)
+	ButtonUp     = Buttons(bus.JoypadUp)
+	ButtonDown   = Buttons(bus.JoypadDown)
+	ButtonA      = Buttons(bus.JoypadA)
+	ButtonB      = Buttons(bus.JoypadB)
+	ButtonSelect = Buttons(bus.JoypadSelect)
+	ButtonStart  = Buttons(bus.JoypadStart)
+)
+
+// buttonNames are the names accepted by ParseButtons.
+var buttonNames = map[string]Buttons{
+	"right":  ButtonRight,
+	"left":   ButtonLeft,
+	"up":     ButtonUp,
+	"down":   ButtonDown,
+	"a":      ButtonA,
+	"b":      ButtonB,
+	"select": ButtonSelect,
+	"start":  ButtonStart,
+}
+
+// ParseButtons returns the buttons with the given names: right, left, up, down, a, b, select and start.
+func ParseButtons(names ...string) (Buttons, error) {
+	var buttons Buttons
+	for _, name := range names {
+		button, ok := buttonNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("unknown button %q", name)
+		}
+		buttons |= button
+	}
+	return buttons, nil
+}
+
+// stateVersion is increased every time the save state format changes.
+const stateVersion = 3
+
+// Options configure a Machine. The zero value is ready to use.
+type Options struct {
+	// SampleRate is the number of stereo samples per second returned by AudioSamples, 48000 if 0.
+	SampleRate int
+	// Logger receives the debug logs of the emulator, nil drops them.
+	Logger log.Logger
+}
+
+// Machine is a Game Boy with a cartridge inserted. It is not safe for concurrent use, except for Screen,
+// Framebuffer and AudioSamples, which can be called whilst another goroutine runs it.
+type Machine struct {
+	options   Options
+	logger    *log.PanicLogger
+	cartridge *cart.Cartridge
+
+	bus *bus.Bus
+	cpu *cpu.CPU
+	ppu *ppu.PPU
+	apu *apu.APU
+	sgb *sgb.SGB // nil unless the game uses SGB functions
+
+	powerOnState state // Loaded by Reset, so the components and what is attached to them are kept
+}
+
+// New returns a powered on Machine running the given ROM.
+func New(rom []byte, options Options) (*Machine, error) {
+	logger := &log.PanicLogger{Logger: options.Logger}
+	cartridge, err := cart.NewCartridgeFromBytes(rom, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Machine{
+		options:   options,
+		logger:    logger,
+		cartridge: cartridge,
+	}
+	m.powerOn()
+	m.powerOnState = m.state()
+	return m, nil
+}
+
+func (m *Machine) powerOn() {
+	m.bus = bus.NewBus(m.cartridge, m.logger)
+	m.cpu = cpu.Init(m.bus, m.logger)
+	m.ppu = ppu.Init(m.bus, m.logger)
+	m.apu = apu.New(m.options.SampleRate)
+
+	m.cpu.AddTicker(m.ppu)
+	m.cpu.AddTicker(m.apu)
+	m.bus.SetSoundDevice(m.apu)
+
+	// The SGB ignores command packets unless the old licensee code is 0x33
+	header := m.cartridge.CartridgeHeader
+	if !m.bus.IsCgbMode() && header.SGBFlag() && header.OldLicenseeCode == 0x33 {
+		m.sgb = sgb.New(m.bus, m.logger)
+		m.bus.SetJoypadDevice(m.sgb)
+	}
+}
+
+// Reset turns the Game Boy off and on again. The cartridge RAM is kept, like the battery backed saves, and so
+// are the serial devices, hooks, tracer and symbols attached to the components.
+func (m *Machine) Reset() {
+	m.cartridge.Reset()
+	m.loadComponents(m.powerOnState)
+}
+
+// RunFrame runs until the PPU completes a frame. If the LCD is off it runs for the time a frame takes. Errors
+// that would stop the emulator, like unknown instructions, are returned.
+func (m *Machine) RunFrame() (err error) {
+	defer log.Recover(&err)
+
+	budget := FrameCycles
+	if m.bus.IsDoubleSpeed() {
+		budget *= 2
+	}
+
+	frame, limit := m.ppu.FrameCount(), m.cpu.Cycles()+budget
+	for m.ppu.FrameCount() == frame && m.cpu.Cycles() < limit {
+		m.cpu.Step()
+	}
+	return nil
+}
+
+// StepInstruction executes a single instruction, or a machine cycle if the CPU is halted.
+func (m *Machine) StepInstruction() (err error) {
+	defer log.Recover(&err)
+
+	m.cpu.Step()
+	return nil
+}
+
+// SetButtons sets which buttons are pressed, until the next call.
+func (m *Machine) SetButtons(buttons Buttons) {
+	m.bus.SetJoypadButtons(byte(buttons))
+}
+
+// QueueButtons sets which buttons are pressed, like SetButtons, but can be called while another goroutine runs
+// the CPU, like a debugger. The buttons are set before the next instruction.
+func (m *Machine) QueueButtons(buttons Buttons) {
+	m.bus.QueueJoypadButtons(byte(buttons))
+}
+
+// Framebuffer returns a copy of the last complete frame, ScreenWidth x ScreenHeight pixels stored row by row
+// as 0xAARRGGBB.
+func (m *Machine) Framebuffer() []uint32 {
+	return m.ppu.Framebuffer()
+}
+
+// Screen returns what a screen would show: the last complete frame or, if the game uses SGB functions, the
+// colorized frame with the SGB border around it. Pixels are stored row by row as 0xAARRGGBB.
+func (m *Machine) Screen() (frame []uint32, width, height int) {
+	if m.sgb != nil {
+		return m.sgb.Render(m.ppu.ShadeBuffer()), sgb.BorderWidth, sgb.BorderHeight
+	}
+	return m.ppu.Framebuffer(), ScreenWidth, ScreenHeight
+}
+
+// AudioSamples returns the sound generated since the last call as signed 16 bit stereo samples, interleaved
+// left and right. Up to one second is kept between calls.
+func (m *Machine) AudioSamples() []int16 {
+	return m.apu.Samples()
+}
+
+// SampleRate returns the number of stereo samples per second returned by AudioSamples.
+func (m *Machine) SampleRate() int {
+	return m.apu.SampleRate()
+}
+
+// Cycles returns the T-cycles run since power on.
+func (m *Machine) Cycles() uint64 {
+	return m.cpu.Cycles()
+}
+
+// FrameCount returns how many frames have been completed since power on.
+func (m *Machine) FrameCount() uint64 {
+	return m.ppu.FrameCount()
+}
+
+// CPU returns the emulated CPU, to attach tracers or inspect the registers.
+func (m *Machine) CPU() *cpu.CPU {
+	return m.cpu
+}
+
+// PPU returns the emulated PPU.
+func (m *Machine) PPU() *ppu.PPU {
+	return m.ppu
+}
+
+// Bus returns the memory bus, to read memory or plug serial devices.
+func (m *Machine) Bus() *bus.Bus {
+	return m.bus
+}
+
+// Cartridge returns the cartridge inserted.
+func (m *Machine) Cartridge() *cart.Cartridge {
+	return m.cartridge
+}
+
+// state is what SaveState writes, encoded with gob.
+type state struct {
+	Version  int
+	Title    [16]byte
+	Checksum [2]byte
+
+	Cpu       cpu.State
+	Bus       bus.State
+	Ppu       ppu.State
+	Apu       apu.State
+	Sgb       sgb.State // Only set if the game uses SGB functions
+	Cartridge cart.State
+}
+
+// SaveState writes the whole state of the machine. It can be loaded back with LoadState on a machine running
+// the same ROM.
+func (m *Machine) SaveState(w io.Writer) error {
+	return gob.NewEncoder(w).Encode(m.state())
+}
+
+func (m *Machine) state() state {
+	header := m.cartridge.CartridgeHeader
+	s := state{
+		Version:   stateVersion,
+		Title:     header.Title,
+		Checksum:  header.GlobalChecksum,
+		Cpu:       m.cpu.State(),
+		Bus:       m.bus.State(),
+		Ppu:       m.ppu.State(),
+		Apu:       m.apu.State(),
+		Cartridge: m.cartridge.State(),
+	}
+	if m.sgb != nil {
+		s.Sgb = m.sgb.State()
+	}
+	return s
+}
+
+// LoadState restores a state written by SaveState. The machine is left untouched if it fails.
+func (m *Machine) LoadState(r io.Reader) error {
+	var s state
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return fmt.Errorf("error decoding state: %w", err)
+	}
+
+	if s.Version != stateVersion {
+		return fmt.Errorf("state version %d is not supported, expected %d", s.Version, stateVersion)
+	}
+
+	header := m.cartridge.CartridgeHeader
+	if s.Title != header.Title || s.Checksum != header.GlobalChecksum {
+		return fmt.Errorf("state belongs to another ROM")
+	}
+
+	if err := m.cartridge.LoadState(s.Cartridge); err != nil {
+		return err
+	}
+	m.loadComponents(s)
+	return nil
+}
+
+// loadComponents loads the state of every component but the cartridge.
+func (m *Machine) loadComponents(s state) {
+	m.cpu.LoadState(s.Cpu)
+	m.bus.LoadState(s.Bus)
+	m.ppu.LoadState(s.Ppu)
+	m.apu.LoadState(s.Apu)
+	if m.sgb != nil {
+		m.sgb.LoadState(s.Sgb)
+	}
+}
@@ -0,0 +1,218 @@
+package goboy
+
+import (
+	"bytes"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/test"
+	"testing"
+)
+
+func newTestMachine(t *testing.T, title string) *Machine {
+	m, err := New(test.Rom(title, test.CounterProgram), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(make([]byte, 0x100), Options{}); err == nil {
+		t.Error("expected an error for a ROM without header")
+	}
+}
+
+func TestRunFrame(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+
+	for i := uint64(1); i <= 3; i++ {
+		if err := m.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+		if m.FrameCount() != i {
+			t.Fatalf("expected %d frames got %d", i, m.FrameCount())
+		}
+		if i == 1 { // The first frame is shorter, frames are completed when VBlank starts
+			m.AudioSamples()
+		}
+	}
+
+	if got := len(m.Framebuffer()); got != ScreenWidth*ScreenHeight {
+		t.Errorf("expected %d pixels got %d", ScreenWidth*ScreenHeight, got)
+	}
+	if m.Bus().Peek(0xC000) == 0 {
+		t.Error("expected the program to run")
+	}
+
+	// 2 frames of stereo samples at 48000Hz
+	expected := int(2 * FrameCycles * 48000 / 4194304 * 2)
+	if got := len(m.AudioSamples()); got < expected-4 || got > expected+4 {
+		t.Errorf("expected about %d audio samples got %d", expected, got)
+	}
+}
+
+func TestStepInstruction(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+
+	if err := m.StepInstruction(); err != nil {
+		t.Fatal(err)
+	}
+	if pc := m.CPU().Registers().PC; pc != 0x150 {
+		t.Errorf("expected PC 0150 after JP got %04X", pc)
+	}
+}
+
+func TestHooksOnlySeeCPU(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+
+	// The program only touches C000, whilst the PPU and the timer use their registers every cycle
+	var accesses []bus.Access
+	m.Bus().AddHook(func(access bus.Access) {
+		if access.Address >= 0xFF00 {
+			accesses = append(accesses, access)
+		}
+	})
+	m.Bus().BusWrite(0xFF07, 0x05) // Timer enabled, increased every 16 cycles
+
+	accesses = nil
+	if err := m.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if len(accesses) != 0 {
+		t.Errorf("expected no IO register access got %d, the first one %+v", len(accesses), accesses[0])
+	}
+}
+
+func TestGeneralPurposeDmaStallsCPU(t *testing.T) {
+	m, err := New(test.Rom("HDMA", map[uint16][]byte{
+		0x100: {0x3E, 0x01, 0xE0, 0x55}, // LD A,$01 ; LDH ($55),A, copies 2 blocks
+		0x143: {0x80},                   // CGB
+	}), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := m.StepInstruction(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// LD A,d8 takes 2 machine cycles, LDH (a8),A 3 and the DMA 8 per block
+	if cycles := m.Cycles(); cycles != (2+3+2*8)*4 {
+		t.Errorf("expected %d T-cycles got %d", (2+3+2*8)*4, cycles)
+	}
+}
+
+func TestFatalError(t *testing.T) {
+	m, err := New(test.Rom("BROKEN", map[uint16][]byte{0x100: {0xD3}}), Options{}) // D3 doesn't exist
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.RunFrame(); err == nil {
+		t.Error("expected an error running an unknown instruction")
+	}
+}
+
+func TestSetButtons(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+
+	m.SetButtons(ButtonStart | ButtonA)
+	m.Bus().BusWrite(0xFF00, 0x10) // Select action buttons
+	if got := m.Bus().BusRead(0xFF00) & 0x0F; got != 0b0110 {
+		t.Errorf("expected Start and A to be pressed, P1 lower nibble is %04b", got)
+	}
+}
+
+func TestParseButtons(t *testing.T) {
+	buttons, err := ParseButtons("start", "A")
+	if err != nil || buttons != ButtonStart|ButtonA {
+		t.Errorf("expected Start and A got %08b, %v", buttons, err)
+	}
+
+	if _, err := ParseButtons("turbo"); err == nil {
+		t.Error("expected an error for an unknown button")
+	}
+}
+
+func TestReset(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+	if err := m.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	var writes int
+	m.Bus().AddHook(func(access bus.Access) {
+		if access.Type == bus.AccessWrite && access.Address == 0xC000 {
+			writes++
+		}
+	})
+	var serial bytes.Buffer
+	m.Bus().SetSerialOutput(&serial)
+
+	m.Reset()
+	if m.Cycles() != 0 || m.FrameCount() != 0 || m.Bus().Peek(0xC000) != 0 {
+		t.Error("expected the machine to be powered on again")
+	}
+
+	// What was attached to the components before the Reset keeps working
+	if err := m.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if writes == 0 {
+		t.Error("expected the hook to be called after a Reset")
+	}
+	m.Bus().BusWrite(0xFF01, 'A')
+	m.Bus().BusWrite(0xFF02, 0x81)
+	for i := 0; i < 2 && serial.Len() == 0; i++ {
+		if err := m.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if serial.String() != "A" {
+		t.Errorf("expected the serial output to get A after a Reset, got %q", serial.String())
+	}
+}
+
+func TestSaveState(t *testing.T) {
+	m := newTestMachine(t, "COUNTER")
+	if err := m.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved bytes.Buffer
+	if err := m.SaveState(&saved); err != nil {
+		t.Fatal(err)
+	}
+	state := saved.Bytes()
+
+	run := func() (uint64, byte, []uint32) {
+		for i := 0; i < 3; i++ {
+			if err := m.RunFrame(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return m.Cycles(), m.Bus().Peek(0xC000), m.Framebuffer()
+	}
+
+	cycles, counter, frame := run()
+	if err := m.LoadState(bytes.NewReader(state)); err != nil {
+		t.Fatal(err)
+	}
+	gotCycles, gotCounter, gotFrame := run()
+
+	if gotCycles != cycles || gotCounter != counter {
+		t.Errorf("expected %d cycles and counter %02X after loading, got %d and %02X", cycles, counter,
+			gotCycles, gotCounter)
+	}
+	for i := range frame {
+		if frame[i] != gotFrame[i] {
+			t.Fatalf("expected the same frame after loading, pixel %d differs", i)
+		}
+	}
+
+	other := newTestMachine(t, "OTHER")
+	if err := other.LoadState(bytes.NewReader(state)); err == nil {
+		t.Error("expected an error loading the state of another ROM")
+	}
+}
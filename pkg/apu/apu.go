@@ -0,0 +1,365 @@
+// Package apu emulates the Game Boy audio processing unit: two square channels, a wave channel and a noise
+// channel, mixed into stereo samples.
+package apu
+
+import (
+	"math"
+	"sync"
+)
+
+// ClockRate is the number of dots per second. The APU is ticked once per dot.
+const ClockRate = 4194304
+
+// DefaultSampleRate is the sample rate used when none is given.
+const DefaultSampleRate = 48000
+
+// Register addresses
+const (
+	nr10RegisterAddr uint16 = 0xFF10
+	nr11RegisterAddr uint16 = 0xFF11
+	nr12RegisterAddr uint16 = 0xFF12
+	nr13RegisterAddr uint16 = 0xFF13
+	nr14RegisterAddr uint16 = 0xFF14
+	nr21RegisterAddr uint16 = 0xFF16
+	nr22RegisterAddr uint16 = 0xFF17
+	nr23RegisterAddr uint16 = 0xFF18
+	nr24RegisterAddr uint16 = 0xFF19
+	nr30RegisterAddr uint16 = 0xFF1A
+	nr31RegisterAddr uint16 = 0xFF1B
+	nr32RegisterAddr uint16 = 0xFF1C
+	nr33RegisterAddr uint16 = 0xFF1D
+	nr34RegisterAddr uint16 = 0xFF1E
+	nr41RegisterAddr uint16 = 0xFF20
+	nr42RegisterAddr uint16 = 0xFF21
+	nr43RegisterAddr uint16 = 0xFF22
+	nr44RegisterAddr uint16 = 0xFF23
+	nr50RegisterAddr uint16 = 0xFF24
+	nr51RegisterAddr uint16 = 0xFF25
+	nr52RegisterAddr uint16 = 0xFF26
+
+	registersStart uint16 = 0xFF10
+	waveRamStart   uint16 = 0xFF30
+	waveRamEnd     uint16 = 0xFF3F
+)
+
+const registerCount = int(waveRamStart - registersStart)
+
+// readMasks are ORed to the register values when read, since unused and write only bits read 1.
+var readMasks = [registerCount]byte{
+	0x80, 0x3F, 0x00, 0xFF, 0xBF, // NR10-NR14
+	0xFF, 0x3F, 0x00, 0xFF, 0xBF, // Unused, NR21-NR24
+	0x7F, 0xFF, 0x9F, 0xFF, 0xBF, // NR30-NR34
+	0xFF, 0xFF, 0x00, 0x00, 0xBF, // Unused, NR41-NR44
+	0x00, 0x00, 0x70, // NR50-NR52
+	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, // Unused
+}
+
+const (
+	sequencerPeriod = ClockRate / 512 // The frame sequencer runs at 512Hz
+	powerBit        = 0x80
+	triggerBit      = 0x80
+	lengthEnableBit = 0x40
+)
+
+// APU generates the sound. Samples are signed 16 bit stereo, interleaved left and right.
+type APU struct {
+	registers [registerCount]byte
+	powered   bool
+
+	square1 Square
+	square2 Square
+	wave    Wave
+	noise   Noise
+
+	sequencerTimer int
+	sequencerStep  byte
+
+	sampleRate    int
+	sampleCounter int
+	chargeFactor  float64    // High pass filter charge factor, which depends on the sample rate
+	capacitors    [2]float64 // High pass filter state of the left and right outputs
+
+	samplesMutex sync.Mutex
+	samples      []int16
+	maxSamples   int
+}
+
+// New returns a powered on APU generating samples at the given rate. It keeps up to one second of samples,
+// newer ones are dropped if they are not read with Samples.
+func New(sampleRate int) *APU {
+	if sampleRate <= 0 {
+		sampleRate = DefaultSampleRate
+	}
+
+	apu := &APU{
+		powered:      true,
+		sampleRate:   sampleRate,
+		chargeFactor: math.Pow(0.999958, float64(ClockRate)/float64(sampleRate)),
+		maxSamples:   sampleRate * 2,
+	}
+
+	// Values left by the boot ROM, which plays its sound on channel 1
+	for _, register := range []struct {
+		address uint16
+		value   byte
+	}{{nr11RegisterAddr, 0x80}, {nr12RegisterAddr, 0xF3}, {nr50RegisterAddr, 0x77}, {nr51RegisterAddr, 0xF3}} {
+		apu.SoundWrite(register.address, register.value)
+	}
+
+	return apu
+}
+
+// SampleRate returns the number of stereo samples generated per second.
+func (a *APU) SampleRate() int {
+	return a.sampleRate
+}
+
+// Tick advances the APU one dot.
+func (a *APU) Tick() {
+	if a.powered {
+		a.sequencerTimer++
+		if a.sequencerTimer == sequencerPeriod {
+			a.sequencerTimer = 0
+			a.clockSequencer()
+		}
+
+		a.square1.tick()
+		a.square2.tick()
+		a.wave.tick()
+		a.noise.tick()
+	}
+
+	a.sampleCounter += a.sampleRate
+	if a.sampleCounter >= ClockRate {
+		a.sampleCounter -= ClockRate
+		a.addSample()
+	}
+}
+
+// clockSequencer runs a step of the frame sequencer, which clocks the length counters at 256Hz, the sweep at
+// 128Hz and the envelopes at 64Hz.
+func (a *APU) clockSequencer() {
+	if a.sequencerStep%2 == 0 {
+		a.square1.Enabled = a.square1.Length.clock() && a.square1.Enabled
+		a.square2.Enabled = a.square2.Length.clock() && a.square2.Enabled
+		a.wave.Enabled = a.wave.Length.clock() && a.wave.Enabled
+		a.noise.Enabled = a.noise.Length.clock() && a.noise.Enabled
+	}
+
+	if a.sequencerStep == 2 || a.sequencerStep == 6 {
+		a.square1.clockSweep()
+	}
+
+	if a.sequencerStep == 7 {
+		a.square1.Envelope.clock()
+		a.square2.Envelope.clock()
+		a.noise.Envelope.clock()
+	}
+
+	a.sequencerStep = (a.sequencerStep + 1) & 7
+}
+
+// Samples returns the samples generated since the last call.
+func (a *APU) Samples() []int16 {
+	a.samplesMutex.Lock()
+	defer a.samplesMutex.Unlock()
+
+	samples := a.samples
+	a.samples = nil
+	return samples
+}
+
+func (a *APU) addSample() {
+	left, right := a.mix()
+	left = a.highPass(0, left)
+	right = a.highPass(1, right)
+
+	a.samplesMutex.Lock()
+	defer a.samplesMutex.Unlock()
+
+	if len(a.samples) < a.maxSamples {
+		a.samples = append(a.samples, toSample(left), toSample(right))
+	}
+}
+
+// mix returns the left and right outputs, between -1 and 1.
+func (a *APU) mix() (float64, float64) {
+	if !a.powered {
+		return 0, 0
+	}
+
+	outputs := [4]byte{a.square1.output(), a.square2.output(), a.wave.output(), a.noise.output()}
+	dacs := [4]bool{a.square1.Envelope.dacEnabled(), a.square2.Envelope.dacEnabled(), a.wave.DacEnabled,
+		a.noise.Envelope.dacEnabled()}
+
+	panning := a.register(nr51RegisterAddr)
+	var left, right float64
+	for i, output := range outputs {
+		if !dacs[i] {
+			continue
+		}
+
+		analog := float64(output)/7.5 - 1 // DACs convert 0-15 to 1 to -1, the sign doesn't matter
+		if panning&(0x10<<i) != 0 {
+			left += analog
+		}
+		if panning&(0x01<<i) != 0 {
+			right += analog
+		}
+	}
+
+	volume := a.register(nr50RegisterAddr)
+	left *= float64(volume>>4&0x7+1) / 8
+	right *= float64(volume&0x7+1) / 8
+	return left / 4, right / 4
+}
+
+// highPass removes the DC offset of the output, like the capacitors of the real hardware.
+func (a *APU) highPass(side int, input float64) float64 {
+	output := input - a.capacitors[side]
+	a.capacitors[side] = input - output*a.chargeFactor
+	return output
+}
+
+func toSample(value float64) int16 {
+	value = math.Max(-1, math.Min(1, value))
+	return int16(value * math.MaxInt16)
+}
+
+func (a *APU) register(address uint16) byte {
+	return a.registers[address-registersStart]
+}
+
+// SoundRead reads a sound register or the wave RAM.
+func (a *APU) SoundRead(address uint16) byte {
+	if address >= waveRamStart && address <= waveRamEnd {
+		return a.wave.Ram[address-waveRamStart]
+	}
+
+	if address == nr52RegisterAddr {
+		value := readMasks[address-registersStart]
+		if a.powered {
+			value |= powerBit
+		}
+		for i, enabled := range []bool{a.square1.Enabled, a.square2.Enabled, a.wave.Enabled, a.noise.Enabled} {
+			if enabled {
+				value |= 1 << i
+			}
+		}
+		return value
+	}
+
+	index := address - registersStart
+	return a.registers[index] | readMasks[index]
+}
+
+// SoundWrite writes a sound register or the wave RAM. Whilst the APU is powered off only NR52 and the wave RAM
+// can be written.
+func (a *APU) SoundWrite(address uint16, value byte) {
+	if address >= waveRamStart && address <= waveRamEnd {
+		a.wave.Ram[address-waveRamStart] = value
+		return
+	}
+
+	if address == nr52RegisterAddr {
+		a.setPower(value&powerBit != 0)
+		return
+	}
+
+	if !a.powered {
+		return
+	}
+	a.registers[address-registersStart] = value
+
+	switch address {
+	case nr10RegisterAddr:
+		a.square1.SweepPeriod = value >> 4 & 0x7
+		a.square1.SweepNegate = value&0x08 != 0
+		a.square1.SweepShift = value & 0x7
+	case nr11RegisterAddr:
+		a.writeSquareLength(&a.square1, value)
+	case nr12RegisterAddr:
+		a.writeEnvelope(&a.square1.Envelope, &a.square1.Enabled, value)
+	case nr13RegisterAddr:
+		a.square1.Frequency = a.square1.Frequency&0x700 | uint16(value)
+	case nr14RegisterAddr:
+		a.writeSquareControl(&a.square1, value)
+
+	case nr21RegisterAddr:
+		a.writeSquareLength(&a.square2, value)
+	case nr22RegisterAddr:
+		a.writeEnvelope(&a.square2.Envelope, &a.square2.Enabled, value)
+	case nr23RegisterAddr:
+		a.square2.Frequency = a.square2.Frequency&0x700 | uint16(value)
+	case nr24RegisterAddr:
+		a.writeSquareControl(&a.square2, value)
+
+	case nr30RegisterAddr:
+		a.wave.DacEnabled = value&0x80 != 0
+		a.wave.Enabled = a.wave.Enabled && a.wave.DacEnabled
+	case nr31RegisterAddr:
+		a.wave.Length.load(256 - int(value))
+	case nr32RegisterAddr:
+		a.wave.VolumeCode = value >> 5 & 0x3
+	case nr33RegisterAddr:
+		a.wave.Frequency = a.wave.Frequency&0x700 | uint16(value)
+	case nr34RegisterAddr:
+		a.wave.Frequency = a.wave.Frequency&0xFF | uint16(value&0x7)<<8
+		a.wave.Length.Enabled = value&lengthEnableBit != 0
+		if value&triggerBit != 0 {
+			a.wave.trigger()
+		}
+
+	case nr41RegisterAddr:
+		a.noise.Length.load(64 - int(value&0x3F))
+	case nr42RegisterAddr:
+		a.writeEnvelope(&a.noise.Envelope, &a.noise.Enabled, value)
+	case nr43RegisterAddr:
+		a.noise.ClockShift = value >> 4
+		a.noise.Narrow = value&0x08 != 0
+		a.noise.Divisor = value & 0x7
+	case nr44RegisterAddr:
+		a.noise.Length.Enabled = value&lengthEnableBit != 0
+		if value&triggerBit != 0 {
+			a.noise.trigger()
+		}
+	}
+}
+
+func (a *APU) writeSquareLength(square *Square, value byte) {
+	square.Duty = value >> 6
+	square.Length.load(64 - int(value&0x3F))
+}
+
+// writeEnvelope sets up an envelope. Turning its DAC off disables the channel.
+func (a *APU) writeEnvelope(envelope *Envelope, enabled *bool, value byte) {
+	envelope.write(value)
+	if !envelope.dacEnabled() {
+		*enabled = false
+	}
+}
+
+func (a *APU) writeSquareControl(square *Square, value byte) {
+	square.Frequency = square.Frequency&0xFF | uint16(value&0x7)<<8
+	square.Length.Enabled = value&lengthEnableBit != 0
+	if value&triggerBit != 0 {
+		square.trigger()
+	}
+}
+
+// setPower turns the APU on or off. Turning it off clears all the registers but the wave RAM.
+func (a *APU) setPower(on bool) {
+	if a.powered == on {
+		return
+	}
+	a.powered = on
+
+	if on {
+		a.sequencerTimer, a.sequencerStep = 0, 0
+		return
+	}
+
+	a.registers = [registerCount]byte{}
+	waveRam := a.wave.Ram
+	a.square1, a.square2, a.wave, a.noise = Square{}, Square{}, Wave{Ram: waveRam}, Noise{}
+}
@@ -0,0 +1,111 @@
+package apu
+
+import "testing"
+
+func runDots(a *APU, dots int) {
+	for i := 0; i < dots; i++ {
+		a.Tick()
+	}
+}
+
+func TestSamples(t *testing.T) {
+	a := New(32768)
+	runDots(a, ClockRate/8)
+
+	samples := a.Samples()
+	if len(samples) != 32768/8*2 {
+		t.Errorf("expected %d samples got %d", 32768/8*2, len(samples))
+	}
+	if len(a.Samples()) != 0 {
+		t.Error("expected samples to be drained")
+	}
+}
+
+func TestSquareChannel(t *testing.T) {
+	a := New(DefaultSampleRate)
+	a.SoundWrite(nr22RegisterAddr, 0xF0) // Full volume
+	a.SoundWrite(nr21RegisterAddr, 0x80|0x3F)
+	a.SoundWrite(nr23RegisterAddr, 0x00)
+	a.SoundWrite(nr24RegisterAddr, triggerBit|lengthEnableBit|0x07)
+
+	if status := a.SoundRead(nr52RegisterAddr); status&0x02 == 0 {
+		t.Fatalf("expected channel 2 to be on, NR52 is %02X", status)
+	}
+
+	var loud int
+	for i := 0; i < 100; i++ {
+		runDots(a, 100)
+		for _, sample := range a.Samples() {
+			if sample > 1000 || sample < -1000 {
+				loud++
+			}
+		}
+	}
+	if loud == 0 {
+		t.Error("expected channel 2 to be heard")
+	}
+
+	// Length is 1, so the channel is disabled by the next length clock
+	runDots(a, 2*sequencerPeriod)
+	if status := a.SoundRead(nr52RegisterAddr); status&0x02 != 0 {
+		t.Errorf("expected channel 2 to be disabled by its length, NR52 is %02X", status)
+	}
+}
+
+func TestDacDisablesChannel(t *testing.T) {
+	a := New(DefaultSampleRate)
+	a.SoundWrite(nr42RegisterAddr, 0xF0)
+	a.SoundWrite(nr44RegisterAddr, triggerBit)
+	if a.SoundRead(nr52RegisterAddr)&0x08 == 0 {
+		t.Fatal("expected channel 4 to be on")
+	}
+
+	a.SoundWrite(nr42RegisterAddr, 0x00)
+	if a.SoundRead(nr52RegisterAddr)&0x08 != 0 {
+		t.Error("expected channel 4 to be turned off with its DAC")
+	}
+}
+
+func TestPower(t *testing.T) {
+	a := New(DefaultSampleRate)
+	a.SoundWrite(waveRamStart, 0x12)
+	a.SoundWrite(nr52RegisterAddr, 0x00)
+
+	if got := a.SoundRead(nr50RegisterAddr); got != 0x00 {
+		t.Errorf("expected NR50 to be cleared got %02X", got)
+	}
+	if got := a.SoundRead(nr52RegisterAddr); got != 0x70 {
+		t.Errorf("expected NR52 to read 70 got %02X", got)
+	}
+
+	a.SoundWrite(nr50RegisterAddr, 0x77)
+	if got := a.SoundRead(nr50RegisterAddr); got != 0x00 {
+		t.Errorf("expected writes to be ignored whilst powered off, NR50 is %02X", got)
+	}
+	if got := a.SoundRead(waveRamStart); got != 0x12 {
+		t.Errorf("expected wave RAM to be kept got %02X", got)
+	}
+
+	a.SoundWrite(nr52RegisterAddr, powerBit)
+	if got := a.SoundRead(nr52RegisterAddr); got != 0xF0 {
+		t.Errorf("expected NR52 to read F0 got %02X", got)
+	}
+}
+
+func TestState(t *testing.T) {
+	a := New(DefaultSampleRate)
+	a.SoundWrite(nr12RegisterAddr, 0xF0)
+	a.SoundWrite(nr14RegisterAddr, triggerBit)
+	runDots(a, 1000)
+	a.Samples()
+	state := a.State()
+
+	runDots(a, 1000)
+	expected := a.Samples()
+
+	a.LoadState(state)
+	runDots(a, 1000)
+	if got := a.Samples(); len(got) != len(expected) || got[len(got)-1] != expected[len(expected)-1] {
+		t.Error("expected the same samples after loading the state")
+	}
+}
@@ -0,0 +1,258 @@
+package apu
+
+// Channel state is exported so it can be saved with the rest of the APU state.
+
+var dutyPatterns = [4][8]byte{
+	{0, 0, 0, 0, 0, 0, 0, 1}, // 12.5%
+	{1, 0, 0, 0, 0, 0, 0, 1}, // 25%
+	{1, 0, 0, 0, 0, 1, 1, 1}, // 50%
+	{0, 1, 1, 1, 1, 1, 1, 0}, // 75%
+}
+
+var noiseDivisors = [8]int{8, 16, 32, 48, 64, 80, 96, 112}
+
+// Length is the length counter, which disables its channel when it reaches 0.
+type Length struct {
+	Counter int
+	Enabled bool
+}
+
+func (l *Length) load(value int) {
+	l.Counter = value
+}
+
+// clock is called at 256Hz by the frame sequencer. It returns false when the channel has to be disabled.
+func (l *Length) clock() bool {
+	if !l.Enabled || l.Counter == 0 {
+		return true
+	}
+
+	l.Counter--
+	return l.Counter > 0
+}
+
+func (l *Length) trigger(max int) {
+	if l.Counter == 0 {
+		l.Counter = max
+	}
+}
+
+// Envelope changes the volume of a channel periodically.
+type Envelope struct {
+	Initial  byte // NRx2 bits 4-7
+	Increase bool // NRx2 bit 3
+	Period   byte // NRx2 bits 0-2
+	Volume   byte
+	Timer    byte
+}
+
+func (e *Envelope) write(value byte) {
+	e.Initial = value >> 4
+	e.Increase = value&0x08 != 0
+	e.Period = value & 0x07
+}
+
+// dacEnabled returns whether the DAC of the channel is on, which is the case when any of NRx2 bits 3-7 is set.
+func (e *Envelope) dacEnabled() bool {
+	return e.Initial != 0 || e.Increase
+}
+
+// clock is called at 64Hz by the frame sequencer.
+func (e *Envelope) clock() {
+	if e.Period == 0 {
+		return
+	}
+
+	e.Timer--
+	if e.Timer > 0 {
+		return
+	}
+	e.Timer = e.Period
+
+	if e.Increase && e.Volume < 15 {
+		e.Volume++
+	} else if !e.Increase && e.Volume > 0 {
+		e.Volume--
+	}
+}
+
+func (e *Envelope) trigger() {
+	e.Volume = e.Initial
+	e.Timer = e.Period
+}
+
+// Square is channel 1, with frequency sweep, or channel 2.
+type Square struct {
+	Enabled   bool
+	Duty      byte // NRx1 bits 6-7
+	Frequency uint16
+	Timer     int
+	Position  byte // Step in the duty pattern
+	Length    Length
+	Envelope  Envelope
+
+	// Frequency sweep, only used by channel 1
+	SweepPeriod  byte // NR10 bits 4-6
+	SweepNegate  bool // NR10 bit 3
+	SweepShift   byte // NR10 bits 0-2
+	SweepTimer   byte
+	SweepEnabled bool
+	SweepShadow  uint16
+}
+
+func (s *Square) tick() {
+	s.Timer--
+	if s.Timer <= 0 {
+		s.Timer = (2048 - int(s.Frequency)) * 4
+		s.Position = (s.Position + 1) & 7
+	}
+}
+
+func (s *Square) output() byte {
+	if !s.Enabled {
+		return 0
+	}
+	return dutyPatterns[s.Duty][s.Position] * s.Envelope.Volume
+}
+
+func (s *Square) trigger() {
+	s.Enabled = s.Envelope.dacEnabled()
+	s.Length.trigger(64)
+	s.Timer = (2048 - int(s.Frequency)) * 4
+	s.Envelope.trigger()
+
+	s.SweepShadow = s.Frequency
+	s.reloadSweepTimer()
+	s.SweepEnabled = s.SweepPeriod != 0 || s.SweepShift != 0
+	if s.SweepShift != 0 {
+		s.sweepFrequency() // Only checks for overflow
+	}
+}
+
+func (s *Square) reloadSweepTimer() {
+	s.SweepTimer = s.SweepPeriod
+	if s.SweepTimer == 0 {
+		s.SweepTimer = 8
+	}
+}
+
+// clockSweep is called at 128Hz by the frame sequencer.
+func (s *Square) clockSweep() {
+	s.SweepTimer--
+	if s.SweepTimer > 0 {
+		return
+	}
+	s.reloadSweepTimer()
+
+	if !s.SweepEnabled || s.SweepPeriod == 0 {
+		return
+	}
+
+	frequency := s.sweepFrequency()
+	if frequency <= 2047 && s.SweepShift != 0 {
+		s.SweepShadow = frequency
+		s.Frequency = frequency
+		s.sweepFrequency()
+	}
+}
+
+// sweepFrequency returns the next frequency of the sweep, disabling the channel if it overflows.
+func (s *Square) sweepFrequency() uint16 {
+	delta := s.SweepShadow >> s.SweepShift
+	frequency := s.SweepShadow + delta
+	if s.SweepNegate {
+		frequency = s.SweepShadow - delta
+	}
+
+	if frequency > 2047 {
+		s.Enabled = false
+	}
+	return frequency
+}
+
+// Wave is channel 3, which plays the 32 4-bit samples of the wave RAM.
+type Wave struct {
+	Enabled    bool
+	DacEnabled bool // NR30 bit 7
+	VolumeCode byte // NR32 bits 5-6
+	Frequency  uint16
+	Timer      int
+	Position   byte // Sample being played
+	Length     Length
+	Ram        [16]byte
+}
+
+// waveVolumeShifts are the right shifts applied to the samples for each volume code: mute, 100%, 50% and 25%.
+var waveVolumeShifts = [4]byte{4, 0, 1, 2}
+
+func (w *Wave) tick() {
+	w.Timer--
+	if w.Timer <= 0 {
+		w.Timer = (2048 - int(w.Frequency)) * 2
+		w.Position = (w.Position + 1) & 31
+	}
+}
+
+func (w *Wave) output() byte {
+	if !w.Enabled {
+		return 0
+	}
+
+	sample := w.Ram[w.Position/2]
+	if w.Position%2 == 0 {
+		sample >>= 4
+	}
+	return (sample & 0x0F) >> waveVolumeShifts[w.VolumeCode]
+}
+
+func (w *Wave) trigger() {
+	w.Enabled = w.DacEnabled
+	w.Length.trigger(256)
+	w.Timer = (2048 - int(w.Frequency)) * 2
+	w.Position = 0
+}
+
+// Noise is channel 4, which outputs the lowest bit of a linear feedback shift register.
+type Noise struct {
+	Enabled    bool
+	ClockShift byte // NR43 bits 4-7
+	Narrow     bool // NR43 bit 3, uses a 7 bit LFSR
+	Divisor    byte // NR43 bits 0-2
+	Timer      int
+	Lfsr       uint16
+	Length     Length
+	Envelope   Envelope
+}
+
+func (n *Noise) period() int {
+	return noiseDivisors[n.Divisor] << n.ClockShift
+}
+
+func (n *Noise) tick() {
+	n.Timer--
+	if n.Timer > 0 {
+		return
+	}
+	n.Timer = n.period()
+
+	feedback := (n.Lfsr ^ n.Lfsr>>1) & 1
+	n.Lfsr = n.Lfsr>>1 | feedback<<14
+	if n.Narrow {
+		n.Lfsr = n.Lfsr&^(1<<6) | feedback<<6
+	}
+}
+
+func (n *Noise) output() byte {
+	if !n.Enabled || n.Lfsr&1 != 0 {
+		return 0
+	}
+	return n.Envelope.Volume
+}
+
+func (n *Noise) trigger() {
+	n.Enabled = n.Envelope.dacEnabled()
+	n.Length.trigger(64)
+	n.Timer = n.period()
+	n.Lfsr = 0x7FFF
+	n.Envelope.trigger()
+}
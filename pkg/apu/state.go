@@ -0,0 +1,49 @@
+package apu
+
+// State is a snapshot of the APU, used for save states. Samples not read yet are not part of it.
+type State struct {
+	Registers [registerCount]byte
+	Powered   bool
+
+	Square1 Square
+	Square2 Square
+	Wave    Wave
+	Noise   Noise
+
+	SequencerTimer int
+	SequencerStep  byte
+	SampleCounter  int
+	Capacitors     [2]float64
+}
+
+// State returns a snapshot of the APU.
+func (a *APU) State() State {
+	return State{
+		Registers:      a.registers,
+		Powered:        a.powered,
+		Square1:        a.square1,
+		Square2:        a.square2,
+		Wave:           a.wave,
+		Noise:          a.noise,
+		SequencerTimer: a.sequencerTimer,
+		SequencerStep:  a.sequencerStep,
+		SampleCounter:  a.sampleCounter,
+		Capacitors:     a.capacitors,
+	}
+}
+
+// LoadState restores a snapshot taken with State. The samples not read yet are dropped.
+func (a *APU) LoadState(state State) {
+	a.registers = state.Registers
+	a.powered = state.Powered
+	a.square1 = state.Square1
+	a.square2 = state.Square2
+	a.wave = state.Wave
+	a.noise = state.Noise
+	a.sequencerTimer = state.SequencerTimer
+	a.sequencerStep = state.SequencerStep
+	a.sampleCounter = state.SampleCounter
+	a.capacitors = state.Capacitors
+
+	a.Samples()
+}
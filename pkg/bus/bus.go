@@ -3,7 +3,6 @@ package bus
 import (
 	"github.com/mikeletux/goboy/pkg/cart"
 	"github.com/mikeletux/goboy/pkg/log"
-	"time"
 )
 
 type DataBusInterface interface {
@@ -11,6 +10,7 @@ type DataBusInterface interface {
 	BusWrite(address uint16, value byte)
 	BusRead16(address uint16) uint16
 	BusWrite16(address uint16, value uint16)
+	RomBank(address uint16) int
 
 	// Methods regarding Timer
 	IncrementTimerDiv() uint16 // Returns the Div value after increasing
@@ -18,6 +18,30 @@ type DataBusInterface interface {
 
 	// Methods regarding DMA
 	DmaTick()
+
+	// Methods regarding Serial
+	SerialTick()
+
+	// Accesses made by components other than the CPU, which are not reported to the hooks
+	Peek(address uint16) byte
+	Poke(address uint16, value byte)
+
+	// Methods regarding Joypad
+	ApplyQueuedJoypadButtons()
+
+	// Methods regarding LCD
+	SetLcdLy(value byte)
+	SetLcdStatus(value byte)
+
+	// Methods regarding CGB
+	IsCgbMode() bool
+	ReadVRamBank(bank byte, address uint16) byte
+	ReadBgPaletteRam(index byte) byte
+	ReadObjPaletteRam(index byte) byte
+	HdmaHBlank()
+	TakeHdmaStall() int
+	SwitchSpeed() bool
+	IsDoubleSpeed() bool
 }
 
 // Bus represents the whole Game boy bus
@@ -32,25 +56,52 @@ type Bus struct {
 	ieRegister byte
 
 	dma *Dma
+
+	// CGB only components
+	cgbMode    bool
+	hdma       *Hdma
+	speed      *speed
+	bgPalette  *palette
+	objPalette *palette
+
+	// Access hooks
+	hooks       []hook
+	nextHookID  int
+	inHook      bool
+	hookContext HookContext
 }
 
 // NewBus initializes a bus given a type that implements the cart.CartridgeInterface interface.
+// CGB mode is enabled if the cartridge header says the game supports it.
 func NewBus(cartridge cart.CartridgeInterface, logger log.Logger) *Bus {
 	dma := initDma()
 
 	return &Bus{
-		Cartridge: cartridge,
-		logger:    logger,
-		vram:      NewVRam(logger),
-		ram:       NewRam(logger),
-		oam:       NewOam(logger),
-		io:        NewIO(logger, dma),
-		dma:       dma,
+		Cartridge:  cartridge,
+		logger:     logger,
+		vram:       NewVRam(logger),
+		ram:        NewRam(logger),
+		oam:        NewOam(logger),
+		io:         NewIO(logger, dma),
+		dma:        dma,
+		cgbMode:    isCgbModeCartridge(cartridge),
+		hdma:       initHdma(),
+		speed:      &speed{},
+		bgPalette:  &palette{},
+		objPalette: &palette{},
 	}
 }
 
 // BusRead given an address it returns the value from that bus memory area.
 func (b *Bus) BusRead(address uint16) byte {
+	value := b.read(address)
+	if len(b.hooks) > 0 {
+		b.callHooks(AccessRead, address, value)
+	}
+	return value
+}
+
+func (b *Bus) read(address uint16) byte {
 	switch {
 	case address <= RomBank01NNEnd: // Cartridge ROM area
 		return b.Cartridge.CartRead(address)
@@ -77,6 +128,9 @@ func (b *Bus) BusRead(address uint16) byte {
 		return 0x0
 
 	case address >= IORegistersStart && address <= IORegistersEnd: // IO Registers area
+		if b.cgbMode && isCgbRegister(address) {
+			return b.readCgbRegister(address)
+		}
 		return b.io.IORead(address)
 
 	case address >= HighRamStart && address <= HighRamEnd: // High RAM area
@@ -94,6 +148,13 @@ func (b *Bus) BusRead(address uint16) byte {
 
 // BusWrite writes a byte into bus given a bus memory area.
 func (b *Bus) BusWrite(address uint16, value byte) {
+	if len(b.hooks) > 0 {
+		b.callHooks(AccessWrite, address, value)
+	}
+	b.write(address, value)
+}
+
+func (b *Bus) write(address uint16, value byte) {
 	switch {
 	case address <= RomBank01NNEnd: // Cartridge ROM area
 		b.Cartridge.CartWrite(address, value)
@@ -117,6 +178,10 @@ func (b *Bus) BusWrite(address uint16, value byte) {
 		return
 
 	case address >= IORegistersStart && address <= IORegistersEnd: // IO Registers area
+		if b.cgbMode && isCgbRegister(address) {
+			b.writeCgbRegister(address, value)
+			return
+		}
 		b.io.IOWrite(address, value)
 
 	case address >= HighRamStart && address <= HighRamEnd: // High RAM area
@@ -144,6 +209,19 @@ func (b *Bus) BusWrite16(address uint16, value uint16) {
 	b.BusWrite(address+1, byte((value>>8)&0xFF)) // High
 }
 
+// RomBank returns the cartridge ROM bank mapped at the given address. Addresses out of the switchable ROM
+// area return bank 0.
+func (b *Bus) RomBank(address uint16) int {
+	if address < RomBank01NNStart || address > RomBank01NNEnd {
+		return 0
+	}
+
+	if cartridge, ok := b.Cartridge.(cart.BankedCartridge); ok {
+		return cartridge.CurrentRomBank()
+	}
+	return 1
+}
+
 func (b *Bus) IncrementTimerDiv() uint16 {
 	b.io.timer.divReg++
 	return b.io.timer.divReg
@@ -153,6 +231,17 @@ func (b *Bus) GetTimerDiv() uint16 {
 	return b.io.timer.divReg
 }
 
+// SetLcdLy sets LY register. It is meant to be used by the PPU since LY is read only for the CPU.
+func (b *Bus) SetLcdLy(value byte) {
+	b.io.lcd.ly = value
+}
+
+// SetLcdStatus sets the whole STAT register, including the read only mode and LYC=LY bits.
+// It is meant to be used by the PPU.
+func (b *Bus) SetLcdStatus(value byte) {
+	b.io.lcd.stat = value & 0x7F
+}
+
 func (b *Bus) DmaTick() {
 	if !b.dma.active {
 		return
@@ -163,12 +252,11 @@ func (b *Bus) DmaTick() {
 		return
 	}
 
-	b.BusWrite(OamStart+uint16(b.dma.byte), b.BusRead(uint16(b.dma.value)*0x100+uint16(b.dma.byte)))
+	b.oam.writeOam(OamStart+uint16(b.dma.byte), b.read(uint16(b.dma.value)*0x100+uint16(b.dma.byte)))
 	b.dma.byte++
 	b.dma.active = b.dma.byte < 0xA0
 
 	if !b.dma.active {
 		b.logger.Debugf("DMA DONE!!!!!")
-		time.Sleep(2 * time.Second) // HACK
 	}
 }
@@ -1,6 +1,7 @@
 package bus
 
 import (
+	"bytes"
 	"github.com/mikeletux/goboy/pkg/log"
 	"testing"
 )
@@ -48,3 +49,366 @@ func TestIOTimer(t *testing.T) {
 		}
 	}
 }
+
+// cgbCartridgeMock is a ROM only cartridge whose header enables CGB mode
+type cgbCartridgeMock struct{}
+
+func (c *cgbCartridgeMock) CartRead(address uint16) byte {
+	if address == 0x143 {
+		return 0x80
+	}
+	return byte(address)
+}
+
+func (c *cgbCartridgeMock) CartWrite(address uint16, value byte) {}
+
+func TestCgbBanking(t *testing.T) {
+	bus := NewBus(&cgbCartridgeMock{}, &log.NilLogger{})
+	if !bus.IsCgbMode() {
+		t.Fatal("expected bus to be in CGB mode")
+	}
+
+	// Write a different value in every WRAM bank
+	for bank := byte(1); bank < workingRamBanks; bank++ {
+		bus.BusWrite(svbkRegisterAddr, bank)
+		bus.BusWrite(WorkRam1Start, bank*0x10)
+	}
+	for bank := byte(1); bank < workingRamBanks; bank++ {
+		bus.BusWrite(svbkRegisterAddr, bank)
+		if got := bus.BusRead(WorkRam1Start); got != bank*0x10 {
+			t.Errorf("WRAM bank %d: expected %X got %X", bank, bank*0x10, got)
+		}
+	}
+
+	// Bank 0 selects bank 1
+	bus.BusWrite(svbkRegisterAddr, 0)
+	if got := bus.BusRead(svbkRegisterAddr); got != 0xF9 {
+		t.Errorf("SVBK: expected %X got %X", 0xF9, got)
+	}
+
+	// Same address on both VRAM banks
+	bus.BusWrite(vbkRegisterAddr, 0)
+	bus.BusWrite(VramStart, 0xAA)
+	bus.BusWrite(vbkRegisterAddr, 1)
+	bus.BusWrite(VramStart, 0xBB)
+	if got := bus.ReadVRamBank(0, VramStart); got != 0xAA {
+		t.Errorf("VRAM bank 0: expected %X got %X", 0xAA, got)
+	}
+	if got := bus.BusRead(VramStart); got != 0xBB {
+		t.Errorf("VRAM bank 1: expected %X got %X", 0xBB, got)
+	}
+}
+
+func TestCgbPaletteAutoIncrement(t *testing.T) {
+	bus := NewBus(&cgbCartridgeMock{}, &log.NilLogger{})
+
+	bus.BusWrite(bcpsRegisterAddr, 0x80|0x3E) // Auto increment from the last color of palette 7
+	bus.BusWrite(bcpdRegisterAddr, 0x1F)
+	bus.BusWrite(bcpdRegisterAddr, 0x7C)
+	bus.BusWrite(bcpdRegisterAddr, 0x12) // Index wraps around to 0
+
+	if got := bus.ReadBgPaletteRam(0x3E); got != 0x1F {
+		t.Errorf("expected %X got %X", 0x1F, got)
+	}
+	if got := bus.ReadBgPaletteRam(0x3F); got != 0x7C {
+		t.Errorf("expected %X got %X", 0x7C, got)
+	}
+	if got := bus.ReadBgPaletteRam(0x0); got != 0x12 {
+		t.Errorf("expected %X got %X", 0x12, got)
+	}
+	if got := bus.BusRead(bcpsRegisterAddr); got != 0xC1 {
+		t.Errorf("BCPS: expected %X got %X", 0xC1, got)
+	}
+}
+
+func TestHdma(t *testing.T) {
+	bus := NewBus(&cgbCartridgeMock{}, &log.NilLogger{})
+
+	// General purpose DMA of 2 blocks from ROM 0x1230 to VRAM 0x8100
+	bus.BusWrite(hdma1RegisterAddr, 0x12)
+	bus.BusWrite(hdma2RegisterAddr, 0x34) // Lower 4 bits are ignored
+	bus.BusWrite(hdma3RegisterAddr, 0x81)
+	bus.BusWrite(hdma4RegisterAddr, 0x00)
+	bus.BusWrite(hdma5RegisterAddr, 0x01)
+
+	for i := uint16(0); i < 0x20; i++ {
+		if got := bus.BusRead(0x8100 + i); got != byte(0x30+i) {
+			t.Errorf("general purpose DMA 0x%X: expected %X got %X", 0x8100+i, byte(0x30+i), got)
+		}
+	}
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0xFF {
+		t.Errorf("HDMA5 after general purpose DMA: expected %X got %X", 0xFF, got)
+	}
+	if got := bus.TakeHdmaStall(); got != 2*hdmaBlockCycles {
+		t.Errorf("general purpose DMA stall: expected %d cycles got %d", 2*hdmaBlockCycles, got)
+	}
+	if got := bus.TakeHdmaStall(); got != 0 {
+		t.Errorf("expected the stall to be taken once, got %d cycles", got)
+	}
+
+	// HBlank DMA of 2 blocks from ROM 0x4000 to VRAM 0x9000, started whilst the PPU draws a line
+	bus.SetLcdStatus(0x03)
+	bus.BusWrite(hdma1RegisterAddr, 0x40)
+	bus.BusWrite(hdma2RegisterAddr, 0x00)
+	bus.BusWrite(hdma3RegisterAddr, 0x10)
+	bus.BusWrite(hdma4RegisterAddr, 0x00)
+	bus.BusWrite(hdma5RegisterAddr, 0x81)
+
+	bus.HdmaHBlank()
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0x00 {
+		t.Errorf("HDMA5 after first HBlank: expected %X got %X", 0x00, got)
+	}
+	if got := bus.BusRead(0x900F); got != 0x0F {
+		t.Errorf("HBlank DMA 0x900F: expected %X got %X", 0x0F, got)
+	}
+
+	bus.HdmaHBlank()
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0xFF {
+		t.Errorf("HDMA5 after second HBlank: expected %X got %X", 0xFF, got)
+	}
+	if got := bus.BusRead(0x901F); got != 0x1F {
+		t.Errorf("HBlank DMA 0x901F: expected %X got %X", 0x1F, got)
+	}
+}
+
+func TestHdmaStartInHBlank(t *testing.T) {
+	bus := NewBus(&cgbCartridgeMock{}, &log.NilLogger{})
+	bus.SetLcdStatus(0x00) // HBlank
+
+	// HBlank DMA of 3 blocks from ROM 0x4000 to VRAM 0x9000, the first one is copied straight away
+	bus.BusWrite(hdma1RegisterAddr, 0x40)
+	bus.BusWrite(hdma2RegisterAddr, 0x00)
+	bus.BusWrite(hdma3RegisterAddr, 0x10)
+	bus.BusWrite(hdma4RegisterAddr, 0x00)
+	bus.BusWrite(hdma5RegisterAddr, 0x82)
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0x01 {
+		t.Errorf("HDMA5 after starting in HBlank: expected %X got %X", 0x01, got)
+	}
+	if got := bus.BusRead(0x900F); got != 0x0F {
+		t.Errorf("HBlank DMA 0x900F: expected %X got %X", 0x0F, got)
+	}
+
+	// Writing bit 7 again restarts the transfer with the new length, from where it was
+	bus.SetLcdStatus(0x03)
+	bus.BusWrite(hdma5RegisterAddr, 0x80)
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0x00 {
+		t.Errorf("HDMA5 after restarting: expected %X got %X", 0x00, got)
+	}
+	bus.HdmaHBlank()
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0xFF {
+		t.Errorf("HDMA5 after the restarted transfer: expected %X got %X", 0xFF, got)
+	}
+	if got := bus.BusRead(0x901F); got != 0x1F {
+		t.Errorf("HBlank DMA 0x901F: expected %X got %X", 0x1F, got)
+	}
+
+	// With the LCD off the first block is copied straight away too
+	bus.BusWrite(0xFF40, 0x00)
+	bus.BusWrite(hdma5RegisterAddr, 0x81)
+	if got := bus.BusRead(hdma5RegisterAddr); got != 0x00 {
+		t.Errorf("HDMA5 after starting with the LCD off: expected %X got %X", 0x00, got)
+	}
+	if got := bus.BusRead(0x902F); got != 0x2F {
+		t.Errorf("HBlank DMA 0x902F: expected %X got %X", 0x2F, got)
+	}
+}
+
+// joypadDeviceMock records P1 writes and always selects the same controller
+type joypadDeviceMock struct {
+	writes       []byte
+	controllerID byte
+}
+
+func (j *joypadDeviceMock) JoypadWrite(value byte) { j.writes = append(j.writes, value) }
+func (j *joypadDeviceMock) ControllerID() byte     { return j.controllerID }
+
+func TestJoypad(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	bus.SetJoypadButtons(JoypadStart | JoypadUp)
+
+	if bus.BusRead(interruptFlagRegisterAddr)&joypadInterruptFlag == 0 {
+		t.Error("expected joypad interrupt to be requested")
+	}
+
+	testCases := []struct {
+		selection    byte
+		expectedRead byte
+	}{
+		{selection: 0x20, expectedRead: 0xEB}, // Direction keys, up pressed
+		{selection: 0x10, expectedRead: 0xD7}, // Action buttons, start pressed
+		{selection: 0x30, expectedRead: 0xFF}, // Nothing selected
+	}
+
+	for _, v := range testCases {
+		bus.BusWrite(joypadRegisterAddr, v.selection)
+		if got := bus.BusRead(joypadRegisterAddr); got != v.expectedRead {
+			t.Errorf("selection %X: expected %X got %X", v.selection, v.expectedRead, got)
+		}
+	}
+
+	device := &joypadDeviceMock{controllerID: 1}
+	bus.SetJoypadDevice(device)
+	bus.BusWrite(joypadRegisterAddr, 0x30)
+	if got := bus.BusRead(joypadRegisterAddr); got != 0xFE {
+		t.Errorf("expected controller 1 to read %X got %X", 0xFE, got)
+	}
+	if len(device.writes) != 1 || device.writes[0] != 0x30 {
+		t.Errorf("expected device to receive P1 write, got %v", device.writes)
+	}
+}
+
+func TestQueueJoypadButtons(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	bus.QueueJoypadButtons(JoypadA)
+	if bus.BusRead(interruptFlagRegisterAddr)&joypadInterruptFlag != 0 {
+		t.Fatal("expected queued buttons not to be set before they are applied")
+	}
+
+	bus.ApplyQueuedJoypadButtons()
+	bus.BusWrite(joypadRegisterAddr, 0x10)
+	if got := bus.BusRead(joypadRegisterAddr); got != 0xDE {
+		t.Errorf("expected A to be pressed got %X", got)
+	}
+
+	bus.QueueJoypadButtons(0) // Releasing every button is queued too
+	bus.ApplyQueuedJoypadButtons()
+	if got := bus.BusRead(joypadRegisterAddr); got != 0xDF {
+		t.Errorf("expected no button to be pressed got %X", got)
+	}
+}
+
+// serialDeviceMock answers every transfer with a fixed byte and records what it receives
+type serialDeviceMock struct {
+	received []byte
+	answer   byte
+	external bool
+}
+
+func (s *serialDeviceMock) Transfer(out byte) byte {
+	s.received = append(s.received, out)
+	return s.answer
+}
+
+func (s *serialDeviceMock) Tick(out byte, waiting bool) (byte, bool) {
+	if !s.external || !waiting {
+		return 0, false
+	}
+	s.received = append(s.received, out)
+	return s.answer, true
+}
+
+func TestSerialInternalClock(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	device := &serialDeviceMock{answer: 0x5A}
+	bus.SetSerialDevice(device)
+
+	bus.BusWrite(serialTransferDataAddr, 0x81)
+	bus.BusWrite(serialTransferControlAddr, 0x81)
+
+	for i := 0; i < 8*serialBitCycles-1; i++ {
+		bus.SerialTick()
+	}
+	if bus.BusRead(serialTransferControlAddr)&serialTransferStartFlag == 0 {
+		t.Fatal("transfer finished too early")
+	}
+	if got := bus.BusRead(serialTransferDataAddr); got != 0xAD { // 7 bits shifted
+		t.Errorf("expected SB %X during transfer got %X", 0xAD, got)
+	}
+
+	bus.SerialTick()
+	if got := bus.BusRead(serialTransferControlAddr); got != 0x7D {
+		t.Errorf("expected SC %X got %X", 0x7D, got)
+	}
+	if got := bus.BusRead(serialTransferDataAddr); got != 0x5A {
+		t.Errorf("expected SB %X got %X", 0x5A, got)
+	}
+	if bus.BusRead(interruptFlagRegisterAddr)&serialInterruptFlag == 0 {
+		t.Error("expected serial interrupt to be requested")
+	}
+	if len(device.received) != 1 || device.received[0] != 0x81 {
+		t.Errorf("expected device to receive 0x81, got %v", device.received)
+	}
+}
+
+func TestSerialOutput(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	var output bytes.Buffer
+	bus.SetSerialOutput(&output)
+
+	for _, c := range []byte("ok") {
+		bus.BusWrite(serialTransferDataAddr, c)
+		bus.BusWrite(serialTransferControlAddr, 0x81)
+		for i := 0; i < 8*serialBitCycles; i++ {
+			bus.SerialTick()
+		}
+	}
+
+	if output.String() != "ok" {
+		t.Errorf("expected serial output %q got %q", "ok", output.String())
+	}
+}
+
+func TestSerialExternalClock(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	device := &serialDeviceMock{answer: 0x42}
+	bus.SetSerialDevice(device)
+
+	bus.BusWrite(serialTransferDataAddr, 0x24)
+	bus.BusWrite(serialTransferControlAddr, 0x80)
+
+	bus.SerialTick()
+	if bus.BusRead(interruptFlagRegisterAddr)&serialInterruptFlag != 0 {
+		t.Fatal("transfer finished without the device clocking it")
+	}
+
+	device.external = true
+	bus.SerialTick()
+	if got := bus.BusRead(serialTransferDataAddr); got != 0x42 {
+		t.Errorf("expected SB %X got %X", 0x42, got)
+	}
+	if bus.BusRead(interruptFlagRegisterAddr)&serialInterruptFlag == 0 {
+		t.Error("expected serial interrupt to be requested")
+	}
+	if len(device.received) != 1 || device.received[0] != 0x24 {
+		t.Errorf("expected device to receive 0x24, got %v", device.received)
+	}
+}
+
+// hookContextMock always reports the same CPU state
+type hookContextMock struct{}
+
+func (h hookContextMock) InstructionPC() uint16 { return 0x150 }
+func (h hookContextMock) Cycles() uint64        { return 1000 }
+
+func TestHooks(t *testing.T) {
+	bus := NewBus(nil, &log.NilLogger{})
+	bus.SetHookContext(hookContextMock{})
+
+	var accesses []Access
+	id := bus.AddHook(func(access Access) {
+		accesses = append(accesses, access)
+		bus.BusRead(0xC001) // Accesses done by hooks are not reported
+	})
+
+	bus.BusWrite(0xC000, 0x42)
+	bus.BusRead(0xC000)
+
+	expected := []Access{
+		{Type: AccessWrite, Address: 0xC000, Value: 0x42, PC: 0x150, Cycle: 1000},
+		{Type: AccessRead, Address: 0xC000, Value: 0x42, PC: 0x150, Cycle: 1000},
+	}
+	if len(accesses) != len(expected) {
+		t.Fatalf("expected %d accesses got %v", len(expected), accesses)
+	}
+	for i := range expected {
+		if accesses[i] != expected[i] {
+			t.Errorf("access %d: expected %+v got %+v", i, expected[i], accesses[i])
+		}
+	}
+
+	bus.RemoveHook(id)
+	bus.BusRead(0xC000)
+	if len(accesses) != len(expected) {
+		t.Error("expected no accesses to be reported after removing the hook")
+	}
+}
@@ -0,0 +1,180 @@
+package bus
+
+import "github.com/mikeletux/goboy/pkg/cart"
+
+// CGB register addresses
+const (
+	key1RegisterAddr  uint16 = 0xFF4D
+	vbkRegisterAddr   uint16 = 0xFF4F
+	hdma1RegisterAddr uint16 = 0xFF51
+	hdma2RegisterAddr uint16 = 0xFF52
+	hdma3RegisterAddr uint16 = 0xFF53
+	hdma4RegisterAddr uint16 = 0xFF54
+	hdma5RegisterAddr uint16 = 0xFF55
+	bcpsRegisterAddr  uint16 = 0xFF68
+	bcpdRegisterAddr  uint16 = 0xFF69
+	ocpsRegisterAddr  uint16 = 0xFF6A
+	ocpdRegisterAddr  uint16 = 0xFF6B
+	svbkRegisterAddr  uint16 = 0xFF70
+)
+
+const paletteRamSize = 64 // 8 palettes x 4 colors x 2 bytes
+
+// speed models KEY1 register, used to switch between normal and double speed.
+type speed struct {
+	prepare     bool // Bit 0, armed speed switch that will take place on next STOP
+	doubleSpeed bool // Bit 7, current speed
+}
+
+func (s *speed) read() byte {
+	value := byte(0x7E)
+	if s.doubleSpeed {
+		value |= 0x80
+	}
+	if s.prepare {
+		value |= 0x1
+	}
+	return value
+}
+
+// palette models the CGB color palette RAM and its specification register (BCPS/OCPS).
+// Each color is stored as two bytes in little endian: Bit 0-4 red, Bit 5-9 green, Bit 10-14 blue.
+type palette struct {
+	index         byte // Bit 0-5
+	autoIncrement bool // Bit 7
+	data          [paletteRamSize]byte
+}
+
+func (p *palette) readSpecification() byte {
+	value := 0x40 | p.index // Bit 6 is unused and always reads 1
+	if p.autoIncrement {
+		value |= 0x80
+	}
+	return value
+}
+
+func (p *palette) writeSpecification(value byte) {
+	p.index = value & 0x3F
+	p.autoIncrement = value&0x80 != 0
+}
+
+func (p *palette) readData() byte {
+	return p.data[p.index]
+}
+
+func (p *palette) writeData(value byte) {
+	p.data[p.index] = value
+	if p.autoIncrement {
+		p.index = (p.index + 1) & 0x3F
+	}
+}
+
+// isCgbModeCartridge checks the cartridge header to know if the game supports CGB functions.
+func isCgbModeCartridge(cartridge cart.CartridgeInterface) bool {
+	if cartridge == nil {
+		return false
+	}
+
+	return cart.IsCgbFlag(cartridge.CartRead(cart.CgbFlagAddr))
+}
+
+func isCgbRegister(address uint16) bool {
+	switch address {
+	case key1RegisterAddr, vbkRegisterAddr, hdma1RegisterAddr, hdma2RegisterAddr, hdma3RegisterAddr,
+		hdma4RegisterAddr, hdma5RegisterAddr, bcpsRegisterAddr, bcpdRegisterAddr, ocpsRegisterAddr,
+		ocpdRegisterAddr, svbkRegisterAddr:
+		return true
+	}
+	return false
+}
+
+func (b *Bus) readCgbRegister(address uint16) byte {
+	switch address {
+	case key1RegisterAddr:
+		return b.speed.read()
+	case vbkRegisterAddr:
+		return b.vram.readBankRegister()
+	case hdma5RegisterAddr:
+		return b.hdma.readControl()
+	case bcpsRegisterAddr:
+		return b.bgPalette.readSpecification()
+	case bcpdRegisterAddr:
+		return b.bgPalette.readData()
+	case ocpsRegisterAddr:
+		return b.objPalette.readSpecification()
+	case ocpdRegisterAddr:
+		return b.objPalette.readData()
+	case svbkRegisterAddr:
+		return b.ram.readBankRegister()
+	default: // HDMA1-4 are write only
+		return 0xFF
+	}
+}
+
+func (b *Bus) writeCgbRegister(address uint16, value byte) {
+	switch address {
+	case key1RegisterAddr:
+		b.speed.prepare = value&0x1 == 0x1
+	case vbkRegisterAddr:
+		b.vram.writeBankRegister(value)
+	case hdma1RegisterAddr:
+		b.hdma.writeSourceHigh(value)
+	case hdma2RegisterAddr:
+		b.hdma.writeSourceLow(value)
+	case hdma3RegisterAddr:
+		b.hdma.writeDestinationHigh(value)
+	case hdma4RegisterAddr:
+		b.hdma.writeDestinationLow(value)
+	case hdma5RegisterAddr:
+		b.writeHdmaControl(value)
+	case bcpsRegisterAddr:
+		b.bgPalette.writeSpecification(value)
+	case bcpdRegisterAddr:
+		b.bgPalette.writeData(value)
+	case ocpsRegisterAddr:
+		b.objPalette.writeSpecification(value)
+	case ocpdRegisterAddr:
+		b.objPalette.writeData(value)
+	case svbkRegisterAddr:
+		b.ram.writeBankRegister(value)
+	}
+}
+
+// IsCgbMode returns true if the bus is running in Game Boy Color mode.
+func (b *Bus) IsCgbMode() bool {
+	return b.cgbMode
+}
+
+// ReadVRamBank returns a byte from a given VRAM bank regardless of the bank selected by VBK.
+func (b *Bus) ReadVRamBank(bank byte, address uint16) byte {
+	return b.vram.readVRamBank(bank, address)
+}
+
+// ReadBgPaletteRam returns a byte from the CGB background palette RAM.
+func (b *Bus) ReadBgPaletteRam(index byte) byte {
+	return b.bgPalette.data[index&0x3F]
+}
+
+// ReadObjPaletteRam returns a byte from the CGB object palette RAM.
+func (b *Bus) ReadObjPaletteRam(index byte) byte {
+	return b.objPalette.data[index&0x3F]
+}
+
+// SwitchSpeed switches between normal and double speed if a switch was armed through KEY1.
+// It is called by the CPU when executing STOP and returns true if the speed was switched.
+func (b *Bus) SwitchSpeed() bool {
+	if !b.cgbMode || !b.speed.prepare {
+		return false
+	}
+
+	b.speed.prepare = false
+	b.speed.doubleSpeed = !b.speed.doubleSpeed
+	b.io.timer.divReg = 0 // STOP resets DIV
+
+	return true
+}
+
+// IsDoubleSpeed returns true if the CGB is running at double speed.
+func (b *Bus) IsDoubleSpeed() bool {
+	return b.speed.doubleSpeed
+}
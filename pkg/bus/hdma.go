@@ -0,0 +1,131 @@
+package bus
+
+const (
+	hdmaBlockSize     uint16 = 0x10
+	hdmaInactiveValue byte   = 0xFF
+
+	// hdmaBlockCycles is the number of machine cycles the CPU is stopped for every block a general purpose DMA
+	// copies. It is doubled in double speed, since the copy takes the same time.
+	hdmaBlockCycles = 8
+)
+
+// Hdma models the CGB VRAM DMA. It can either copy all the data at once (general purpose DMA) or copy
+// 0x10 bytes every time the PPU enters HBlank (HBlank DMA).
+type Hdma struct {
+	source       uint16 // HDMA1 and HDMA2, lower 4 bits are ignored
+	destination  uint16 // HDMA3 and HDMA4, only bits 12-4 are used since it always points to VRAM
+	length       byte   // HDMA5 bits 0-6, number of 0x10 blocks left minus 1
+	hblankActive bool   // HDMA5 bit 7 while an HBlank DMA is running
+	finished     bool   // True when no HBlank DMA is running nor has been cancelled
+	stall        int    // Machine cycles the CPU has to be stopped for the last general purpose DMA
+}
+
+func initHdma() *Hdma {
+	return &Hdma{
+		length:   0x7F,
+		finished: true,
+	}
+}
+
+func (h *Hdma) writeSourceHigh(value byte) {
+	h.source = uint16(value)<<8 | h.source&0x00F0
+}
+
+func (h *Hdma) writeSourceLow(value byte) {
+	h.source = h.source&0xFF00 | uint16(value&0xF0)
+}
+
+func (h *Hdma) writeDestinationHigh(value byte) {
+	h.destination = uint16(value&0x1F)<<8 | h.destination&0x00F0
+}
+
+func (h *Hdma) writeDestinationLow(value byte) {
+	h.destination = h.destination&0x1F00 | uint16(value&0xF0)
+}
+
+func (h *Hdma) readControl() byte {
+	if h.hblankActive {
+		return h.length
+	}
+
+	if h.finished {
+		return hdmaInactiveValue
+	}
+
+	return 0x80 | h.length // HBlank DMA was cancelled, report how many blocks were left
+}
+
+// nextBlock returns the source and destination addresses for the next block and moves both pointers forward.
+func (h *Hdma) nextBlock() (uint16, uint16) {
+	source, destination := h.source, VramStart+h.destination
+	h.source += hdmaBlockSize
+	h.destination = (h.destination + hdmaBlockSize) & 0x1FF0
+	return source, destination
+}
+
+// HdmaHBlank copies a 0x10 bytes block into VRAM if an HBlank DMA is running. The PPU calls it every time it
+// enters HBlank mode.
+func (b *Bus) HdmaHBlank() {
+	if !b.cgbMode || !b.hdma.hblankActive {
+		return
+	}
+
+	b.hdmaCopyBlock()
+
+	b.hdma.length--
+	if b.hdma.length == 0xFF { // All blocks have been transferred
+		b.hdma.length = 0x7F
+		b.hdma.hblankActive = false
+		b.hdma.finished = true
+	}
+}
+
+func (b *Bus) hdmaCopyBlock() {
+	source, destination := b.hdma.nextBlock()
+	for i := uint16(0); i < hdmaBlockSize; i++ {
+		b.vram.writeVRam(destination+i, b.read(source+i))
+	}
+}
+
+func (b *Bus) writeHdmaControl(value byte) {
+	if b.hdma.hblankActive && value&0x80 == 0 { // Writing bit 7 to 0 cancels the running HBlank DMA
+		b.hdma.hblankActive = false
+		return
+	}
+
+	// Writing bit 7 to 1 whilst an HBlank DMA is running restarts it with the new length
+	b.hdma.length = value & 0x7F
+	b.hdma.finished = false
+
+	if value&0x80 != 0 { // HBlank DMA, blocks are copied by HdmaHBlank
+		b.hdma.hblankActive = true
+
+		// The PPU only calls HdmaHBlank when it enters HBlank, so if it is already there or the LCD is off the
+		// first block is copied straight away
+		if b.io.lcd.lcdc&0x80 == 0 || b.io.lcd.stat&0x03 == 0 {
+			b.HdmaHBlank()
+		}
+		return
+	}
+
+	// General purpose DMA, all blocks are copied at once whilst the CPU is stopped
+	blocks := int(b.hdma.length) + 1
+	for i := 0; i < blocks; i++ {
+		b.hdmaCopyBlock()
+	}
+	b.hdma.length = 0x7F
+	b.hdma.finished = true
+
+	b.hdma.stall = blocks * hdmaBlockCycles
+	if b.speed.doubleSpeed {
+		b.hdma.stall *= 2
+	}
+}
+
+// TakeHdmaStall returns the machine cycles the CPU has to be stopped for the general purpose DMA started by the
+// last instruction, if any.
+func (b *Bus) TakeHdmaStall() int {
+	stall := b.hdma.stall
+	b.hdma.stall = 0
+	return stall
+}
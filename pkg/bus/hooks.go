@@ -0,0 +1,92 @@
+package bus
+
+// AccessType is the kind of bus access reported to hooks.
+type AccessType int
+
+const (
+	AccessRead AccessType = iota
+	AccessWrite
+)
+
+func (a AccessType) String() string {
+	if a == AccessWrite {
+		return "write"
+	}
+	return "read"
+}
+
+// Access describes a bus access.
+type Access struct {
+	Type    AccessType
+	Address uint16
+	Value   byte
+	PC      uint16 // Address of the instruction being executed
+	Cycle   uint64 // CPU T-cycles since power on
+}
+
+// Hook is called on every bus access made by the CPU once added with AddHook. Accesses made by other components,
+// like the PPU, the timer or DMA, and by the hook itself are not reported.
+type Hook func(access Access)
+
+// HookContext provides the CPU state that is given to hooks. It is implemented by the CPU.
+type HookContext interface {
+	InstructionPC() uint16
+	Cycles() uint64
+}
+
+type hook struct {
+	id int
+	fn Hook
+}
+
+// SetHookContext sets where the PC and cycle count given to hooks come from.
+func (b *Bus) SetHookContext(context HookContext) {
+	b.hookContext = context
+}
+
+// AddHook registers a hook and returns an id to remove it. Hooks have no cost when none are registered.
+func (b *Bus) AddHook(fn Hook) int {
+	b.nextHookID++
+	b.hooks = append(b.hooks, hook{id: b.nextHookID, fn: fn})
+	return b.nextHookID
+}
+
+// RemoveHook removes the hook with the given id.
+func (b *Bus) RemoveHook(id int) {
+	for i, h := range b.hooks {
+		if h.id == id {
+			b.hooks = append(b.hooks[:i], b.hooks[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *Bus) callHooks(accessType AccessType, address uint16, value byte) {
+	if b.inHook {
+		return
+	}
+
+	access := Access{Type: accessType, Address: address, Value: value}
+	if b.hookContext != nil {
+		access.PC = b.hookContext.InstructionPC()
+		access.Cycle = b.hookContext.Cycles()
+	}
+
+	b.inHook = true
+	for _, h := range b.hooks {
+		h.fn(access)
+	}
+	b.inHook = false
+}
+
+// Peek reads memory without reporting the access to the hooks, so tools like tracers and components like the
+// PPU don't trigger watchpoints when they inspect memory.
+func (b *Bus) Peek(address uint16) byte {
+	return b.read(address)
+}
+
+// Poke writes memory without reporting the access to the hooks. It is used by components other than the CPU,
+// like the PPU when it requests an interrupt.
+func (b *Bus) Poke(address uint16, value byte) {
+	b.write(address, value)
+}
@@ -6,20 +6,33 @@ import (
 
 // Register addresses
 const (
-	serialTransferDataAddr    uint16 = 0xFF01
-	serialTransferControlAddr uint16 = 0xFF02
-
 	divRegisterAddr  uint16 = 0xFF04
 	timaRegisterAddr uint16 = 0xFF05
 	tmaRegisterAddr  uint16 = 0xFF06
 	tacRegisterAddr  uint16 = 0xFF07
 
 	interruptFlagRegisterAddr uint16 = 0xFF0F
-	oamDmaRegisterAddr        uint16 = 0xFF46
+
+	lcdControlRegisterAddr uint16 = 0xFF40
+	lcdStatusRegisterAddr  uint16 = 0xFF41
+	scyRegisterAddr        uint16 = 0xFF42
+	scxRegisterAddr        uint16 = 0xFF43
+	lyRegisterAddr         uint16 = 0xFF44
+	lycRegisterAddr        uint16 = 0xFF45
+	oamDmaRegisterAddr     uint16 = 0xFF46
+	bgpRegisterAddr        uint16 = 0xFF47
+	obp0RegisterAddr       uint16 = 0xFF48
+	obp1RegisterAddr       uint16 = 0xFF49
+	wyRegisterAddr         uint16 = 0xFF4A
+	wxRegisterAddr         uint16 = 0xFF4B
 )
 
 const (
-	initialDivRegisterValue uint16 = 0xABCC
+	initialDivRegisterValue  uint16 = 0xABCC
+	initialLcdcRegisterValue byte   = 0x91
+	initialBgpRegisterValue  byte   = 0xFC
+
+	lcdStatusWritableMask byte = 0b01111000 // Only the interrupt sources can be written by the CPU
 )
 
 type timer struct {
@@ -29,16 +42,27 @@ type timer struct {
 	tacReg  byte   // FF07
 }
 
-type serial struct {
-	serialTransferData    byte // FF01
-	serialTransferControl byte // FF02
+type lcd struct {
+	lcdc byte // FF40
+	stat byte // FF41
+	scy  byte // FF42
+	scx  byte // FF43
+	ly   byte // FF44
+	lyc  byte // FF45
+	bgp  byte // FF47
+	obp0 byte // FF48
+	obp1 byte // FF49
+	wy   byte // FF4A
+	wx   byte // FF4B
 }
 
 type io struct {
+	joypad *joypad
 	serial *serial
 	timer  *timer
+	lcd    *lcd
+	sound  SoundDevice
 	ifReg  byte // Interrupt Flag FF0F
-	ly     byte // HACK - REMOVE IT LATER
 	dma    *Dma
 	logger log.Logger
 }
@@ -46,10 +70,17 @@ type io struct {
 func NewIO(logger log.Logger, dma *Dma) *io {
 	io := &io{
 		logger: logger,
+		joypad: &joypad{
+			selection: 0x30,
+		},
 		serial: &serial{},
 		timer: &timer{
 			divReg: initialDivRegisterValue,
 		},
+		lcd: &lcd{
+			lcdc: initialLcdcRegisterValue,
+			bgp:  initialBgpRegisterValue,
+		},
 		dma: dma,
 	}
 
@@ -57,16 +88,17 @@ func NewIO(logger log.Logger, dma *Dma) *io {
 }
 
 func (i *io) IORead(address uint16) byte {
-	if address == 0xFF44 {
-		i.ly++
-		return i.ly // HACK
+	if i.sound != nil && isSoundRegister(address) {
+		return i.sound.SoundRead(address)
 	}
 
 	switch address { // This switch is for special cases (Like 16bit Timer DIV register)
+	case joypadRegisterAddr:
+		return i.joypad.read()
 	case serialTransferDataAddr:
-		return i.serial.serialTransferData
+		return i.serial.data
 	case serialTransferControlAddr:
-		return i.serial.serialTransferControl
+		return i.serial.readControl()
 	case divRegisterAddr:
 		return byte(i.timer.divReg >> 8)
 	case timaRegisterAddr:
@@ -77,17 +109,48 @@ func (i *io) IORead(address uint16) byte {
 		return i.timer.tacReg
 	case interruptFlagRegisterAddr:
 		return i.ifReg
+	case lcdControlRegisterAddr:
+		return i.lcd.lcdc
+	case lcdStatusRegisterAddr:
+		return 0x80 | i.lcd.stat // Bit 7 is unused and always reads 1
+	case scyRegisterAddr:
+		return i.lcd.scy
+	case scxRegisterAddr:
+		return i.lcd.scx
+	case lyRegisterAddr:
+		return i.lcd.ly
+	case lycRegisterAddr:
+		return i.lcd.lyc
+	case oamDmaRegisterAddr:
+		return i.dma.value
+	case bgpRegisterAddr:
+		return i.lcd.bgp
+	case obp0RegisterAddr:
+		return i.lcd.obp0
+	case obp1RegisterAddr:
+		return i.lcd.obp1
+	case wyRegisterAddr:
+		return i.lcd.wy
+	case wxRegisterAddr:
+		return i.lcd.wx
 	default:
 		return 0x0
 	}
 }
 
 func (i *io) IOWrite(address uint16, data byte) {
+	if i.sound != nil && isSoundRegister(address) {
+		i.sound.SoundWrite(address, data)
+		return
+	}
+
 	switch address { // This switch is for special cases (Like 16bit Timer DIV register)
+	case joypadRegisterAddr:
+		i.joypad.write(data)
 	case serialTransferDataAddr:
-		i.serial.serialTransferData = data
+		i.serial.data = data
 	case serialTransferControlAddr:
-		i.serial.serialTransferControl = data
+		i.serial.writeControl(data)
 	case divRegisterAddr:
 		i.timer.divReg = 0
 	case timaRegisterAddr:
@@ -98,6 +161,27 @@ func (i *io) IOWrite(address uint16, data byte) {
 		i.timer.tacReg = data
 	case interruptFlagRegisterAddr:
 		i.ifReg = data
+	case lcdControlRegisterAddr:
+		i.lcd.lcdc = data
+	case lcdStatusRegisterAddr:
+		i.lcd.stat = i.lcd.stat&^lcdStatusWritableMask | data&lcdStatusWritableMask
+	case scyRegisterAddr:
+		i.lcd.scy = data
+	case scxRegisterAddr:
+		i.lcd.scx = data
+	case lyRegisterAddr: // LY is read only
+	case lycRegisterAddr:
+		i.lcd.lyc = data
+	case bgpRegisterAddr:
+		i.lcd.bgp = data
+	case obp0RegisterAddr:
+		i.lcd.obp0 = data
+	case obp1RegisterAddr:
+		i.lcd.obp1 = data
+	case wyRegisterAddr:
+		i.lcd.wy = data
+	case wxRegisterAddr:
+		i.lcd.wx = data
 	case oamDmaRegisterAddr:
 		i.dma.start(data)
 		i.logger.Debugf("DMA STARTED\n")
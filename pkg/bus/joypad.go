@@ -0,0 +1,99 @@
+package bus
+
+import (
+	"sync/atomic"
+)
+
+const (
+	joypadRegisterAddr uint16 = 0xFF00
+
+	joypadInterruptFlag byte = 0x10
+
+	joypadQueuedFlag uint32 = 1 << 8 // Set in joypad.queued when there are buttons to apply
+)
+
+// Joypad buttons, used as a bitmask by SetJoypadButtons. A set bit means the button is pressed.
+const (
+	JoypadRight byte = 1 << iota
+	JoypadLeft
+	JoypadUp
+	JoypadDown
+	JoypadA
+	JoypadB
+	JoypadSelect
+	JoypadStart
+)
+
+// JoypadDevice is implemented by devices that are attached to P1 register besides the buttons, like the SGB.
+type JoypadDevice interface {
+	// JoypadWrite is called every time the CPU writes P1.
+	JoypadWrite(value byte)
+	// ControllerID returns the controller currently selected (0-3). It is read from P1 lower nibble when
+	// neither direction nor action buttons are selected.
+	ControllerID() byte
+}
+
+type joypad struct {
+	selection byte // P1 bits 4-5. P14 low selects direction keys, P15 low selects action buttons
+	buttons   byte // Pressed buttons
+	device    JoypadDevice
+	queued    atomic.Uint32 // Buttons set by QueueJoypadButtons, with joypadQueuedFlag
+}
+
+func (j *joypad) read() byte {
+	value := 0xC0 | j.selection | 0x0F // Buttons are active low
+
+	if j.selection&0x10 == 0 {
+		value &^= j.buttons & 0x0F
+	}
+
+	if j.selection&0x20 == 0 {
+		value &^= j.buttons >> 4
+	}
+
+	if j.selection == 0x30 && j.device != nil {
+		value = value&0xF0 | (0x0F - j.device.ControllerID()&0x3)
+	}
+
+	return value
+}
+
+func (j *joypad) write(value byte) {
+	j.selection = value & 0x30
+
+	if j.device != nil {
+		j.device.JoypadWrite(value)
+	}
+}
+
+// SetJoypadButtons sets which buttons are pressed. A joypad interrupt is requested if any button has just
+// been pressed.
+func (b *Bus) SetJoypadButtons(buttons byte) {
+	if buttons&^b.io.joypad.buttons != 0 {
+		b.io.ifReg |= joypadInterruptFlag
+	}
+
+	b.io.joypad.buttons = buttons
+}
+
+// QueueJoypadButtons sets which buttons are pressed from a goroutine other than the one running the CPU, like
+// a frontend showing the game while a debugger runs it. The buttons are set by the next call to
+// ApplyQueuedJoypadButtons, made by the CPU before each instruction.
+func (b *Bus) QueueJoypadButtons(buttons byte) {
+	b.io.joypad.queued.Store(uint32(buttons) | joypadQueuedFlag)
+}
+
+// ApplyQueuedJoypadButtons sets the buttons queued by QueueJoypadButtons, if any.
+func (b *Bus) ApplyQueuedJoypadButtons() {
+	if b.io.joypad.queued.Load() == 0 {
+		return
+	}
+	if queued := b.io.joypad.queued.Swap(0); queued != 0 {
+		b.SetJoypadButtons(byte(queued))
+	}
+}
+
+// SetJoypadDevice attaches a device to P1 register.
+func (b *Bus) SetJoypadDevice(device JoypadDevice) {
+	b.io.joypad.device = device
+}
@@ -18,8 +18,8 @@ func (b *MapMock) BusRead(address uint16) byte {
 
 func (b *MapMock) BusRead16(address uint16) uint16 {
 	low := b.Data[address]
-	high := b.Data[address + 1]
-	return uint16(high<<8 | low)
+	high := b.Data[address+1]
+	return uint16(high)<<8 | uint16(low)
 }
 
 func (b *MapMock) BusWrite(address uint16, value byte) {
@@ -27,10 +27,106 @@ func (b *MapMock) BusWrite(address uint16, value byte) {
 	return
 }
 
+func (b *MapMock) Peek(address uint16) byte {
+	return b.Data[address]
+}
+
+func (b *MapMock) Poke(address uint16, value byte) {
+	b.Data[address] = value
+}
+
 func (b *MapMock) BusWrite16(address uint16, value uint16) {
 	low := byte(value & 0xFF)
-	high := byte(value>>8 & 0xFF)
+	high := byte(value >> 8 & 0xFF)
 	b.Data[address] = low
 	b.Data[address+1] = high
 	return
 }
+
+// The following methods are not needed by proc functions, so they do nothing.
+
+func (b *MapMock) RomBank(address uint16) int                  { return 0 }
+func (b *MapMock) IncrementTimerDiv() uint16                   { return 0 }
+func (b *MapMock) GetTimerDiv() uint16                         { return 0 }
+func (b *MapMock) DmaTick()                                    {}
+func (b *MapMock) SerialTick()                                 {}
+func (b *MapMock) ApplyQueuedJoypadButtons()                   {}
+func (b *MapMock) SetLcdLy(value byte)                         {}
+func (b *MapMock) SetLcdStatus(value byte)                     {}
+func (b *MapMock) IsCgbMode() bool                             { return false }
+func (b *MapMock) ReadVRamBank(bank byte, address uint16) byte { return 0 }
+func (b *MapMock) ReadBgPaletteRam(index byte) byte            { return 0 }
+func (b *MapMock) ReadObjPaletteRam(index byte) byte           { return 0 }
+func (b *MapMock) HdmaHBlank()                                 {}
+func (b *MapMock) TakeHdmaStall() int                          { return 0 }
+func (b *MapMock) SwitchSpeed() bool                           { return false }
+func (b *MapMock) IsDoubleSpeed() bool                         { return false }
+
+// RecordingMock is a flat 64 KiB memory that records the accesses done in every machine cycle, used to check
+// CPU timings. The CPU calls DmaTick once per machine cycle, which is used to tell cycles apart.
+type RecordingMock struct {
+	Memory [0x10000]byte
+	Cycles [][]Access // Accesses of every completed machine cycle
+
+	pending []Access
+}
+
+// NewRecordingMock returns an empty RecordingMock ready to use
+func NewRecordingMock() *RecordingMock {
+	return &RecordingMock{}
+}
+
+func (b *RecordingMock) BusRead(address uint16) byte {
+	value := b.Memory[address]
+	b.pending = append(b.pending, Access{Type: AccessRead, Address: address, Value: value})
+	return value
+}
+
+func (b *RecordingMock) BusWrite(address uint16, value byte) {
+	b.Memory[address] = value
+	b.pending = append(b.pending, Access{Type: AccessWrite, Address: address, Value: value})
+}
+
+// Peek and Poke access memory without recording, like the bus doesn't report them to hooks.
+func (b *RecordingMock) Peek(address uint16) byte {
+	return b.Memory[address]
+}
+
+func (b *RecordingMock) Poke(address uint16, value byte) {
+	b.Memory[address] = value
+}
+
+func (b *RecordingMock) BusRead16(address uint16) uint16 {
+	low := b.BusRead(address)
+	high := b.BusRead(address + 1)
+	return uint16(high)<<8 | uint16(low)
+}
+
+func (b *RecordingMock) BusWrite16(address uint16, value uint16) {
+	b.BusWrite(address, byte(value&0xFF))
+	b.BusWrite(address+1, byte(value>>8))
+}
+
+// DmaTick closes the current machine cycle.
+func (b *RecordingMock) DmaTick() {
+	b.Cycles = append(b.Cycles, b.pending)
+	b.pending = nil
+}
+
+// The following methods are not needed to check CPU timings, so they do nothing.
+
+func (b *RecordingMock) RomBank(address uint16) int                  { return 0 }
+func (b *RecordingMock) IncrementTimerDiv() uint16                   { return 0 }
+func (b *RecordingMock) GetTimerDiv() uint16                         { return 0 }
+func (b *RecordingMock) SerialTick()                                 {}
+func (b *RecordingMock) ApplyQueuedJoypadButtons()                   {}
+func (b *RecordingMock) SetLcdLy(value byte)                         {}
+func (b *RecordingMock) SetLcdStatus(value byte)                     {}
+func (b *RecordingMock) IsCgbMode() bool                             { return false }
+func (b *RecordingMock) ReadVRamBank(bank byte, address uint16) byte { return 0 }
+func (b *RecordingMock) ReadBgPaletteRam(index byte) byte            { return 0 }
+func (b *RecordingMock) ReadObjPaletteRam(index byte) byte           { return 0 }
+func (b *RecordingMock) HdmaHBlank()                                 {}
+func (b *RecordingMock) TakeHdmaStall() int                          { return 0 }
+func (b *RecordingMock) SwitchSpeed() bool                           { return false }
+func (b *RecordingMock) IsDoubleSpeed() bool                         { return false }
@@ -3,38 +3,59 @@ package bus
 import "github.com/mikeletux/goboy/pkg/log"
 
 const (
-	workingRamSize = WorkRam1End - WorkRam0Start + 1
-	highRamSize    = HighRamEnd - HighRamStart + 1
+	workingRamBankSize = WorkRam0End - WorkRam0Start + 1
+	workingRamBanks    = 8 // Banks 2-7 are only reachable in CGB mode
+	highRamSize        = HighRamEnd - HighRamStart + 1
 )
 
 type Ram struct {
 	logger     log.Logger
-	WorkingRam [workingRamSize]byte // length 0x2000
-	HighRam    [highRamSize]byte    // length 0x7F
+	WorkingRam [workingRamBanks][workingRamBankSize]byte // length 8 x 0x1000
+	HighRam    [highRamSize]byte                         // length 0x7F
+	bank       byte                                      // SVBK FF70
 }
 
 func NewRam(logger log.Logger) *Ram {
 	return &Ram{
 		logger: logger,
+		bank:   1,
 	}
 }
 
 func (r *Ram) readWorkingRam(address uint16) byte {
-	address -= WorkRam0Start
-	if address > workingRamSize { // Bigger than working RAM size
-		r.logger.Fatalf("Invalid working RAM address 0x%X", address)
-	}
-
-	return r.WorkingRam[address]
+	bank, offset := r.translateWorkingRamAddress(address)
+	return r.WorkingRam[bank][offset]
 }
 
 func (r *Ram) writeWorkingRam(address uint16, value byte) {
+	bank, offset := r.translateWorkingRamAddress(address)
+	r.WorkingRam[bank][offset] = value
+}
+
+// translateWorkingRamAddress returns the bank and the offset inside that bank for a given working RAM address.
+// C000-CFFF is always bank 0 whilst D000-DFFF is the bank selected by SVBK.
+func (r *Ram) translateWorkingRamAddress(address uint16) (byte, uint16) {
 	address -= WorkRam0Start
-	if address > workingRamSize { // Bigger than working RAM size
+	if address >= 2*workingRamBankSize { // Bigger than working RAM size
 		r.logger.Fatalf("Invalid working RAM address 0x%X", address)
 	}
 
-	r.WorkingRam[address] = value
+	if address < workingRamBankSize {
+		return 0, address
+	}
+
+	return r.bank, address - workingRamBankSize
+}
+
+func (r *Ram) readBankRegister() byte {
+	return 0xF8 | r.bank
+}
+
+func (r *Ram) writeBankRegister(value byte) {
+	r.bank = value & 0x7
+	if r.bank == 0 { // Writing 0 selects bank 1
+		r.bank = 1
+	}
 }
 
 func (r *Ram) readHighRam(address uint16) byte {
@@ -0,0 +1,129 @@
+package bus
+
+import (
+	goio "io" // io is already taken by the IO registers type
+)
+
+const (
+	serialTransferDataAddr    uint16 = 0xFF01
+	serialTransferControlAddr uint16 = 0xFF02
+
+	serialInterruptFlag byte = 0x08
+
+	serialTransferStartFlag byte = 0x80
+	serialClockSpeedFlag    byte = 0x02 // CGB only
+	serialInternalClockFlag byte = 0x01
+	serialControlUnusedBits byte = 0x7C
+
+	serialBitCycles     = 128 // Machine cycles per bit using the 8192Hz internal clock
+	serialFastBitCycles = 4   // Machine cycles per bit using the CGB 262144Hz internal clock
+)
+
+// SerialDevice is implemented by peripherals plugged into the serial port, like a link cable or a printer.
+type SerialDevice interface {
+	// Transfer is called when the Game Boy starts a transfer using its internal clock. It receives the byte
+	// being sent and returns the byte that will be shifted in.
+	Transfer(out byte) byte
+	// Tick is called every machine cycle, so devices can keep time with the Game Boy. waiting tells whether the
+	// Game Boy waits for the device to clock a transfer, sending back out. It returns the byte received when
+	// the device clocks a transfer while the Game Boy waits.
+	Tick(out byte, waiting bool) (in byte, ok bool)
+}
+
+type serial struct {
+	data     byte // SB FF01
+	control  byte // SC FF02
+	incoming byte // Byte being shifted into SB during an internal clock transfer
+	bits     int  // Bits left to shift in the current transfer
+	cycles   int  // Machine cycles left to shift the next bit
+	device   SerialDevice
+	output   goio.Writer
+}
+
+func (s *serial) readControl() byte {
+	return s.control | serialControlUnusedBits
+}
+
+func (s *serial) writeControl(value byte) {
+	s.control = value &^ serialControlUnusedBits
+
+	if s.control&serialTransferStartFlag == 0 { // Cancels any ongoing transfer
+		s.bits = 0
+	}
+}
+
+// SetSerialDevice plugs a device into the serial port. Without a device the Game Boy receives 0xFF.
+func (b *Bus) SetSerialDevice(device SerialDevice) {
+	b.io.serial.device = device
+}
+
+// SetSerialOutput sets a writer that receives every byte sent through the serial port, as soon as it is
+// transmitted. Test ROMs use it to report their results.
+func (b *Bus) SetSerialOutput(output goio.Writer) {
+	b.io.serial.output = output
+}
+
+// SerialTick advances the serial port one machine cycle. Bits are shifted out of SB and into it at the
+// internal clock rate, and the serial interrupt is requested once the whole byte has been transferred.
+func (b *Bus) SerialTick() {
+	s := b.io.serial
+	waiting := s.control&serialTransferStartFlag != 0 && s.control&serialInternalClockFlag == 0
+	if s.device != nil {
+		if in, ok := s.device.Tick(s.data, waiting); ok && waiting {
+			b.writeSerialOutput(s.data)
+			s.data = in
+			b.finishSerialTransfer()
+			return
+		}
+	}
+
+	if s.control&serialTransferStartFlag == 0 || waiting {
+		return
+	}
+
+	if s.bits == 0 { // Transfer has just been started
+		b.writeSerialOutput(s.data)
+		s.incoming = 0xFF
+		if s.device != nil {
+			s.incoming = s.device.Transfer(s.data)
+		}
+		s.bits = 8
+		s.cycles = b.serialBitCycles()
+	}
+
+	s.cycles--
+	if s.cycles > 0 {
+		return
+	}
+
+	s.data = s.data<<1 | s.incoming>>7
+	s.incoming <<= 1
+	s.bits--
+	s.cycles = b.serialBitCycles()
+
+	if s.bits == 0 {
+		b.finishSerialTransfer()
+	}
+}
+
+func (b *Bus) serialBitCycles() int {
+	if b.cgbMode && b.io.serial.control&serialClockSpeedFlag != 0 {
+		return serialFastBitCycles
+	}
+	return serialBitCycles
+}
+
+func (b *Bus) writeSerialOutput(value byte) {
+	if b.io.serial.output == nil {
+		return
+	}
+
+	if _, err := b.io.serial.output.Write([]byte{value}); err != nil {
+		b.logger.Debugf("error writing serial output: %s", err)
+	}
+}
+
+func (b *Bus) finishSerialTransfer() {
+	b.io.serial.control &^= serialTransferStartFlag
+	b.io.ifReg |= serialInterruptFlag
+}
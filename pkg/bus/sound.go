@@ -0,0 +1,21 @@
+package bus
+
+const (
+	soundRegistersStart uint16 = 0xFF10
+	soundRegistersEnd   uint16 = 0xFF3F // Includes the wave RAM, FF30-FF3F
+)
+
+// SoundDevice is implemented by the APU. It handles the accesses to the sound registers and the wave RAM.
+type SoundDevice interface {
+	SoundRead(address uint16) byte
+	SoundWrite(address uint16, value byte)
+}
+
+// SetSoundDevice attaches the APU to the sound registers. Without it they read 0.
+func (b *Bus) SetSoundDevice(device SoundDevice) {
+	b.io.sound = device
+}
+
+func isSoundRegister(address uint16) bool {
+	return address >= soundRegistersStart && address <= soundRegistersEnd
+}
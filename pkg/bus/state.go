@@ -0,0 +1,163 @@
+package bus
+
+// State is a snapshot of the memory and the registers handled by the bus, used for save states. The
+// cartridge and the devices plugged into the bus are not part of it.
+type State struct {
+	VideoRam       [vramBanks][vramSize]byte
+	VRamBank       byte
+	WorkingRam     [workingRamBanks][workingRamBankSize]byte
+	WorkingRamBank byte
+	HighRam        [highRamSize]byte
+	Oam            [oamSize]byte
+
+	InterruptEnable byte
+	InterruptFlag   byte
+
+	JoypadSelection byte
+	JoypadButtons   byte
+
+	SerialData     byte
+	SerialControl  byte
+	SerialIncoming byte
+	SerialBits     int
+	SerialCycles   int
+
+	Div  uint16
+	Tima byte
+	Tma  byte
+	Tac  byte
+
+	Lcdc byte
+	Stat byte
+	Scy  byte
+	Scx  byte
+	Ly   byte
+	Lyc  byte
+	Bgp  byte
+	Obp0 byte
+	Obp1 byte
+	Wy   byte
+	Wx   byte
+
+	DmaActive     bool
+	DmaByte       byte
+	DmaValue      byte
+	DmaStartDelay byte
+
+	HdmaSource       uint16
+	HdmaDestination  uint16
+	HdmaLength       byte
+	HdmaHBlankActive bool
+	HdmaFinished     bool
+
+	SpeedPrepare bool
+	DoubleSpeed  bool
+
+	BgPaletteIndex          byte
+	BgPaletteAutoIncrement  bool
+	BgPaletteRam            [paletteRamSize]byte
+	ObjPaletteIndex         byte
+	ObjPaletteAutoIncrement bool
+	ObjPaletteRam           [paletteRamSize]byte
+}
+
+// State returns a snapshot of the bus.
+func (b *Bus) State() State {
+	i := b.io
+	return State{
+		VideoRam:       b.vram.VideoRam,
+		VRamBank:       b.vram.bank,
+		WorkingRam:     b.ram.WorkingRam,
+		WorkingRamBank: b.ram.bank,
+		HighRam:        b.ram.HighRam,
+		Oam:            b.oam.objectAttributeMemory,
+
+		InterruptEnable: b.ieRegister,
+		InterruptFlag:   i.ifReg,
+
+		JoypadSelection: i.joypad.selection,
+		JoypadButtons:   i.joypad.buttons,
+
+		SerialData:     i.serial.data,
+		SerialControl:  i.serial.control,
+		SerialIncoming: i.serial.incoming,
+		SerialBits:     i.serial.bits,
+		SerialCycles:   i.serial.cycles,
+
+		Div:  i.timer.divReg,
+		Tima: i.timer.timaReg,
+		Tma:  i.timer.tmaReg,
+		Tac:  i.timer.tacReg,
+
+		Lcdc: i.lcd.lcdc,
+		Stat: i.lcd.stat,
+		Scy:  i.lcd.scy,
+		Scx:  i.lcd.scx,
+		Ly:   i.lcd.ly,
+		Lyc:  i.lcd.lyc,
+		Bgp:  i.lcd.bgp,
+		Obp0: i.lcd.obp0,
+		Obp1: i.lcd.obp1,
+		Wy:   i.lcd.wy,
+		Wx:   i.lcd.wx,
+
+		DmaActive:     b.dma.active,
+		DmaByte:       b.dma.byte,
+		DmaValue:      b.dma.value,
+		DmaStartDelay: b.dma.startDelay,
+
+		HdmaSource:       b.hdma.source,
+		HdmaDestination:  b.hdma.destination,
+		HdmaLength:       b.hdma.length,
+		HdmaHBlankActive: b.hdma.hblankActive,
+		HdmaFinished:     b.hdma.finished,
+
+		SpeedPrepare: b.speed.prepare,
+		DoubleSpeed:  b.speed.doubleSpeed,
+
+		BgPaletteIndex:          b.bgPalette.index,
+		BgPaletteAutoIncrement:  b.bgPalette.autoIncrement,
+		BgPaletteRam:            b.bgPalette.data,
+		ObjPaletteIndex:         b.objPalette.index,
+		ObjPaletteAutoIncrement: b.objPalette.autoIncrement,
+		ObjPaletteRam:           b.objPalette.data,
+	}
+}
+
+// LoadState restores a snapshot taken with State.
+func (b *Bus) LoadState(state State) {
+	i := b.io
+	b.vram.VideoRam = state.VideoRam
+	b.vram.bank = state.VRamBank
+	b.ram.WorkingRam = state.WorkingRam
+	b.ram.bank = state.WorkingRamBank
+	b.ram.HighRam = state.HighRam
+	b.oam.objectAttributeMemory = state.Oam
+
+	b.ieRegister = state.InterruptEnable
+	i.ifReg = state.InterruptFlag
+
+	i.joypad.selection = state.JoypadSelection
+	i.joypad.buttons = state.JoypadButtons
+
+	i.serial.data = state.SerialData
+	i.serial.control = state.SerialControl
+	i.serial.incoming = state.SerialIncoming
+	i.serial.bits = state.SerialBits
+	i.serial.cycles = state.SerialCycles
+
+	*i.timer = timer{divReg: state.Div, timaReg: state.Tima, tmaReg: state.Tma, tacReg: state.Tac}
+	*i.lcd = lcd{lcdc: state.Lcdc, stat: state.Stat, scy: state.Scy, scx: state.Scx, ly: state.Ly, lyc: state.Lyc,
+		bgp: state.Bgp, obp0: state.Obp0, obp1: state.Obp1, wy: state.Wy, wx: state.Wx}
+
+	*b.dma = Dma{active: state.DmaActive, byte: state.DmaByte, value: state.DmaValue,
+		startDelay: state.DmaStartDelay}
+	*b.hdma = Hdma{source: state.HdmaSource, destination: state.HdmaDestination, length: state.HdmaLength,
+		hblankActive: state.HdmaHBlankActive, finished: state.HdmaFinished}
+	*b.speed = speed{prepare: state.SpeedPrepare, doubleSpeed: state.DoubleSpeed}
+
+	*b.bgPalette = palette{index: state.BgPaletteIndex, autoIncrement: state.BgPaletteAutoIncrement,
+		data: state.BgPaletteRam}
+	*b.objPalette = palette{index: state.ObjPaletteIndex, autoIncrement: state.ObjPaletteAutoIncrement,
+		data: state.ObjPaletteRam}
+}
@@ -2,11 +2,15 @@ package bus
 
 import "github.com/mikeletux/goboy/pkg/log"
 
-const vramSize = VramEnd - VramStart + 1
+const (
+	vramSize  = VramEnd - VramStart + 1
+	vramBanks = 2 // Bank 1 is only reachable in CGB mode
+)
 
 type VRam struct {
 	logger   log.Logger
-	VideoRam [vramSize]byte // length 0x2000
+	VideoRam [vramBanks][vramSize]byte // length 2 x 0x2000
+	bank     byte                      // VBK FF4F
 }
 
 func NewVRam(logger log.Logger) *VRam {
@@ -16,19 +20,35 @@ func NewVRam(logger log.Logger) *VRam {
 }
 
 func (v *VRam) readVRam(address uint16) byte {
+	return v.readVRamBank(v.bank, address)
+}
+
+func (v *VRam) writeVRam(address uint16, value byte) {
+	v.writeVRamBank(v.bank, address, value)
+}
+
+func (v *VRam) readVRamBank(bank byte, address uint16) byte {
 	address -= VramStart
-	if address > vramSize {
-		v.logger.Fatalf("Invalid Video RAM read address 0x%X", address)
+	if address >= vramSize || bank >= vramBanks {
+		v.logger.Fatalf("Invalid Video RAM read address 0x%X (bank %d)", address, bank)
 	}
 
-	return v.VideoRam[address]
+	return v.VideoRam[bank][address]
 }
 
-func (v *VRam) writeVRam(address uint16, value byte) {
+func (v *VRam) writeVRamBank(bank byte, address uint16, value byte) {
 	address -= VramStart
-	if address > vramSize {
-		v.logger.Fatalf("Invalid Video RAM write address 0x%X", address)
+	if address >= vramSize || bank >= vramBanks {
+		v.logger.Fatalf("Invalid Video RAM write address 0x%X (bank %d)", address, bank)
 	}
 
-	v.VideoRam[address] = value
+	v.VideoRam[bank][address] = value
+}
+
+func (v *VRam) readBankRegister() byte {
+	return 0xFE | v.bank
+}
+
+func (v *VRam) writeBankRegister(value byte) {
+	v.bank = value & 0x1
 }
@@ -0,0 +1,196 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image/png"
+	"io"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// APNG writes an animated PNG as the frames are added, without keeping them in memory. Viewers that don't know
+// APNG show the first frame. The frame count is only known at the end, so it is written by Close seeking back.
+type APNG struct {
+	w        io.WriteSeeker
+	buffer   *bufio.Writer
+	scale    int
+	sequence sequence
+	encoder  png.Encoder
+	number   uint32 // Sequence number of the next fcTL or fdAT chunk
+	frames   uint32 // Frames written
+	acTL     int64  // Offset of the animation control chunk
+	offset   int64
+	err      error // First write error, returned by Close
+}
+
+// NewAPNG returns an APNG writing to w, with each pixel repeated scale x scale times.
+func NewAPNG(w io.WriteSeeker, scale int) *APNG {
+	if scale < 1 {
+		scale = 1
+	}
+	return &APNG{
+		w:       w,
+		buffer:  bufio.NewWriter(w),
+		scale:   scale,
+		encoder: png.Encoder{CompressionLevel: png.BestSpeed}, // Frames are encoded whilst the game runs
+	}
+}
+
+// AddFrame implements Recorder.
+func (a *APNG) AddFrame(frame []uint32, width, height int) error {
+	if err := a.sequence.add(frame, width, height, a.writeFrame); err != nil {
+		return err
+	}
+	return a.err
+}
+
+// Frames implements Recorder.
+func (a *APNG) Frames() int {
+	return a.sequence.count
+}
+
+// Close writes the last frame, the end of the image and the frame count. It doesn't close the underlying
+// writer.
+func (a *APNG) Close() error {
+	if err := a.sequence.flush(a.writeFrame); err != nil {
+		return err
+	}
+	if a.frames == 0 {
+		return a.err
+	}
+
+	a.writeChunk("IEND", nil)
+	if err := a.buffer.Flush(); a.err == nil {
+		a.err = err
+	}
+	if a.err != nil {
+		return a.err
+	}
+
+	if _, err := a.w.Seek(a.acTL, io.SeekStart); err != nil {
+		return err
+	}
+	a.writeChunk("acTL", a.animationControl())
+	if err := a.buffer.Flush(); a.err == nil {
+		a.err = err
+	}
+	if _, err := a.w.Seek(0, io.SeekEnd); err != nil && a.err == nil {
+		a.err = err
+	}
+	return a.err
+}
+
+// animationControl returns the data of the acTL chunk: the frame count and 0 to loop forever.
+func (a *APNG) animationControl() []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, a.frames)
+	return data
+}
+
+func (a *APNG) writeChunk(chunkType string, data []byte) {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], chunkType)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := binary.BigEndian.AppendUint32(nil, crc.Sum32())
+
+	for _, part := range [][]byte{header, data, footer} {
+		n, err := a.buffer.Write(part)
+		a.offset += int64(n)
+		if err != nil && a.err == nil {
+			a.err = err
+		}
+	}
+}
+
+// writeFrame writes the frame control chunk and the image data of the changed area of a frame. The first
+// frame also starts the file.
+func (a *APNG) writeFrame(pending *pendingFrame, end int) error {
+	var encoded bytes.Buffer
+	img := crop(pending.frame, a.sequence.width, pending.rect, a.scale)
+	if err := a.encoder.Encode(&encoded, img); err != nil {
+		return err
+	}
+	header, data, err := readPNG(encoded.Bytes())
+	if err != nil {
+		return err
+	}
+
+	if a.frames == 0 {
+		a.buffer.Write(pngSignature)
+		a.offset += int64(len(pngSignature))
+		a.writeChunk("IHDR", header)
+		a.acTL = a.offset
+		a.writeChunk("acTL", a.animationControl())
+	}
+
+	a.writeChunk("fcTL", a.frameControl(pending, end))
+	if a.frames == 0 {
+		a.writeChunk("IDAT", data)
+	} else {
+		a.writeChunk("fdAT", append(binary.BigEndian.AppendUint32(nil, a.nextNumber()), data...))
+	}
+	a.frames++
+	return a.err
+}
+
+// frameControl returns the data of the fcTL chunk of a frame: its area and how long it is shown.
+func (a *APNG) frameControl(pending *pendingFrame, end int) []byte {
+	rect := pending.rect
+	data := binary.BigEndian.AppendUint32(nil, a.nextNumber())
+	for _, value := range []int{rect.Dx(), rect.Dy(), rect.Min.X, rect.Min.Y} {
+		data = binary.BigEndian.AppendUint32(data, uint32(value*a.scale))
+	}
+
+	// The delay is a fraction of 16 bit numbers, in ten thousandths of a second unless it is too long
+	numerator, denominator := delay(pending.start, end, 0.0001), 10000
+	for numerator > 0xFFFF && denominator > 1 {
+		numerator, denominator = (numerator+5)/10, denominator/10
+	}
+	if numerator > 0xFFFF {
+		numerator = 0xFFFF
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(numerator))
+	data = binary.BigEndian.AppendUint16(data, uint16(denominator))
+	return append(data, 0, 0) // Don't dispose nor blend, the area is replaced
+}
+
+func (a *APNG) nextNumber() uint32 {
+	a.number++
+	return a.number - 1
+}
+
+// readPNG returns the IHDR chunk data and the image data, joining the IDAT chunks, of a PNG image.
+func readPNG(encoded []byte) (header, data []byte, err error) {
+	if !bytes.HasPrefix(encoded, pngSignature) {
+		return nil, nil, errors.New("invalid PNG signature")
+	}
+
+	encoded = encoded[len(pngSignature):]
+	for len(encoded) >= 12 {
+		length := int(binary.BigEndian.Uint32(encoded))
+		if len(encoded) < 12+length {
+			break
+		}
+		chunkType, chunkData := string(encoded[4:8]), encoded[8:8+length]
+		switch chunkType {
+		case "IHDR":
+			header = chunkData
+		case "IDAT":
+			data = append(data, chunkData...)
+		}
+		encoded = encoded[12+length:]
+	}
+
+	if header == nil || data == nil {
+		return nil, nil, errors.New("invalid PNG image")
+	}
+	return header, data, nil
+}
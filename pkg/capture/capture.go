@@ -0,0 +1,92 @@
+// Package capture saves what the emulator shows: screenshots as PNG images and gameplay as animated GIF or APNG
+// files, at the native resolution or scaled up. A Session takes them when the screenshot and record hotkeys are
+// pressed.
+package capture
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// frameSeconds is how long a frame is shown, 70224 T-cycles at 4194304Hz, so recordings play at 59.7 fps.
+const frameSeconds = float64(goboy.FrameCycles) / 4194304
+
+// Image returns a frame of width x height pixels, stored row by row as 0xAARRGGBB, as an opaque image with each
+// pixel repeated scale x scale times.
+func Image(frame []uint32, width, height, scale int) *image.RGBA {
+	return crop(frame, width, image.Rect(0, 0, width, height), scale)
+}
+
+// crop returns the area rect of a frame as an image with each pixel repeated scale x scale times.
+func crop(frame []uint32, width int, rect image.Rectangle, scale int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, rect.Dx()*scale, rect.Dy()*scale))
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		row := img.Pix[(y-rect.Min.Y)*scale*img.Stride:]
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			pixel := frame[y*width+x]
+			for i := 0; i < scale; i++ {
+				offset := ((x-rect.Min.X)*scale + i) * 4
+				row[offset] = byte(pixel >> 16)
+				row[offset+1] = byte(pixel >> 8)
+				row[offset+2] = byte(pixel)
+				row[offset+3] = 0xFF
+			}
+		}
+		for i := 1; i < scale; i++ {
+			copy(row[i*img.Stride:], row[:rect.Dx()*scale*4])
+		}
+	}
+	return img
+}
+
+// WritePNG writes a frame as a PNG image, each pixel repeated scale x scale times.
+func WritePNG(w io.Writer, frame []uint32, width, height, scale int) error {
+	return png.Encode(w, Image(frame, width, height, scale))
+}
+
+// SavePNG saves a frame as a PNG file, each pixel repeated scale x scale times.
+func SavePNG(path string, frame []uint32, width, height, scale int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := WritePNG(f, frame, width, height, scale); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// NextFileName returns the first file name of the form prefix_001.ext that doesn't exist yet in dir.
+func NextFileName(dir, prefix, ext string) string {
+	for i := 1; ; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("%s_%03d%s", prefix, i, ext))
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return file
+		}
+	}
+}
+
+// changedRect returns the smallest rectangle holding the pixels that differ between two frames. It is empty if
+// they are the same, and the whole frame if there is no previous one.
+func changedRect(frame, previous []uint32, width, height int) image.Rectangle {
+	if previous == nil {
+		return image.Rect(0, 0, width, height)
+	}
+
+	var rect image.Rectangle
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if frame[y*width+x] != previous[y*width+x] {
+				rect = rect.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return rect
+}
@@ -0,0 +1,313 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/test"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	white uint32 = 0xFFFFFFFF
+	black uint32 = 0xFF000000
+	red   uint32 = 0xFFFF0000
+)
+
+// testFrames are 4x2 frames: the second one is the same as the first, so they are written as one.
+var testFrames = [][]uint32{
+	{white, white, white, white, white, white, white, white},
+	{white, white, white, white, white, white, white, white},
+	{white, black, white, white, white, white, red, white},
+	{white, black, white, white, white, white, white, white},
+}
+
+func addFrames(t *testing.T, r Recorder) {
+	for _, frame := range testFrames {
+		if err := r.AddFrame(frame, 4, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.AddFrame(make([]uint32, 9), 3, 3); err == nil {
+		t.Error("expected an error adding a frame of another size")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Frames() != len(testFrames) {
+		t.Errorf("expected %d frames got %d", len(testFrames), r.Frames())
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	var out bytes.Buffer
+	if err := WritePNG(&out, testFrames[2], 4, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size != image.Pt(12, 6) {
+		t.Fatalf("expected a 12x6 image got %v", size)
+	}
+	expected := map[image.Point]color.RGBA{
+		{0, 0}: {0xFF, 0xFF, 0xFF, 0xFF},
+		{3, 0}: {0, 0, 0, 0xFF},
+		{5, 2}: {0, 0, 0, 0xFF},
+		{6, 5}: {0xFF, 0, 0, 0xFF},
+	}
+	for point, c := range expected {
+		if got := color.RGBAModel.Convert(img.At(point.X, point.Y)); got != c {
+			t.Errorf("expected %v at %v got %v", c, point, got)
+		}
+	}
+}
+
+func TestNextFileName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "screenshot_001.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := NextFileName(dir, "screenshot", ".png"); got != filepath.Join(dir, "screenshot_002.png") {
+		t.Errorf("expected screenshot_002.png got %s", got)
+	}
+}
+
+func TestGIF(t *testing.T) {
+	var out bytes.Buffer
+	addFrames(t, NewGIF(&out, 2))
+
+	animation, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if animation.LoopCount != 0 || animation.Config.Width != 8 || animation.Config.Height != 4 {
+		t.Errorf("expected an 8x4 animation looping forever got %+v", animation.Config)
+	}
+
+	// 59.7 fps in hundredths: frames start at 0, 1.67, 3.35 and 5.02, and the last one ends at 6.7
+	if len(animation.Image) != 3 || animation.Delay[0] != 3 || animation.Delay[1] != 2 || animation.Delay[2] != 2 {
+		t.Fatalf("expected 3 frames lasting 3, 2 and 2 hundredths got %d frames %v", len(animation.Image),
+			animation.Delay)
+	}
+	if bounds := animation.Image[1].Bounds(); bounds != image.Rect(2, 0, 6, 4) {
+		t.Errorf("expected the second frame to hold the changed area got %v", bounds)
+	}
+
+	screen := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for _, frame := range animation.Image {
+		draw.Draw(screen, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	}
+	if got := color.RGBAModel.Convert(screen.At(2, 0)); got != (color.RGBA{0, 0, 0, 0xFF}) {
+		t.Errorf("expected a black pixel got %v", got)
+	}
+	if got := color.RGBAModel.Convert(screen.At(4, 2)); got != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Errorf("expected the red pixel to be replaced got %v", got)
+	}
+}
+
+func TestGIFColors(t *testing.T) {
+	frame := make([]uint32, 32*32)
+	for i := range frame {
+		frame[i] = 0xFF000000 | uint32(i)
+	}
+	frame[len(frame)-1] = 0xFFFF00FF
+
+	var out bytes.Buffer
+	g := NewGIF(&out, 1)
+	if err := g.AddFrame(frame, 32, 32); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := gif.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _, b, _ := img.At(31, 31).RGBA()
+	if r>>8 != 0xFF || b>>8 != 0xFF {
+		t.Errorf("expected a frame with too many colors to be reduced, got %v", img.At(31, 31))
+	}
+}
+
+// apngChunks returns the data of the chunks of an APNG by type.
+func apngChunks(t *testing.T, data []byte) map[string][][]byte {
+	chunks := make(map[string][][]byte)
+	data = data[len(pngSignature):]
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data)
+		chunkType := string(data[4:8])
+		chunks[chunkType] = append(chunks[chunkType], data[8:8+length])
+		data = data[12+length:]
+	}
+	return chunks
+}
+
+func TestAPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.png")
+	r, err := Create(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFrames(t, r)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data)) // Only the first frame
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 4, 2) {
+		t.Errorf("expected a 4x2 image got %v", img.Bounds())
+	}
+
+	chunks := apngChunks(t, data)
+	if frames := binary.BigEndian.Uint32(chunks["acTL"][0]); frames != 3 {
+		t.Errorf("expected 3 frames in acTL got %d", frames)
+	}
+	if len(chunks["fcTL"]) != 3 || len(chunks["fdAT"]) != 2 || len(chunks["IDAT"]) != 1 {
+		t.Fatalf("expected 3 fcTL, 2 fdAT and 1 IDAT chunks got %d, %d and %d", len(chunks["fcTL"]),
+			len(chunks["fdAT"]), len(chunks["IDAT"]))
+	}
+
+	// Frames start at 0, 167.4, 334.9 and 502.3 ten thousandths, and the last one ends at 669.7
+	for i, expected := range []uint16{335, 167, 168} {
+		frameControl := chunks["fcTL"][i]
+		if number := binary.BigEndian.Uint32(frameControl); number != []uint32{0, 1, 3}[i] {
+			t.Errorf("frame %d: expected sequence number %d got %d", i, []uint32{0, 1, 3}[i], number)
+		}
+		if got := binary.BigEndian.Uint16(frameControl[20:]); got != expected {
+			t.Errorf("frame %d: expected a delay of %d got %d", i, expected, got)
+		}
+	}
+	if x := binary.BigEndian.Uint32(chunks["fcTL"][1][12:]); x != 1 {
+		t.Errorf("expected the second frame to start at the changed area got x %d", x)
+	}
+
+	if _, err := Create(filepath.Join(t.TempDir(), "video.avi"), 1); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// hotkeyFrontend returns the hotkeys set in the next call to Hotkeys.
+type hotkeyFrontend struct {
+	*frontend.Headless
+	hotkeys frontend.Hotkeys
+}
+
+func (f *hotkeyFrontend) Hotkeys() frontend.Hotkeys {
+	hotkeys := f.hotkeys
+	f.hotkeys = frontend.Hotkeys{}
+	return hotkeys
+}
+
+func TestSession(t *testing.T) {
+	machine, err := goboy.New(test.Rom("COUNTER", test.CounterProgram), goboy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	session, err := New(machine, Options{Dir: dir, Scale: 2, Format: "gif"}, &log.NilLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hotkeys := &hotkeyFrontend{Headless: frontend.NewHeadless()}
+	f := session.Wrap(hotkeys)
+
+	hotkeys.hotkeys = frontend.Hotkeys{Screenshot: true, Record: true}
+	f.Hotkeys()
+	if !session.Recording() {
+		t.Fatal("expected the record hotkey to start recording")
+	}
+	for i := 0; i < 3; i++ {
+		f.Poll()
+		if err := machine.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hotkeys.hotkeys = frontend.Hotkeys{Record: true}
+	f.Hotkeys()
+	if session.Recording() {
+		t.Fatal("expected the record hotkey to stop recording")
+	}
+
+	screenshot, err := os.Open(filepath.Join(dir, "screenshot_001.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer screenshot.Close()
+	if config, err := png.DecodeConfig(screenshot); err != nil || config.Width != goboy.ScreenWidth*2 {
+		t.Errorf("expected a screenshot scaled twice got %+v, %v", config, err)
+	}
+
+	recording, err := os.Open(filepath.Join(dir, "recording_001.gif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recording.Close()
+	animation, err := gif.DecodeAll(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var duration int
+	for _, frameDelay := range animation.Delay {
+		duration += frameDelay
+	}
+	if duration != 5 { // 3 frames
+		t.Errorf("expected a recording lasting 5 hundredths got %d", duration)
+	}
+
+	// Nothing is run whilst recording, so there is nothing to save
+	if err := session.StartRecording(filepath.Join(dir, "empty.gif")); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "empty.gif")); !os.IsNotExist(err) {
+		t.Error("expected an empty recording to be removed")
+	}
+}
+
+func TestSessionDefaultFormat(t *testing.T) {
+	machine, err := goboy.New(test.Rom("COUNTER", test.CounterProgram), goboy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	session, err := New(machine, Options{Dir: dir}, &log.NilLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hotkeys := &hotkeyFrontend{Headless: frontend.NewHeadless(), hotkeys: frontend.Hotkeys{Record: true}}
+	f := session.Wrap(hotkeys)
+	f.Hotkeys()
+	f.Poll()
+	if err := machine.RunFrame(); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "recording_001.png")); err != nil {
+		t.Errorf("expected an APNG recording by default: %s", err)
+	}
+}
@@ -0,0 +1,209 @@
+package capture
+
+import (
+	"bufio"
+	"compress/lzw"
+	"image"
+	"io"
+)
+
+// GIF writes an animated GIF as the frames are added, without keeping them in memory. Each frame has its own
+// color table, frames with more than 256 colors are reduced to 3-3-2 bit RGB.
+//
+// GIF delays are counted in hundredths of a second, so frames last 1 or 2 hundredths to keep 59.7 fps on
+// average. Some viewers, like most browsers, slow down delays shorter than 2 hundredths, an APNG plays at the
+// right speed everywhere.
+type GIF struct {
+	w        *bufio.Writer
+	scale    int
+	sequence sequence
+	err      error // First write error, returned by Close
+}
+
+// NewGIF returns a GIF writing to w, with each pixel repeated scale x scale times.
+func NewGIF(w io.Writer, scale int) *GIF {
+	if scale < 1 {
+		scale = 1
+	}
+	return &GIF{w: bufio.NewWriter(w), scale: scale}
+}
+
+// AddFrame implements Recorder.
+func (g *GIF) AddFrame(frame []uint32, width, height int) error {
+	if g.sequence.count == 0 {
+		g.writeHeader(width*g.scale, height*g.scale)
+	}
+	if err := g.sequence.add(frame, width, height, g.writeFrame); err != nil {
+		return err
+	}
+	return g.err
+}
+
+// Frames implements Recorder.
+func (g *GIF) Frames() int {
+	return g.sequence.count
+}
+
+// Close writes the last frame and the trailer. It doesn't close the underlying writer.
+func (g *GIF) Close() error {
+	if err := g.sequence.flush(g.writeFrame); err != nil {
+		return err
+	}
+	if g.sequence.count > 0 {
+		g.w.WriteByte(0x3B) // Trailer
+	}
+	if err := g.w.Flush(); g.err == nil {
+		g.err = err
+	}
+	return g.err
+}
+
+func (g *GIF) write(data ...byte) {
+	if _, err := g.w.Write(data); err != nil && g.err == nil {
+		g.err = err
+	}
+}
+
+func (g *GIF) writeUint16(value int) {
+	g.write(byte(value), byte(value>>8))
+}
+
+// writeHeader writes the header, the logical screen without global color table and the loop extension.
+func (g *GIF) writeHeader(width, height int) {
+	g.write([]byte("GIF89a")...)
+	g.writeUint16(width)
+	g.writeUint16(height)
+	g.write(0x00, 0x00, 0x00) // No global color table, background color and aspect ratio
+
+	g.write(0x21, 0xFF, 0x0B)
+	g.write([]byte("NETSCAPE2.0")...)
+	g.write(0x03, 0x01, 0x00, 0x00, 0x00) // Loop forever
+}
+
+// writeFrame writes the changed area of a frame with its graphic control extension and color table.
+func (g *GIF) writeFrame(pending *pendingFrame, end int) error {
+	frameDelay := delay(pending.start, end, 0.01)
+	if frameDelay > 0xFFFF {
+		frameDelay = 0xFFFF
+	}
+	g.write(0x21, 0xF9, 0x04, 0x04) // Graphic control extension, leave the frame in place
+	g.writeUint16(frameDelay)
+	g.write(0x00, 0x00) // No transparent color
+
+	rect := pending.rect
+	palette, indexes := paletteOf(pending.frame, g.sequence.width, rect)
+	bits := 1
+	for 1<<bits < len(palette) {
+		bits++
+	}
+
+	g.write(0x2C) // Image descriptor
+	g.writeUint16(rect.Min.X * g.scale)
+	g.writeUint16(rect.Min.Y * g.scale)
+	g.writeUint16(rect.Dx() * g.scale)
+	g.writeUint16(rect.Dy() * g.scale)
+	g.write(0x80 | byte(bits-1)) // Local color table
+	for i := 0; i < 1<<bits; i++ {
+		var color uint32
+		if i < len(palette) {
+			color = palette[i]
+		}
+		g.write(byte(color>>16), byte(color>>8), byte(color))
+	}
+
+	litWidth := bits
+	if litWidth < 2 { // The minimum LZW code size is 2, even for 2 colors
+		litWidth = 2
+	}
+	g.write(byte(litWidth))
+	blocks := &blockWriter{gif: g}
+	compressor := lzw.NewWriter(blocks, lzw.LSB, litWidth)
+	row := make([]byte, rect.Dx()*g.scale)
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			for i := 0; i < g.scale; i++ {
+				row[x*g.scale+i] = indexes[y*rect.Dx()+x]
+			}
+		}
+		for i := 0; i < g.scale; i++ {
+			compressor.Write(row)
+		}
+	}
+	compressor.Close()
+	blocks.close()
+	return g.err
+}
+
+// paletteOf returns the colors used in the changed area of a frame and the index of each of its pixels.
+func paletteOf(frame []uint32, width int, rect image.Rectangle) ([]uint32, []byte) {
+	indexes := make([]byte, 0, rect.Dx()*rect.Dy())
+	colors := make(map[uint32]byte)
+	var palette []uint32
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			color := frame[y*width+x] & 0xFFFFFF
+			index, ok := colors[color]
+			if !ok {
+				if len(palette) == 256 {
+					return reducedPalette(frame, width, rect)
+				}
+				index = byte(len(palette))
+				colors[color] = index
+				palette = append(palette, color)
+			}
+			indexes = append(indexes, index)
+		}
+	}
+	return palette, indexes
+}
+
+// reducedPalette is paletteOf for frames with too many colors: each pixel is stored as 3-3-2 bit RGB.
+func reducedPalette(frame []uint32, width int, rect image.Rectangle) ([]uint32, []byte) {
+	palette := make([]uint32, 256)
+	for i := range palette {
+		r, g, b := uint32(i>>5)*255/7, uint32(i>>2&7)*255/7, uint32(i&3)*255/3
+		palette[i] = r<<16 | g<<8 | b
+	}
+
+	indexes := make([]byte, 0, rect.Dx()*rect.Dy())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			pixel := frame[y*width+x]
+			indexes = append(indexes, byte(pixel>>16)&0xE0|byte(pixel>>11)&0x1C|byte(pixel>>6)&0x03)
+		}
+	}
+	return palette, indexes
+}
+
+// blockWriter splits the compressed image data in the sub-blocks of up to 255 bytes GIF requires.
+type blockWriter struct {
+	gif    *GIF
+	block  [255]byte
+	length int
+}
+
+func (b *blockWriter) Write(data []byte) (int, error) {
+	for _, value := range data {
+		b.block[b.length] = value
+		b.length++
+		if b.length == len(b.block) {
+			b.flush()
+		}
+	}
+	return len(data), b.gif.err
+}
+
+func (b *blockWriter) flush() {
+	if b.length == 0 {
+		return
+	}
+	b.gif.write(byte(b.length))
+	b.gif.write(b.block[:b.length]...)
+	b.length = 0
+}
+
+// close writes the last sub-block and the block terminator.
+func (b *blockWriter) close() {
+	b.flush()
+	b.gif.write(0x00)
+}
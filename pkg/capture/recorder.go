@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errSizeChanged = errors.New("the frame size changed during the recording")
+
+// Recorder writes an animation, a frame at a time.
+type Recorder interface {
+	// AddFrame adds a frame of width x height pixels, stored row by row as 0xAARRGGBB, shown for 1/59.7
+	// seconds. The size can't change, and the frame must not be modified afterwards.
+	AddFrame(frame []uint32, width, height int) error
+	// Frames returns the number of frames added.
+	Frames() int
+	// Close finishes the animation.
+	Close() error
+}
+
+// Create creates an animation file, an animated GIF if the path ends in .gif or an APNG if it ends in .png or
+// .apng. Each pixel is repeated scale x scale times.
+func Create(path string, scale int) (Recorder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".gif" && ext != ".png" && ext != ".apng" {
+		return nil, fmt.Errorf("unknown animation format %q, use .gif, .png or .apng", ext)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext == ".gif" {
+		return &fileRecorder{Recorder: NewGIF(f, scale), file: f}, nil
+	}
+	return &fileRecorder{Recorder: NewAPNG(f, scale), file: f}, nil
+}
+
+// fileRecorder closes the file once the animation is finished.
+type fileRecorder struct {
+	Recorder
+	file *os.File
+}
+
+func (r *fileRecorder) Close() error {
+	err := r.Recorder.Close()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sequence turns the frames added to a recorder into the frames written: each one holds the area that changed
+// since the previous frame and is shown until the next change, so identical frames are written once.
+type sequence struct {
+	previous []uint32
+	width    int
+	height   int
+	pending  *pendingFrame // Written once the next change shows how long it lasts
+	count    int
+}
+
+type pendingFrame struct {
+	frame []uint32
+	rect  image.Rectangle // Area that changed, in frame pixels
+	start int             // Index of the frame added
+}
+
+// writeFunc writes a frame, shown from the start of frame pending.start to the start of frame end.
+type writeFunc func(pending *pendingFrame, end int) error
+
+func (s *sequence) add(frame []uint32, width, height int, write writeFunc) error {
+	if s.previous != nil && (width != s.width || height != s.height) {
+		return errSizeChanged
+	}
+	if len(frame) < width*height {
+		return fmt.Errorf("frame has %d pixels, expected %d", len(frame), width*height)
+	}
+
+	rect := changedRect(frame, s.previous, width, height)
+	if rect.Empty() {
+		s.count++
+		return nil
+	}
+
+	if err := s.flush(write); err != nil {
+		return err
+	}
+	s.pending = &pendingFrame{frame: frame, rect: rect, start: s.count}
+	s.previous, s.width, s.height = frame, width, height
+	s.count++
+	return nil
+}
+
+// flush writes the pending frame.
+func (s *sequence) flush(write writeFunc) error {
+	if s.pending == nil {
+		return nil
+	}
+	pending := s.pending
+	s.pending = nil
+	return write(pending, s.count)
+}
+
+// delay returns the time from the start of frame start to the start of frame end in units of the given
+// seconds. Rounding each frame start instead of each frame keeps the animation at 59.7 fps on average.
+func delay(start, end int, unit float64) int {
+	startTime := math.Round(float64(start) * frameSeconds / unit)
+	endTime := math.Round(float64(end) * frameSeconds / unit)
+	return int(endTime - startTime)
+}
@@ -0,0 +1,180 @@
+package capture
+
+import (
+	"errors"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/log"
+	"os"
+	"sync"
+)
+
+// Options configure the captures of a Session.
+type Options struct {
+	// Dir is the folder where the screenshots and recordings taken with the hotkeys are saved.
+	Dir string
+	// Scale is how many times each pixel is repeated horizontally and vertically, 1 if 0.
+	Scale int
+	// Format is the format of the recordings taken with the hotkey: gif or apng. apng if empty.
+	Format string
+}
+
+// Session takes screenshots and records every frame of a machine. Frame must be called before each frame is run,
+// the rest can be called from other goroutines.
+type Session struct {
+	machine *goboy.Machine
+	options Options
+	logger  log.Logger
+
+	mutex     sync.Mutex
+	recorder  Recorder
+	path      string // File being recorded
+	lastFrame uint64 // Frame count of the last frame recorded
+}
+
+// New returns a session capturing the screen of the machine. Errors of the captures taken with the hotkeys are
+// logged.
+func New(machine *goboy.Machine, options Options, logger log.Logger) (*Session, error) {
+	if options.Scale == 0 {
+		options.Scale = 1
+	}
+	if options.Scale < 0 {
+		return nil, errors.New("the capture scale must be positive")
+	}
+	if options.Format == "" {
+		options.Format = "apng"
+	}
+	if options.Format != "gif" && options.Format != "apng" {
+		return nil, errors.New("the recording format must be gif or apng")
+	}
+	return &Session{machine: machine, options: options, logger: logger}, nil
+}
+
+// Screenshot saves what the screen shows as a PNG file.
+func (s *Session) Screenshot(path string) error {
+	frame, width, height := s.machine.Screen()
+	return SavePNG(path, frame, width, height, s.options.Scale)
+}
+
+// StartRecording starts recording every frame run to an animation file, a GIF if the path ends in .gif or an APNG
+// if it ends in .png or .apng.
+func (s *Session) StartRecording(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.recorder != nil {
+		return errors.New("already recording")
+	}
+	recorder, err := Create(path, s.options.Scale)
+	if err != nil {
+		return err
+	}
+	s.recorder, s.path, s.lastFrame = recorder, path, s.machine.FrameCount()
+	return nil
+}
+
+// StopRecording finishes the recording, if any. The file is removed if no frame was run whilst recording.
+func (s *Session) StopRecording() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.recorder == nil {
+		return nil
+	}
+	s.recordFrame()
+
+	recorder := s.recorder
+	s.recorder = nil
+	err := recorder.Close()
+	if recorder.Frames() == 0 {
+		os.Remove(s.path)
+	}
+	return err
+}
+
+// Recording returns whether frames are being recorded.
+func (s *Session) Recording() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.recorder != nil
+}
+
+// Frame records the last frame run, if it hasn't been yet.
+func (s *Session) Frame() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.recordFrame()
+}
+
+// recordFrame must be called with the mutex held. The recording is stopped if it fails.
+func (s *Session) recordFrame() {
+	if s.recorder == nil || s.machine.FrameCount() == s.lastFrame {
+		return
+	}
+	s.lastFrame = s.machine.FrameCount()
+
+	frame, width, height := s.machine.Screen()
+	if err := s.recorder.AddFrame(frame, width, height); err != nil {
+		s.logger.Debugf("error recording %s: %s", s.path, err)
+		s.recorder.Close()
+		s.recorder = nil
+	}
+}
+
+// takeScreenshot saves a screenshot with the next free name.
+func (s *Session) takeScreenshot() {
+	path := NextFileName(s.options.Dir, "screenshot", ".png")
+	if err := s.Screenshot(path); err != nil {
+		s.logger.Debugf("error saving screenshot %s: %s", path, err)
+		return
+	}
+	s.logger.Debugf("screenshot saved to %s", path)
+}
+
+// toggleRecording starts a recording with the next free name, or stops the one in progress.
+func (s *Session) toggleRecording() {
+	if s.Recording() {
+		if err := s.StopRecording(); err != nil {
+			s.logger.Debugf("error saving recording: %s", err)
+		}
+		return
+	}
+
+	ext := ".png"
+	if s.options.Format == "gif" {
+		ext = ".gif"
+	}
+	path := NextFileName(s.options.Dir, "recording", ext)
+	if err := s.StartRecording(path); err != nil {
+		s.logger.Debugf("error recording %s: %s", path, err)
+		return
+	}
+	s.logger.Debugf("recording to %s", path)
+}
+
+// Wrap returns a frontend that records the frames run and takes the captures asked for with the screenshot and
+// record hotkeys of f.
+func (s *Session) Wrap(f frontend.Frontend) frontend.Frontend {
+	return &sessionFrontend{Frontend: f, session: s}
+}
+
+type sessionFrontend struct {
+	frontend.Frontend
+	session *Session
+}
+
+func (f *sessionFrontend) Poll() (goboy.Buttons, bool) {
+	f.session.Frame()
+	return f.Frontend.Poll()
+}
+
+func (f *sessionFrontend) Hotkeys() frontend.Hotkeys {
+	hotkeys := f.Frontend.Hotkeys()
+	if hotkeys.Screenshot {
+		f.session.takeScreenshot()
+	}
+	if hotkeys.Record {
+		f.session.toggleRecording()
+	}
+	return hotkeys
+}
@@ -52,6 +52,16 @@ func (c *CartridgeHeader) SGBFlag() bool {
 	return false
 }
 
+// CGBFlag returns true if the game supports CGB functions.
+func (c *CartridgeHeader) CGBFlag() bool {
+	return IsCgbFlag(c.CgbFlag)
+}
+
+// IsCgbFlag returns true if the CGB flag value from the cartridge header enables color mode.
+func IsCgbFlag(cgbFlag byte) bool {
+	return cgbFlag == CgbFlagCompatible || cgbFlag == CgbFlagOnly
+}
+
 func (c *CartridgeHeader) GetReadableCartridgeType() string {
 	return CartridgeType[c.CartridgeType]
 }
@@ -77,10 +87,18 @@ type CartridgeInterface interface {
 	CartWrite(address uint16, value byte)
 }
 
+// BankedCartridge is implemented by cartridges that can report which ROM bank is mapped at 0x4000-0x7FFF.
+type BankedCartridge interface {
+	CurrentRomBank() int
+}
+
 // Cartridge implements all the logic regarding GB cartridges
 type Cartridge struct {
 	CartridgeHeader *CartridgeHeader
 	rawData         []byte
+	ram             []byte // External RAM, empty if the cartridge has none
+	mbc             mbcKind
+	banks           banks
 	logger          log.Logger
 }
 
@@ -91,6 +109,15 @@ func NewCartridge(romPath string, logger log.Logger) (*Cartridge, error) {
 		return nil, fmt.Errorf("error while loading ROM cartridges - %v", err)
 	}
 
+	return NewCartridgeFromBytes(romData, logger)
+}
+
+// NewCartridgeFromBytes returns a pointer to Cartridge given the ROM contents
+func NewCartridgeFromBytes(romData []byte, logger log.Logger) (*Cartridge, error) {
+	if len(romData) <= int(GlobalChecksumAddrEnd) {
+		return nil, fmt.Errorf("ROM is too small to have a cartridge header (%d bytes)", len(romData))
+	}
+
 	// Do checksum to ensure cartridge integrity
 	var checksum uint8
 	for address := TitleAddrStart; address <= MaskRomVersionNumberAddr; address++ {
@@ -101,22 +128,76 @@ func NewCartridge(romPath string, logger log.Logger) (*Cartridge, error) {
 		return nil, fmt.Errorf("calculated header checksum doesn't correspond with cartridge checksum")
 	}
 
+	header := parseCartridgeHeader(romData)
 	return &Cartridge{
-		CartridgeHeader: parseCartridgeHeader(romData),
+		CartridgeHeader: header,
 		rawData:         romData,
+		ram:             make([]byte, RamSizeBytes[header.RamSize]),
+		mbc:             mbcKindOf(header.CartridgeType),
+		banks:           powerOnBanks(),
 		logger:          logger,
 	}, nil
 }
 
 // CartRead returns a given byte from the cartridge given a memory address
 func (c *Cartridge) CartRead(address uint16) byte {
-	// For now just ROM only type supported
-	return c.rawData[address]
+	if address >= ExternalRamStart && address <= ExternalRamEnd {
+		return c.readRam(address)
+	}
+
+	offset := c.romOffset(address)
+	if offset >= len(c.rawData) {
+		return 0xFF
+	}
+	return c.rawData[offset]
 }
 
-// CartWrite write a value in the address specified
+// CartWrite write a value in the address specified. Writes to the ROM set the bank registers of the MBC.
 func (c *Cartridge) CartWrite(address uint16, value byte) {
-	// For now just ROM only type supported
+	if address >= ExternalRamStart && address <= ExternalRamEnd {
+		c.writeRam(address, value)
+		return
+	}
+	c.writeBankRegister(address, value)
+}
+
+// readRam reads the external RAM, or the clock on MBC3 cartridges. Reads return 0xFF if the cartridge has no
+// RAM or it isn't enabled, like an open bus.
+func (c *Cartridge) readRam(address uint16) byte {
+	if register := c.rtcRegister(); register != nil {
+		if !c.banks.ramEnabled {
+			return 0xFF
+		}
+		return *register
+	}
+
+	if !c.ramAccessible() {
+		return 0xFF
+	}
+	return c.ram[c.ramOffset(address)]
+}
+
+func (c *Cartridge) writeRam(address uint16, value byte) {
+	if register := c.rtcRegister(); register != nil {
+		if c.banks.ramEnabled {
+			*register = value
+		}
+		return
+	}
+
+	if c.ramAccessible() {
+		c.ram[c.ramOffset(address)] = value
+	}
+}
+
+// CurrentRomBank returns the ROM bank mapped at 0x4000-0x7FFF.
+func (c *Cartridge) CurrentRomBank() int {
+	return c.romBank(switchableRomStart) % c.romBanks()
+}
+
+// Reset puts the bank registers back to their power on values. The RAM is kept, like on a real cartridge.
+func (c *Cartridge) Reset() {
+	c.banks = powerOnBanks()
 }
 
 func parseCartridgeHeader(cartridgeRawData []byte) *CartridgeHeader {
@@ -140,12 +221,13 @@ func parseCartridgeHeader(cartridgeRawData []byte) *CartridgeHeader {
 }
 
 func (c *Cartridge) LogCartridgeHeaderInfo() {
-	c.logger.Debugf("Cartridge information:\nTitle:%s\nLicensee:%s\nCartridge type:%s\nRom size:%d KiB\nRam size:%s KiB\nSGB flag:%t\n",
+	c.logger.Debugf("Cartridge information:\nTitle:%s\nLicensee:%s\nCartridge type:%s\nRom size:%d KiB\nRam size:%s KiB\nSGB flag:%t\nCGB flag:%t\n",
 		c.CartridgeHeader.GetReadableTitle(),
 		c.CartridgeHeader.GetReadableLicenseeCode(),
 		c.CartridgeHeader.GetReadableCartridgeType(),
 		c.CartridgeHeader.GetReadableRomSize(),
 		c.CartridgeHeader.GetReadableRamSize(),
 		c.CartridgeHeader.SGBFlag(),
+		c.CartridgeHeader.CGBFlag(),
 	)
 }
@@ -3,6 +3,7 @@ package cart
 import (
 	"github.com/mikeletux/goboy/pkg/log"
 	"github.com/mikeletux/goboy/pkg/test"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
@@ -15,9 +16,17 @@ const (
 )
 
 func TestCartridge(t *testing.T) {
-	cartridge, err := NewCartridge(testRomPath, log.NewBuiltinStdoutLogger())
+	romPath := testRomPath
+	if env := os.Getenv("GOBOY_TETRIS_ROM"); len(env) > 0 {
+		romPath = env
+	}
+	if _, err := os.Stat(romPath); err != nil {
+		t.Skipf("Tetris ROM not found at %s, set GOBOY_TETRIS_ROM to its location", romPath)
+	}
+
+	cartridge, err := NewCartridge(romPath, &log.NilLogger{})
 	if err != nil {
-		t.Errorf("error while initiating cartridge - %s", err)
+		t.Fatalf("error while initiating cartridge - %s", err)
 	}
 
 	t.Parallel()
@@ -47,3 +56,158 @@ func TestCartridge(t *testing.T) {
 	})
 
 }
+
+func TestNewCartridgeFromBytes(t *testing.T) {
+	rom := make([]byte, 0x8000)
+	copy(rom[NintendoLogoAddrStart:], test.NintendoCartridgeLogo)
+	copy(rom[TitleAddrStart:], "TEST")
+	rom[RamSizeAddr] = 0x02 // 8 KiB
+
+	if _, err := NewCartridgeFromBytes(rom, &log.NilLogger{}); err == nil {
+		t.Error("expected a header checksum error")
+	}
+
+	var checksum uint8
+	for address := TitleAddrStart; address <= MaskRomVersionNumberAddr; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	rom[HeaderChecksumAddr] = checksum
+
+	cartridge, err := NewCartridgeFromBytes(rom, &log.NilLogger{})
+	if err != nil {
+		t.Fatalf("error while initiating cartridge - %s", err)
+	}
+	if title := cartridge.CartridgeHeader.GetReadableTitle(); !strings.HasPrefix(title, "TEST") {
+		t.Errorf("expected title TEST got %q", title)
+	}
+
+	cartridge.CartWrite(ExternalRamStart, 0x42)
+	if got := cartridge.CartRead(ExternalRamStart); got != 0x42 {
+		t.Errorf("expected external RAM to hold 42 got %02X", got)
+	}
+
+	if _, err := NewCartridgeFromBytes(rom[:0x100], &log.NilLogger{}); err == nil {
+		t.Error("expected an error for a ROM without header")
+	}
+}
+
+// newBankedCartridge returns a cartridge of the given type with 64 banks of ROM, each of them holding its bank
+// number at 0x200, and 32 KiB of RAM.
+func newBankedCartridge(t *testing.T, cartridgeType byte) *Cartridge {
+	rom := make([]byte, 64*romBankSize)
+	for bank := 0; bank < 64; bank++ {
+		rom[bank*romBankSize+0x200] = byte(bank)
+	}
+	rom[CartridgeTypeAddr] = cartridgeType
+	rom[RomSizeAddr] = 0x05 // 1 MiB
+	rom[RamSizeAddr] = 0x03 // 32 KiB
+
+	var checksum uint8
+	for address := TitleAddrStart; address <= MaskRomVersionNumberAddr; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	rom[HeaderChecksumAddr] = checksum
+
+	cartridge, err := NewCartridgeFromBytes(rom, &log.NilLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cartridge
+}
+
+func TestMbcRomBanks(t *testing.T) {
+	tests := []struct {
+		name          string
+		cartridgeType byte
+		writes        map[uint16]byte
+		bank0, bank   int
+	}{
+		{"MBC1 power on", 0x01, nil, 0, 1},
+		{"MBC1 bank 5", 0x01, map[uint16]byte{0x2000: 0x05}, 0, 5},
+		{"MBC1 bank 0 selects 1", 0x01, map[uint16]byte{0x2000: 0x00}, 0, 1},
+		{"MBC1 upper bits", 0x01, map[uint16]byte{0x2000: 0x03, 0x4000: 0x01}, 0, 0x23},
+		{"MBC1 mode 1", 0x01, map[uint16]byte{0x2000: 0x03, 0x4000: 0x01, 0x6000: 0x01}, 0x20, 0x23},
+		{"MBC3 bank 0x3A", 0x13, map[uint16]byte{0x2000: 0x3A}, 0, 0x3A},
+		{"MBC3 bank 0 selects 1", 0x13, map[uint16]byte{0x2000: 0x00}, 0, 1},
+		{"MBC5 bank 0", 0x1B, map[uint16]byte{0x2000: 0x00}, 0, 0},
+		{"MBC5 bank 0x2F", 0x1B, map[uint16]byte{0x2000: 0x2F}, 0, 0x2F},
+		{"MBC5 wraps around", 0x1B, map[uint16]byte{0x2000: 0x05, 0x3000: 0x01}, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cartridge := newBankedCartridge(t, tt.cartridgeType)
+			for address, value := range tt.writes {
+				cartridge.CartWrite(address, value)
+			}
+
+			if got := cartridge.CartRead(0x0200); got != byte(tt.bank0) {
+				t.Errorf("expected bank %02X at 0000-3FFF got %02X", tt.bank0, got)
+			}
+			if got := cartridge.CartRead(0x4200); got != byte(tt.bank) {
+				t.Errorf("expected bank %02X at 4000-7FFF got %02X", tt.bank, got)
+			}
+			if got := cartridge.CurrentRomBank(); got != tt.bank {
+				t.Errorf("expected current ROM bank %02X got %02X", tt.bank, got)
+			}
+		})
+	}
+}
+
+func TestMbcRamBanks(t *testing.T) {
+	for _, cartridgeType := range []byte{0x03, 0x13, 0x1B} {
+		cartridge := newBankedCartridge(t, cartridgeType)
+
+		cartridge.CartWrite(ExternalRamStart, 0x42)
+		if got := cartridge.CartRead(ExternalRamStart); got != 0xFF {
+			t.Errorf("type %02X: expected disabled RAM to read FF got %02X", cartridgeType, got)
+		}
+
+		cartridge.CartWrite(0x0000, 0x0A)
+		cartridge.CartWrite(0x6000, 0x01) // MBC1 only maps the RAM banks in mode 1
+		for bank := byte(0); bank < 4; bank++ {
+			cartridge.CartWrite(0x4000, bank)
+			cartridge.CartWrite(ExternalRamStart, 0x10+bank)
+		}
+		for bank := byte(0); bank < 4; bank++ {
+			cartridge.CartWrite(0x4000, bank)
+			if got := cartridge.CartRead(ExternalRamStart); got != 0x10+bank {
+				t.Errorf("type %02X: expected RAM bank %d to hold %02X got %02X", cartridgeType, bank, 0x10+bank, got)
+			}
+		}
+	}
+}
+
+func TestMbc3Rtc(t *testing.T) {
+	cartridge := newBankedCartridge(t, 0x10)
+	cartridge.CartWrite(0x0000, 0x0A)
+	cartridge.CartWrite(0x4000, 0x08) // Seconds
+	cartridge.CartWrite(ExternalRamStart, 42)
+	cartridge.CartWrite(0x4000, 0x00)
+
+	if got := cartridge.CartRead(ExternalRamStart); got == 42 {
+		t.Error("expected RAM bank 0 to be mapped instead of the clock")
+	}
+	cartridge.CartWrite(0x4000, 0x08)
+	if got := cartridge.CartRead(ExternalRamStart); got != 42 {
+		t.Errorf("expected seconds register to hold 42 got %d", got)
+	}
+}
+
+func TestMbcState(t *testing.T) {
+	cartridge := newBankedCartridge(t, 0x1B)
+	cartridge.CartWrite(0x2000, 0x07)
+	state := cartridge.State()
+
+	cartridge.Reset()
+	if got := cartridge.CurrentRomBank(); got != 1 {
+		t.Errorf("expected bank 1 after reset got %d", got)
+	}
+
+	if err := cartridge.LoadState(state); err != nil {
+		t.Fatal(err)
+	}
+	if got := cartridge.CurrentRomBank(); got != 7 {
+		t.Errorf("expected bank 7 after loading the state got %d", got)
+	}
+}
@@ -24,6 +24,18 @@ const (
 	GlobalChecksumAddrEnd    uint16 = 0x14D
 )
 
+// External RAM area, mapped to the cartridge RAM
+const (
+	ExternalRamStart uint16 = 0xA000
+	ExternalRamEnd   uint16 = 0xBFFF
+)
+
+// CGB flag values
+const (
+	CgbFlagCompatible byte = 0x80 // The game supports CGB enhancements, but is backwards compatible with DMG
+	CgbFlagOnly       byte = 0xC0 // The game works on CGB only
+)
+
 var NewLicenseeCodePublishers = map[uint16]string{
 	0x0:  "None",
 	0x1:  "Nintendo R&D1",
@@ -145,6 +157,14 @@ var RamSize = map[byte]string{
 	0x5: "64 KiB",
 }
 
+// RamSizeBytes is the cartridge RAM size in bytes for each RAM size code
+var RamSizeBytes = map[byte]int{
+	0x2: 8 * 1024,
+	0x3: 32 * 1024,
+	0x4: 128 * 1024,
+	0x5: 64 * 1024,
+}
+
 var DestinationCode = map[byte]string{
 	0x0: "Japan (and possibly overseas)",
 	0x1: "Overseas only",
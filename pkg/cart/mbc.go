@@ -0,0 +1,170 @@
+package cart
+
+// mbcKind is the memory bank controller of a cartridge, which maps the ROM and RAM banks into the address space.
+type mbcKind byte
+
+const (
+	noMbc mbcKind = iota
+	mbc1
+	mbc3
+	mbc5
+)
+
+const (
+	romBankSize = 0x4000
+	ramBankSize = 0x2000
+
+	switchableRomStart uint16 = 0x4000
+
+	// Bank register areas, written through the ROM addresses
+	ramEnableEnd   uint16 = 0x1FFF
+	romBankLowEnd  uint16 = 0x2FFF // MBC5 only, the other MBCs use 2000-3FFF as a whole
+	romBankEnd     uint16 = 0x3FFF
+	ramBankEnd     uint16 = 0x5FFF
+	bankingModeEnd uint16 = 0x7FFF
+
+	ramEnableValue byte = 0x0A // Written to the lower nibble of 0000-1FFF to enable the RAM
+
+	// MBC3 RAM bank numbers 08-0C select the clock registers instead of a RAM bank
+	mbc3RtcFirstRegister = 0x08
+	mbc3RtcLastRegister  = 0x0C
+)
+
+// mbcKindOf returns the memory bank controller used by the given cartridge type. MBC2, MMM01 and the other
+// rare controllers are not supported, so they are treated as ROM only.
+func mbcKindOf(cartridgeType byte) mbcKind {
+	switch {
+	case cartridgeType >= 0x01 && cartridgeType <= 0x03:
+		return mbc1
+	case cartridgeType >= 0x0F && cartridgeType <= 0x13:
+		return mbc3
+	case cartridgeType >= 0x19 && cartridgeType <= 0x1E:
+		return mbc5
+	}
+	return noMbc
+}
+
+// banks holds the bank registers of the memory bank controller.
+type banks struct {
+	ramEnabled  bool
+	romBank     int     // ROM bank register as written, MBC1 only keeps the lower 5 bits here
+	ramBank     int     // RAM bank register, MBC1 also uses it as the upper 2 bits of the ROM bank
+	bankingMode byte    // MBC1 only, in mode 1 ramBank also applies to 0000-3FFF and A000-BFFF
+	rtc         [5]byte // MBC3 clock: seconds, minutes, hours, lower 8 bits of the day and day high/flags
+}
+
+// powerOnBanks returns the bank registers of a cartridge that has just been powered on.
+func powerOnBanks() banks {
+	return banks{romBank: 1}
+}
+
+// romBank returns the ROM bank mapped at the given address, before wrapping it around the ROM size.
+func (c *Cartridge) romBank(address uint16) int {
+	b := &c.banks
+	if address < switchableRomStart {
+		if c.mbc == mbc1 && b.bankingMode == 1 {
+			return b.ramBank << 5
+		}
+		return 0
+	}
+
+	switch c.mbc {
+	case mbc1:
+		bank := b.romBank & 0x1F
+		if bank == 0 { // Bank 0 can't be selected, 00, 20, 40 and 60 select the next one
+			bank = 1
+		}
+		return bank | b.ramBank<<5
+	case mbc3:
+		bank := b.romBank & 0x7F
+		if bank == 0 {
+			bank = 1
+		}
+		return bank
+	case mbc5:
+		return b.romBank & 0x1FF
+	}
+	return 1
+}
+
+// romBanks returns the number of 16 KiB banks in the ROM.
+func (c *Cartridge) romBanks() int {
+	if banks := (len(c.rawData) + romBankSize - 1) / romBankSize; banks > 0 {
+		return banks
+	}
+	return 1
+}
+
+// romOffset returns the offset in the ROM of an address in 0000-7FFF. Bank numbers larger than the ROM wrap
+// around, like the unconnected upper bits of the bank register on a real cartridge.
+func (c *Cartridge) romOffset(address uint16) int {
+	bank := c.romBank(address) % c.romBanks()
+	return bank*romBankSize + int(address)%romBankSize
+}
+
+// ramAccessible returns whether the game can read and write the external RAM, which has to be enabled first
+// on cartridges with an MBC.
+func (c *Cartridge) ramAccessible() bool {
+	return len(c.ram) > 0 && (c.mbc == noMbc || c.banks.ramEnabled)
+}
+
+// ramOffset returns the offset in the external RAM of an address in A000-BFFF.
+func (c *Cartridge) ramOffset(address uint16) int {
+	bank := c.banks.ramBank
+	if c.mbc == noMbc || (c.mbc == mbc1 && c.banks.bankingMode == 0) {
+		bank = 0
+	}
+	return (bank*ramBankSize + int(address-ExternalRamStart)) % len(c.ram)
+}
+
+// rtcRegister returns the MBC3 clock register mapped at A000-BFFF, or nil if a RAM bank is mapped.
+func (c *Cartridge) rtcRegister() *byte {
+	if c.mbc != mbc3 || c.banks.ramBank < mbc3RtcFirstRegister || c.banks.ramBank > mbc3RtcLastRegister {
+		return nil
+	}
+	return &c.banks.rtc[c.banks.ramBank-mbc3RtcFirstRegister]
+}
+
+// writeBankRegister handles a write to the ROM area, which sets the registers of the MBC.
+func (c *Cartridge) writeBankRegister(address uint16, value byte) {
+	b := &c.banks
+
+	switch c.mbc {
+	case mbc1:
+		switch {
+		case address <= ramEnableEnd:
+			b.ramEnabled = value&0x0F == ramEnableValue
+		case address <= romBankEnd:
+			b.romBank = int(value & 0x1F)
+		case address <= ramBankEnd:
+			b.ramBank = int(value & 0x03)
+		case address <= bankingModeEnd:
+			b.bankingMode = value & 0x01
+		}
+
+	case mbc3:
+		switch {
+		case address <= ramEnableEnd:
+			b.ramEnabled = value&0x0F == ramEnableValue
+		case address <= romBankEnd:
+			b.romBank = int(value & 0x7F)
+		case address <= ramBankEnd:
+			b.ramBank = int(value & 0x0F)
+		case address <= bankingModeEnd:
+			// Latching the clock copies the running clock into the registers. The clock doesn't run, so the
+			// registers always hold the time the game set.
+		}
+
+	case mbc5:
+		switch {
+		case address <= ramEnableEnd:
+			b.ramEnabled = value&0x0F == ramEnableValue
+		case address <= romBankLowEnd:
+			b.romBank = b.romBank&0x100 | int(value)
+		case address <= romBankEnd:
+			b.romBank = b.romBank&0xFF | int(value&0x01)<<8
+		case address <= ramBankEnd:
+			b.ramBank = int(value & 0x0F)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package cart
+
+import "fmt"
+
+// State is a snapshot of the cartridge, used for save states.
+type State struct {
+	Ram []byte
+
+	RamEnabled  bool
+	RomBank     int
+	RamBank     int
+	BankingMode byte
+	Rtc         [5]byte
+}
+
+// State returns a snapshot of the cartridge.
+func (c *Cartridge) State() State {
+	ram := make([]byte, len(c.ram))
+	copy(ram, c.ram)
+	return State{
+		Ram:         ram,
+		RamEnabled:  c.banks.ramEnabled,
+		RomBank:     c.banks.romBank,
+		RamBank:     c.banks.ramBank,
+		BankingMode: c.banks.bankingMode,
+		Rtc:         c.banks.rtc,
+	}
+}
+
+// LoadState restores a snapshot taken with State. It fails if the snapshot was taken from a cartridge with a
+// different RAM size.
+func (c *Cartridge) LoadState(state State) error {
+	if len(state.Ram) != len(c.ram) {
+		return fmt.Errorf("state has %d bytes of cartridge RAM but the cartridge has %d", len(state.Ram), len(c.ram))
+	}
+
+	copy(c.ram, state.Ram)
+	c.banks = banks{
+		ramEnabled:  state.RamEnabled,
+		romBank:     state.RomBank,
+		ramBank:     state.RamBank,
+		bankingMode: state.BankingMode,
+		rtc:         state.Rtc,
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"github.com/mikeletux/goboy/pkg/frontend"
 	"golang.org/x/sys/unix"
 	"gopkg.in/yaml.v3"
 	"os"
@@ -16,6 +17,12 @@ type Config struct {
 	LogStdoutEnable bool   `yaml:"log_stdout_enable"`
 	LogFileEnable   bool   `yaml:"log_file_enable"`
 	LogFilePath     string `yaml:"log_file_path"`
+
+	PrinterEnable     bool   `yaml:"printer_enable"`
+	PrinterOutputPath string `yaml:"printer_output_path"`
+
+	Speed       float64 `yaml:"speed"` // Multiplier of the emulation speed, 1 if not set
+	StartPaused bool    `yaml:"start_paused"`
 }
 
 func (c *Config) checkEssentialValues() (bool, string) {
@@ -28,6 +35,10 @@ func (c *Config) checkEssentialValues() (bool, string) {
 		missingValues = append(missingValues, "log_file_path")
 	}
 
+	if c.PrinterEnable && len(c.PrinterOutputPath) == 0 {
+		missingValues = append(missingValues, "printer_output_path")
+	}
+
 	if len(missingValues) > 0 {
 		return false, strings.Join(missingValues, ",")
 	}
@@ -35,6 +46,10 @@ func (c *Config) checkEssentialValues() (bool, string) {
 	return true, ""
 }
 
+func (c *Config) checkSpeed() bool {
+	return c.Speed == 0 || (c.Speed >= frontend.MinSpeed && c.Speed <= frontend.MaxSpeed)
+}
+
 func (c *Config) checkRomExist() bool {
 	_, err := os.Stat(c.RomPath)
 	if os.IsNotExist(err) {
@@ -57,6 +72,15 @@ func (c *Config) checkLogPathIsWritable() bool {
 	return unix.Access(filepath.Dir(c.LogFilePath), unix.W_OK) == nil
 }
 
+func (c *Config) checkPrinterPathIsWritable() bool {
+	_, err := os.Stat(c.PrinterOutputPath)
+	if err != nil {
+		return false
+	}
+
+	return unix.Access(c.PrinterOutputPath, unix.W_OK) == nil
+}
+
 func NewConfigParser(configPathIn string) (*Parser, error) {
 	configPath := configPathIn
 	if len(configPath) == 0 {
@@ -88,6 +112,10 @@ func (p Parser) Parse() (*Config, error) {
 		return nil, &MissingConfigValuesError{missingParameters}
 	}
 
+	if !configStruct.checkSpeed() {
+		return nil, &SpeedError{configStruct.Speed}
+	}
+
 	if !configStruct.checkRomExist() {
 		return nil, &RomNotFoundError{configStruct.RomPath}
 	}
@@ -96,5 +124,9 @@ func (p Parser) Parse() (*Config, error) {
 		return nil, &LogWriteError{configStruct.LogFilePath}
 	}
 
+	if configStruct.PrinterEnable && !configStruct.checkPrinterPathIsWritable() {
+		return nil, &PrinterWriteError{configStruct.PrinterOutputPath}
+	}
+
 	return configStruct, nil
 }
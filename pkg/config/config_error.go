@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"github.com/mikeletux/goboy/pkg/frontend"
 	"path/filepath"
 )
 
@@ -36,6 +37,16 @@ func (m *MissingConfigValuesError) Error() string {
 		m.missingParameters)
 }
 
+// SpeedError is an error intended to be returned when the speed set is out of the supported range
+type SpeedError struct {
+	speed float64
+}
+
+func (s *SpeedError) Error() string {
+	return fmt.Sprintf("Speed %g is not supported, it must be between %g and %g.", s.speed, frontend.MinSpeed,
+		frontend.MaxSpeed)
+}
+
 // RomNotFoundError is an error intended to be returned when the rom specified does not exist
 type RomNotFoundError struct {
 	romPath string
@@ -55,3 +66,13 @@ func (l *LogWriteError) Error() string {
 	return fmt.Sprintf("Folder %s does not exist or do not have permission to write the log.",
 		filepath.Dir(l.filePath))
 }
+
+// PrinterWriteError is an error intended to be returned when the printer output folder does not exist or the user
+// does not have permission to write in it
+type PrinterWriteError struct {
+	dirPath string
+}
+
+func (p *PrinterWriteError) Error() string {
+	return fmt.Sprintf("Folder %s does not exist or do not have permission to write the printouts.", p.dirPath)
+}
@@ -16,18 +16,36 @@ const (
 	initLRegisterValue  byte   = 0x4D
 	initSPRegisterValue uint16 = 0xFFFE
 	initPCRegisterValue uint16 = 0x0100
+
+	// CGB boot ROM leaves different values in some registers
+	initCgbARegisterValue byte = 0x11
+	initCgbFRegisterValue byte = 0x80
+	initCgbDRegisterValue byte = 0xFF
+	initCgbERegisterValue byte = 0x56
+	initCgbHRegisterValue byte = 0x00
+	initCgbLRegisterValue byte = 0x0D
 )
 
 const (
-	joypadIOAddr                uint16 = 0xFF00
-	serialTransferDataIOAddr    uint16 = 0xFF01
-	serialTransferControlIOAddr uint16 = 0xFF02
+	joypadIOAddr uint16 = 0xFF00
 
 	interruptFlagIOAddr uint16 = 0xFF0F
 
 	interruptEnableAddr uint16 = 0xFFFF
 )
 
+// Tracer is implemented by the execution tracers. Trace is called before every instruction is executed, with
+// InstructionPC and Cycles pointing at that instruction.
+type Tracer interface {
+	Trace(c *CPU)
+}
+
+// Ticker is implemented by the components that run alongside the CPU, like the PPU.
+// Tick is called once per dot, which is not affected by CGB double speed.
+type Ticker interface {
+	Tick()
+}
+
 type CPU struct {
 	registers *Registers
 	bus       bus.DataBusInterface
@@ -43,15 +61,18 @@ type CPU struct {
 	EnablingIme               bool
 
 	Halted   bool
-	Stepping bool
+	Stepping bool // Stepping is set whilst a debugger keeps the CPU paused
 
-	ticks uint64
+	ticks         uint64
+	tickers       []Ticker
+	instructionPC uint16 // Address of the instruction being executed
 
+	tracer Tracer
 	logger log.Logger
 }
 
 func Init(bus bus.DataBusInterface, logger log.Logger) *CPU {
-	return &CPU{
+	cpu := &CPU{
 		registers: &Registers{
 			A:  initARegisterValue,
 			F:  initFRegisterValue,
@@ -67,12 +88,60 @@ func Init(bus bus.DataBusInterface, logger log.Logger) *CPU {
 		bus:    bus,
 		logger: logger,
 	}
+
+	if bus != nil && bus.IsCgbMode() {
+		cpu.registers.A = initCgbARegisterValue
+		cpu.registers.F = initCgbFRegisterValue
+		cpu.registers.D = initCgbDRegisterValue
+		cpu.registers.E = initCgbERegisterValue
+		cpu.registers.H = initCgbHRegisterValue
+		cpu.registers.L = initCgbLRegisterValue
+	}
+
+	return cpu
+}
+
+// Registers returns the CPU registers. Changes made to them take effect straight away, which is what
+// debuggers need to modify the CPU state.
+func (c *CPU) Registers() *Registers {
+	return c.registers
+}
+
+// InstructionPC returns the address of the instruction being executed.
+func (c *CPU) InstructionPC() uint16 {
+	return c.instructionPC
+}
+
+// Cycles returns the T-cycles run since power on.
+func (c *CPU) Cycles() uint64 {
+	return c.ticks
+}
+
+// SetTracer sets the tracer notified of every instruction executed. nil disables tracing.
+func (c *CPU) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
+// Tracer returns the tracer set with SetTracer, so other tracers can be chained to it.
+func (c *CPU) Tracer() Tracer {
+	return c.tracer
+}
+
+// AddTicker registers a component that needs to be ticked alongside the CPU.
+func (c *CPU) AddTicker(ticker Ticker) {
+	c.tickers = append(c.tickers, ticker)
 }
 
 func (c *CPU) Step() bool {
+	c.bus.ApplyQueuedJoypadButtons()
+
 	if !c.Halted {
 		// Fetch instruction
-		instructionPC := c.registers.PC // used for debugging purposes
+		c.instructionPC = c.registers.PC
+		if c.tracer != nil {
+			c.tracer.Trace(c)
+		}
+
 		c.CurrentOperationCode = c.bus.BusRead(c.registers.GetPCAndIncrement())
 		instruction, ok := instructionsMap[c.CurrentOperationCode]
 		if !ok {
@@ -81,11 +150,7 @@ func (c *CPU) Step() bool {
 		c.CurrentInstruction = instruction
 		c.emulateCpuCycles(1)
 
-		c.logRegistersGameboyDoctor(instructionPC)
-		// c.logRegisterValues(instructionPC) // used for debugging purposes
-
-		c.dbgUpdate() // Useful for debugging roms
-		c.dbgPrint()
+		// c.logRegisterValues(c.instructionPC) // used for debugging purposes
 
 		// Fetch data
 		err := c.fetchData()
@@ -102,11 +167,16 @@ func (c *CPU) Step() bool {
 
 		execFunc(c)
 
+		// A general purpose DMA started by the instruction stops the CPU until it's done
+		if stall := c.bus.TakeHdmaStall(); stall > 0 {
+			c.emulateCpuCycles(stall)
+		}
+
 	} else {
 		// CPU is halted at this point
 		c.emulateCpuCycles(1)
 
-		if c.bus.BusRead(interruptFlagIOAddr) != 0x0 {
+		if c.bus.Peek(interruptFlagIOAddr) != 0x0 {
 			c.Halted = false
 		}
 	}
@@ -130,6 +200,27 @@ func (c *CPU) emulateCpuCycles(numCycles int) {
 			c.timerTick()
 		}
 
+		c.tickComponents()
 		c.bus.DmaTick()
+		c.bus.SerialTick()
+	}
+}
+
+// tickComponents ticks all registered components for one machine cycle. In double speed the CPU runs twice
+// as fast as the rest of the system, so components only get half of the dots.
+func (c *CPU) tickComponents() {
+	if len(c.tickers) == 0 {
+		return
+	}
+
+	dots := 4
+	if c.bus.IsDoubleSpeed() {
+		dots = 2
+	}
+
+	for i := 0; i < dots; i++ {
+		for _, ticker := range c.tickers {
+			ticker.Tick()
+		}
 	}
 }
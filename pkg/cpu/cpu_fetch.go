@@ -234,6 +234,4 @@ func (c *CPU) fetchData() error {
 	default:
 		return fmt.Errorf("addressing mode %d doesn't exist", c.CurrentInstruction.AddressingMode)
 	}
-
-	return nil // This return should not be reached ever
 }
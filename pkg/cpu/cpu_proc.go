@@ -315,10 +315,8 @@ func cbExecFunc(c *CPU) {
 	bitOperation := (cbOperation >> 6) & 0b11
 	registerValue := c.fetchRegisterPrefixCB(registerType)
 
-	c.emulateCpuCycles(1)
-
-	if registerType == rtHL {
-		c.emulateCpuCycles(2)
+	if registerType == rtHL { // Reading (HL) takes a machine cycle, writing it back takes another one
+		c.emulateCpuCycles(1)
 	}
 
 	switch bitOperation {
@@ -499,6 +497,12 @@ func rraExecFunc(c *CPU) {
 }
 
 func stopExecFunc(c *CPU) {
+	c.registers.PC++ // STOP is followed by a byte that is skipped
+
+	if c.bus.SwitchSpeed() { // CGB speed switch armed through KEY1
+		return
+	}
+
 	c.logger.Fatal("Stopping Gameboy...") // Do some research about how to implement this better.
 }
 
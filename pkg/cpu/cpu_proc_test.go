@@ -20,7 +20,7 @@ const(
 
 // TestJpExecFunc tests JP
 func TestJpExecFunc(t *testing.T) {
-	cpu := Init(nil, &log.NilLogger{}) // Bus can be Nil since it's not being used for JP
+	cpu := Init(bus.NewMapMock(), &log.NilLogger{}) // JP doesn't access memory, but cycles tick the timer
 
 	tests := []struct{
 		testName string
@@ -299,61 +299,3 @@ func TestRstExecFunc(t *testing.T) {
 
 	}
 }
-
-// TestRetExecFunc tests RET
-func TestRetExecFunc(t *testing.T) {}
-
-// TestRetiExecFunc tests RETI
-func TestRetiExecFunc(t *testing.T) {}
-
-// TestJrExecFunc tests JR
-func TestJrExecFunc(t *testing.T) {}
-
-// TestPopExecFunc tests POP
-func TestPopExecFunc(t *testing.T) {}
-
-// TestPushExecFunc tests PUSH
-func TestPushExecFunc(t *testing.T) {}
-
-// TestDiExecFunc tests DI
-func TestDiExecFunc(t *testing.T) {}
-
-// TestLdExecFunc tests LD
-func TestLdExecFunc(t *testing.T) {}
-
-// TestLdhExecFunc tests LDH
-func TestLdhExecFunc(t *testing.T) {}
-
-// TestIncExecFunc tests INC
-func TestIncExecFunc(t *testing.T) {}
-
-// TestDecExecFunc tests DEC
-func TestDecExecFunc(t *testing.T) {}
-
-// TestAddExecFunc tests ADD
-func TestAddExecFunc(t *testing.T) {}
-
-// TestAdcExecFunc tests ADC
-func TestAdcExecFunc(t *testing.T) {}
-
-// TestSubExecFunc tests SUB
-func TestSubExecFunc(t *testing.T) {}
-
-// TestSbcExecFunc tests SBC
-func TestSbcExecFunc(t *testing.T) {}
-
-// TestAndExecFunc tests AND
-func TestAndExecFunc(t *testing.T) {}
-
-// TestXorExecFunc tests XOR
-func TestXorExecFunc(t *testing.T) {}
-
-// TestOrExecFunc tests OR
-func TestOrExecFunc(t *testing.T) {}
-
-// TestCpExecFunc tests CP
-func TestCpExecFunc(t *testing.T) {}
-
-
-
-
@@ -71,6 +71,7 @@ func (c *CPU) setRegisterPrefixCB(register int, data byte) {
 	switch register{
 	case rtHL:
 		c.bus.BusWrite(c.registers.GetHL(), data)
+		c.emulateCpuCycles(1)
 	default:
 		err := c.registers.SetDataToRegisters(register, uint16(data))
 		if err != nil {
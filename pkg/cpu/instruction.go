@@ -19,6 +19,13 @@ type Instruction struct {
 	execFunc func(c *CPU)
 }
 
+// LookupInstruction returns the instruction for an opcode. CB prefixed instructions are not in the table, they
+// are decoded by the PREFIX CB instruction itself.
+func LookupInstruction(opcode byte) (Instruction, bool) {
+	instruction, ok := instructionsMap[opcode]
+	return instruction, ok
+}
+
 var instructionsMap = map[byte]Instruction{
 	// 0x0
 	0x00: {Type: inNop, Mnemonic: "NOP", execFunc: nopExecFunc},                                                                        // NOP
@@ -35,7 +42,7 @@ var instructionsMap = map[byte]Instruction{
 	0x0B: {Type: inDec, AddressingMode: amR, RegisterType1: rtBC, Mnemonic: "DEC BC", execFunc: decExecFunc},                           // DEC BC
 	0x0C: {Type: inInc, AddressingMode: amR, RegisterType1: rtC, Mnemonic: "INC C", execFunc: incExecFunc},                             // INC C
 	0x0D: {Type: inDec, AddressingMode: amR, RegisterType1: rtC, Mnemonic: "DEC C", execFunc: decExecFunc},                             // DEC C
-	0x0E: {Type: inLd, AddressingMode: amRnD8, RegisterType1: rtC, Mnemonic: "LD C, d8", execFunc: ldExecFunc},                         // LD C, d8
+	0x0E: {Type: inLd, AddressingMode: amRnD8, RegisterType1: rtC, Mnemonic: "LD C,d8", execFunc: ldExecFunc},                          // LD C,d8
 	0x0F: {Type: inRrca, AddressingMode: amImp, Mnemonic: "RRCA", execFunc: rrcaExecFunc},                                              // RRCA
 	// 0x1
 	0x10: {Type: inStop, Mnemonic: "STOP 0", execFunc: stopExecFunc},                                                                   // STOP 0
@@ -5,7 +5,7 @@ const (
 	lcdStatInterruptFlag byte = 0x2
 	timerInterruptFlag   byte = 0x4
 	serialInterruptFlag  byte = 0x8
-	joypadInterruptFlag  byte = 0x16
+	joypadInterruptFlag  byte = 0x10
 )
 
 const (
@@ -22,14 +22,14 @@ func (c *CPU) pushPCToStack(address uint16) {
 }
 
 func (c *CPU) interruptCheck(addressToJump uint16, interruptType byte) bool {
-	ieRegister := c.bus.BusRead(interruptEnableAddr)
-	ifRegister := c.bus.BusRead(interruptFlagIOAddr)
+	ieRegister := c.bus.Peek(interruptEnableAddr)
+	ifRegister := c.bus.Peek(interruptFlagIOAddr)
 
 	if ifRegister&interruptType == interruptType &&
 		ieRegister&interruptType == interruptType {
 
 		c.pushPCToStack(addressToJump)
-		c.bus.BusWrite(interruptFlagIOAddr, ifRegister & ^interruptType)
+		c.bus.Poke(interruptFlagIOAddr, ifRegister & ^interruptType)
 		c.Halted = false
 
 		return true
@@ -48,6 +48,6 @@ func (c *CPU) handleInterruptions() {
 }
 
 func (c *CPU) requestInterrupt(interruptType byte) {
-	interrupts := c.bus.BusRead(interruptFlagIOAddr)
-	c.bus.BusWrite(interruptFlagIOAddr, interrupts|interruptType)
+	interrupts := c.bus.Peek(interruptFlagIOAddr)
+	c.bus.Poke(interruptFlagIOAddr, interrupts|interruptType)
 }
@@ -0,0 +1,302 @@
+package cpu
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/log"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// singleStepDir is where the SingleStepTests sm83 JSON files are looked for, one file per opcode like 00.json
+// or cb 00.json. The repository only has a few cases of some opcodes. To run all of them, download the whole
+// set to a directory and run the test against it with:
+//
+//	GOBOY_SM83_TESTS=~/sm83 go test ./pkg/cpu -run TestSingleStep -sm83.fetch
+const singleStepDir = "testdata/sm83/v1"
+
+// singleStepURL is where -sm83.fetch downloads the files from.
+const singleStepURL = "https://raw.githubusercontent.com/SingleStepTests/sm83/main/v1/"
+
+var fetchSingleStep = flag.Bool("sm83.fetch", false,
+	"download the SingleStepTests sm83 files missing from GOBOY_SM83_TESTS before running them")
+
+// singleStepMaxFailures is the number of failing cases reported per opcode.
+const singleStepMaxFailures = 5
+
+type singleStepState struct {
+	PC  uint16      `json:"pc"`
+	SP  uint16      `json:"sp"`
+	A   byte        `json:"a"`
+	B   byte        `json:"b"`
+	C   byte        `json:"c"`
+	D   byte        `json:"d"`
+	E   byte        `json:"e"`
+	F   byte        `json:"f"`
+	H   byte        `json:"h"`
+	L   byte        `json:"l"`
+	IME byte        `json:"ime"`
+	IE  byte        `json:"ie"`
+	RAM [][2]uint16 `json:"ram"` // Address and value pairs
+}
+
+type singleStepCase struct {
+	Name    string          `json:"name"`
+	Initial singleStepState `json:"initial"`
+	Final   singleStepState `json:"final"`
+	// Cycles has an entry per machine cycle: address, value and a kind like "r-m" or "-wm". Cycles without
+	// bus access are null or have a null address.
+	//
+	// The SM83 fetches the next opcode in the last cycle of every instruction. The cases are laid out that way:
+	// the opcode was fetched by the previous instruction, at PC-1, and the last cycle fetches the next one,
+	// leaving PC past it.
+	Cycles [][]any `json:"cycles"`
+}
+
+// TestSingleStep runs the SingleStepTests sm83 vectors. Each case executes a single instruction and checks
+// the final registers, the memory and the bus access done in every machine cycle.
+func TestSingleStep(t *testing.T) {
+	dir := singleStepDir
+	if env := os.Getenv("GOBOY_SM83_TESTS"); len(env) > 0 {
+		dir = env
+	}
+
+	if *fetchSingleStep {
+		if dir == singleStepDir {
+			t.Fatal("set GOBOY_SM83_TESTS to the directory where the SingleStepTests are downloaded")
+		}
+		if err := downloadSingleStep(t, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no SingleStepTests found in %s", dir)
+	}
+
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var cases []singleStepCase
+			if err := json.Unmarshal(data, &cases); err != nil {
+				t.Fatal(err)
+			}
+
+			failures := 0
+			for _, test := range cases {
+				errs := runSingleStepCase(test)
+				if len(errs) == 0 {
+					continue
+				}
+
+				failures++
+				if failures <= singleStepMaxFailures {
+					t.Errorf("[%s] %s", test.Name, strings.Join(errs, "; "))
+				}
+			}
+
+			if failures > singleStepMaxFailures {
+				t.Errorf("%d of %d cases failed", failures, len(cases))
+			}
+		})
+	}
+}
+
+// singleStepFiles returns the names of the files of every opcode the CPU implements, CB prefixed ones included.
+func singleStepFiles() []string {
+	var names []string
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		if _, ok := LookupInstruction(byte(opcode)); ok && opcode != 0xCB {
+			names = append(names, fmt.Sprintf("%02x.json", opcode))
+		}
+	}
+	for opcode := 0; opcode <= 0xFF; opcode++ {
+		names = append(names, fmt.Sprintf("cb %02x.json", opcode))
+	}
+	return names
+}
+
+// downloadSingleStep downloads the files missing from dir. Opcodes without a file upstream are only logged.
+func downloadSingleStep(t *testing.T, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	for _, name := range singleStepFiles() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+
+		response, err := client.Get(singleStepURL + url.PathEscape(name))
+		if err != nil {
+			return err
+		}
+		if response.StatusCode == http.StatusNotFound {
+			response.Body.Close()
+			t.Logf("%s is not in the SingleStepTests", name)
+			continue
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return fmt.Errorf("error downloading %s: %s", name, response.Status)
+		}
+
+		data, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return fmt.Errorf("error downloading %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSingleStepCase executes a case and returns the differences found.
+func runSingleStepCase(test singleStepCase) []string {
+	mock := bus.NewRecordingMock()
+	cpu := Init(mock, &log.NilLogger{})
+
+	// The CPU fetches the opcode in the first cycle of the instruction instead of the last one of the previous
+	// instruction, so it starts at the opcode and its fetch is not part of the cycles checked
+	initial := test.Initial
+	*cpu.registers = Registers{A: initial.A, F: initial.F, B: initial.B, C: initial.C, D: initial.D,
+		E: initial.E, H: initial.H, L: initial.L, SP: initial.SP, PC: initial.PC - 1}
+	cpu.EnableMasterInterruptions = initial.IME != 0
+	mock.Memory[interruptEnableAddr] = initial.IE
+	for _, entry := range initial.RAM {
+		mock.Memory[entry[0]] = byte(entry[1])
+	}
+
+	cpu.Step()
+
+	// Fetch the next opcode like the last cycle of the instruction does
+	mock.BusRead(cpu.registers.GetPCAndIncrement())
+	mock.DmaTick()
+	cycles := mock.Cycles[1:]
+
+	var errs []string
+	final := test.Final
+	expected := Registers{A: final.A, F: final.F, B: final.B, C: final.C, D: final.D, E: final.E, H: final.H,
+		L: final.L, SP: final.SP, PC: final.PC}
+	if *cpu.registers != expected {
+		errs = append(errs, fmt.Sprintf("registers: expected %+v got %+v", expected, *cpu.registers))
+	}
+
+	if ime := cpu.EnableMasterInterruptions; ime != (final.IME != 0) {
+		errs = append(errs, fmt.Sprintf("IME: expected %d got %t", final.IME, ime))
+	}
+
+	if ie := mock.Memory[interruptEnableAddr]; ie != final.IE {
+		errs = append(errs, fmt.Sprintf("IE: expected %02X got %02X", final.IE, ie))
+	}
+
+	for _, entry := range final.RAM {
+		if got := mock.Memory[entry[0]]; got != byte(entry[1]) {
+			errs = append(errs, fmt.Sprintf("memory %04X: expected %02X got %02X", entry[0], entry[1], got))
+		}
+	}
+
+	return append(errs, compareSingleStepCycles(test.Cycles, cycles)...)
+}
+
+// compareSingleStepCycles compares the expected bus cycles with the accesses recorded in every machine cycle.
+func compareSingleStepCycles(expected [][]any, got [][]bus.Access) []string {
+	var errs []string
+	if len(expected) != len(got) {
+		errs = append(errs, fmt.Sprintf("expected %d cycles got %d", len(expected), len(got)))
+	}
+
+	for i := 0; i < len(expected) && i < len(got); i++ {
+		want, ok := parseSingleStepCycle(expected[i])
+
+		switch {
+		case !ok && len(got[i]) > 0:
+			errs = append(errs, fmt.Sprintf("cycle %d: expected no access got %s", i, describeAccesses(got[i])))
+		case ok && (len(got[i]) != 1 || got[i][0] != want):
+			errs = append(errs, fmt.Sprintf("cycle %d: expected %s got %s", i, describeAccesses([]bus.Access{want}),
+				describeAccesses(got[i])))
+		}
+	}
+
+	return errs
+}
+
+// parseSingleStepCycle returns the access of a cycle entry, or false if the cycle has no bus access.
+func parseSingleStepCycle(entry []any) (bus.Access, bool) {
+	if len(entry) < 3 || entry[0] == nil || entry[1] == nil {
+		return bus.Access{}, false
+	}
+
+	address, _ := entry[0].(float64)
+	value, _ := entry[1].(float64)
+	kind, _ := entry[2].(string)
+
+	access := bus.Access{Address: uint16(address), Value: byte(value)}
+	switch {
+	case strings.Contains(kind, "w"):
+		access.Type = bus.AccessWrite
+	case strings.Contains(kind, "r"):
+		access.Type = bus.AccessRead
+	default:
+		return bus.Access{}, false
+	}
+	return access, true
+}
+
+func describeAccesses(accesses []bus.Access) string {
+	if len(accesses) == 0 {
+		return "no access"
+	}
+
+	descriptions := make([]string, len(accesses))
+	for i, access := range accesses {
+		descriptions[i] = fmt.Sprintf("%s %04X=%02X", access.Type, access.Address, access.Value)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func TestSingleStepHarness(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join(singleStepDir, "77.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases []singleStepCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatal(err)
+	}
+	test := cases[0]
+	if errs := runSingleStepCase(test); len(errs) > 0 {
+		t.Fatalf("[%s] %s", test.Name, strings.Join(errs, "; "))
+	}
+
+	// Wrong expectations must be reported
+	test.Cycles[0] = []any{test.Cycles[0][0], float64(byte(test.Final.A + 1)), "-wm"}
+	test.Final.IE++
+	if errs := runSingleStepCase(test); len(errs) != 2 {
+		t.Errorf("expected a cycle and an IE mismatch, got %q", errs)
+	}
+}
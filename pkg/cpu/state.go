@@ -0,0 +1,30 @@
+package cpu
+
+// State is a snapshot of the CPU, used for save states.
+type State struct {
+	Registers                 Registers
+	EnableMasterInterruptions bool
+	EnablingIme               bool
+	Halted                    bool
+	Ticks                     uint64
+}
+
+// State returns a snapshot of the CPU.
+func (c *CPU) State() State {
+	return State{
+		Registers:                 *c.registers,
+		EnableMasterInterruptions: c.EnableMasterInterruptions,
+		EnablingIme:               c.EnablingIme,
+		Halted:                    c.Halted,
+		Ticks:                     c.ticks,
+	}
+}
+
+// LoadState restores a snapshot taken with State.
+func (c *CPU) LoadState(state State) {
+	*c.registers = state.Registers
+	c.EnableMasterInterruptions = state.EnableMasterInterruptions
+	c.EnablingIme = state.EnablingIme
+	c.Halted = state.Halted
+	c.ticks = state.Ticks
+}
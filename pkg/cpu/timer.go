@@ -39,13 +39,13 @@ func (c *CPU) timerTick() {
 	}
 }
 
-func (c *CPU) getTima() byte      { return c.bus.BusRead(timaRegisterAddr) }
-func (c *CPU) setTima(value byte) { c.bus.BusWrite(timaRegisterAddr, value) }
+func (c *CPU) getTima() byte      { return c.bus.Peek(timaRegisterAddr) }
+func (c *CPU) setTima(value byte) { c.bus.Poke(timaRegisterAddr, value) }
 func (c *CPU) incrementTima() byte {
 	tima := c.getTima()
-	c.bus.BusWrite(timaRegisterAddr, tima+1)
+	c.bus.Poke(timaRegisterAddr, tima+1)
 	return tima + 1
 }
 
-func (c *CPU) getTma() byte { return c.bus.BusRead(tmaRegisterAddr) }
-func (c *CPU) getTac() byte { return c.bus.BusRead(tacRegisterAddr) }
+func (c *CPU) getTma() byte { return c.bus.Peek(tmaRegisterAddr) }
+func (c *CPU) getTac() byte { return c.bus.Peek(tacRegisterAddr) }
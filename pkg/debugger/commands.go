@@ -0,0 +1,301 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/disasm"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultExamineBytes = 64
+	defaultListLines    = 10
+)
+
+type command struct {
+	aliases []string
+	usage   string
+	help    string
+	run     func(d *Debugger, args []string) error
+}
+
+// commands is filled in init since the help command needs to read it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{aliases: []string{"break", "b"}, usage: "break [[bank:]addr|label]", help: "Add a breakpoint, or list them without arguments", run: breakCommand},
+		{aliases: []string{"delete", "d"}, usage: "delete [n]", help: "Delete breakpoint n, or all of them without arguments", run: deleteCommand},
+		{aliases: []string{"watch", "wa"}, usage: "watch [r|w|rw|x addr[-end] [op value]]", help: "Add a watchpoint, optionally when the value compares true with op (==, !=, <, <=, >, >=). Lists them without arguments", run: watchCommand},
+		{aliases: []string{"unwatch", "u"}, usage: "unwatch [n]", help: "Delete watchpoint n, or all of them without arguments", run: unwatchCommand},
+		{aliases: []string{"step", "s"}, usage: "step [n]", help: "Execute n instructions, 1 by default", run: stepCommand},
+		{aliases: []string{"next", "n"}, usage: "next", help: "Execute one instruction, stepping over CALL and RST", run: nextCommand},
+		{aliases: []string{"finish", "f"}, usage: "finish", help: "Run until the current routine returns", run: finishCommand},
+		{aliases: []string{"continue", "c"}, usage: "continue", help: "Run until a breakpoint is hit or Ctrl-C is pressed", run: continueCommand},
+		{aliases: []string{"regs", "r"}, usage: "regs", help: "Print registers and flags", run: regsCommand},
+		{aliases: []string{"examine", "x"}, usage: "examine addr [n]", help: "Dump n bytes of memory starting at addr", run: examineCommand},
+		{aliases: []string{"write", "w"}, usage: "write addr value...", help: "Write bytes to memory starting at addr", run: writeCommand},
+		{aliases: []string{"list", "l"}, usage: "list [addr] [n]", help: "Disassemble n instructions from addr, PC by default", run: listCommand},
+		{aliases: []string{"help", "h"}, usage: "help", help: "Show this help", run: helpCommand},
+		{aliases: []string{"quit", "q"}, usage: "quit", help: "Exit the emulator", run: quitCommand},
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		for _, alias := range c.aliases {
+			if alias == name {
+				return c, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// parseHex parses addresses and values, which are hexadecimal with an optional $ or 0x prefix.
+func parseHex(s string, bitSize int) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	value, err := strconv.ParseUint(s, 16, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hexadecimal value %q", s)
+	}
+	return value, nil
+}
+
+func parseAddress(s string) (uint16, error) {
+	value, err := parseHex(s, 16)
+	return uint16(value), err
+}
+
+// parseCount parses a decimal count, returning defaultValue if there's no argument at index.
+func parseCount(args []string, index int, defaultValue int) (int, error) {
+	if len(args) <= index {
+		return defaultValue, nil
+	}
+
+	count, err := strconv.Atoi(args[index])
+	if err != nil || count < 1 {
+		return 0, fmt.Errorf("invalid count %q", args[index])
+	}
+	return count, nil
+}
+
+// parseBreakpoint parses a [bank:]address or a symbol name. Symbols in the switchable ROM area only break in
+// their own bank.
+func (d *Debugger) parseBreakpoint(s string) (breakpoint, error) {
+	if symbol, ok := d.symbols.Find(s); ok {
+		bp := breakpoint{bank: -1, address: symbol.Address, label: symbol.Name}
+		if symbol.Address >= disasm.RomxStart && symbol.Address <= disasm.RomxEnd {
+			bp.bank = symbol.Bank
+		}
+		return bp, nil
+	}
+
+	bp := breakpoint{bank: -1}
+
+	if bank, address, ok := strings.Cut(s, ":"); ok {
+		value, err := parseHex(bank, 16)
+		if err != nil {
+			return bp, err
+		}
+		bp.bank = int(value)
+		s = address
+	}
+
+	address, err := parseAddress(s)
+	bp.address = address
+	return bp, err
+}
+
+func breakCommand(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		if len(d.breakpoints) == 0 {
+			fmt.Fprintln(d.out, "No breakpoints")
+		}
+		for i, bp := range d.breakpoints {
+			fmt.Fprintf(d.out, "%d: %s\n", i, bp)
+		}
+		return nil
+	}
+
+	bp, err := d.parseBreakpoint(args[0])
+	if err != nil {
+		return err
+	}
+
+	d.breakpoints = append(d.breakpoints, bp)
+	fmt.Fprintf(d.out, "Breakpoint %d at %s\n", len(d.breakpoints)-1, bp)
+	return nil
+}
+
+func deleteCommand(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		d.breakpoints = nil
+		return nil
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 || index >= len(d.breakpoints) {
+		return fmt.Errorf("no breakpoint %s", args[0])
+	}
+
+	d.breakpoints = append(d.breakpoints[:index], d.breakpoints[index+1:]...)
+	return nil
+}
+
+func stepCommand(d *Debugger, args []string) error {
+	count, err := parseCount(args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	d.runUntil(func() bool {
+		count--
+		return count == 0
+	})
+	return nil
+}
+
+func nextCommand(d *Debugger, args []string) error {
+	registers := d.cpu.Registers()
+	instruction := d.disassemble(registers.PC)
+	if !strings.HasPrefix(instruction.Text, "CALL") && !strings.HasPrefix(instruction.Text, "RST") {
+		return stepCommand(d, nil)
+	}
+
+	// Recursive calls can come back to the same address, so the stack must be back where it was too
+	returnAddress := registers.PC + instruction.Length()
+	sp := registers.SP
+	d.runUntil(func() bool {
+		return registers.PC == returnAddress && registers.SP >= sp
+	})
+	return nil
+}
+
+func finishCommand(d *Debugger, args []string) error {
+	registers := d.cpu.Registers()
+	sp := registers.SP
+	d.runUntil(func() bool {
+		return strings.HasPrefix(d.cpu.CurrentInstruction.Mnemonic, "RET") && registers.SP > sp
+	})
+	return nil
+}
+
+func continueCommand(d *Debugger, args []string) error {
+	d.runUntil(func() bool { return false })
+	return nil
+}
+
+func regsCommand(d *Debugger, args []string) error {
+	r := d.cpu.Registers()
+	fmt.Fprintf(d.out, "A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X\n",
+		r.A, r.F, r.B, r.C, r.D, r.E, r.H, r.L, r.SP, r.PC)
+
+	flags := []byte("----")
+	for i, set := range []bool{r.GetFZ(), r.GetFN(), r.GetFH(), r.GetFC()} {
+		if set {
+			flags[i] = "ZNHC"[i]
+		}
+	}
+	fmt.Fprintf(d.out, "Flags:%s IME:%t HALT:%t\n", flags, d.cpu.EnableMasterInterruptions, d.cpu.Halted)
+	return nil
+}
+
+func examineCommand(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: examine addr [n]")
+	}
+
+	address, err := parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	count, err := parseCount(args, 1, defaultExamineBytes)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		if i%16 == 0 {
+			if i > 0 {
+				fmt.Fprintln(d.out)
+			}
+			fmt.Fprintf(d.out, "%04X:", address)
+		}
+		fmt.Fprintf(d.out, " %02X", d.bus.Peek(address))
+		address++
+	}
+	fmt.Fprintln(d.out)
+	return nil
+}
+
+func writeCommand(d *Debugger, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: write addr value...")
+	}
+
+	address, err := parseAddress(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args[1:] {
+		value, err := parseHex(arg, 8)
+		if err != nil {
+			return err
+		}
+		d.bus.Poke(address, byte(value))
+		address++
+	}
+	return nil
+}
+
+func listCommand(d *Debugger, args []string) error {
+	pc := d.cpu.Registers().PC
+	address := pc
+
+	if len(args) > 0 {
+		var err error
+		if address, err = parseAddress(args[0]); err != nil {
+			return err
+		}
+	}
+
+	count, err := parseCount(args, 1, defaultListLines)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		marker := "  "
+		if address == pc {
+			marker = "=>"
+		}
+
+		if label, ok := d.label(address); ok {
+			fmt.Fprintf(d.out, "   %s:\n", label)
+		}
+
+		instruction := d.disassemble(address)
+		fmt.Fprintf(d.out, "%s %02X:%04X  %s\n", marker, d.bus.RomBank(address), address, instruction.Text)
+		address += instruction.Length()
+	}
+	return nil
+}
+
+func helpCommand(d *Debugger, args []string) error {
+	lines := make([]string, 0, len(commands))
+	for _, c := range commands {
+		lines = append(lines, fmt.Sprintf("  %-40s %s (%s)", c.usage, c.help, strings.Join(c.aliases[1:], ", ")))
+	}
+
+	fmt.Fprintln(d.out, "Addresses and values are hexadecimal, counts are decimal. An empty line repeats the last command.")
+	fmt.Fprintln(d.out, strings.Join(lines, "\n"))
+	return nil
+}
+
+func quitCommand(d *Debugger, args []string) error {
+	return errQuit
+}
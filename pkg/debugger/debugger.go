@@ -0,0 +1,190 @@
+// Package debugger implements an interactive command line debugger. It drives the CPU itself, so it replaces
+// the main emulation loop when enabled.
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/disasm"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+const prompt = "(goboy) "
+
+// errQuit is returned by the quit command to leave the REPL.
+var errQuit = errors.New("quit")
+
+type breakpoint struct {
+	bank    int // -1 matches any bank
+	address uint16
+	label   string // Symbol the breakpoint was set on, if any
+}
+
+func (b breakpoint) String() string {
+	location := fmt.Sprintf("$%04X", b.address)
+	if b.bank >= 0 {
+		location = fmt.Sprintf("%02X:%04X", b.bank, b.address)
+	}
+
+	if len(b.label) > 0 {
+		return fmt.Sprintf("%s (%s)", location, b.label)
+	}
+	return location
+}
+
+// Debugger is an interactive debugger that reads commands from in and writes its output to out.
+type Debugger struct {
+	cpu *cpu.CPU
+	bus *bus.Bus
+	in  *bufio.Scanner
+	out io.Writer
+
+	disassembler *disasm.Disassembler
+	symbols      *symbols.Table
+
+	breakpoints []breakpoint
+	watchpoints []watchpoint
+	hookID      int    // Bus hook used by watchpoints, 0 if not registered
+	watchHit    string // Description of the watchpoint hit by the last instruction
+	lastCommand string
+	interrupted atomic.Bool
+}
+
+// New returns a debugger for the given CPU and bus.
+func New(c *cpu.CPU, b *bus.Bus, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		cpu: c,
+		bus: b,
+		in:  bufio.NewScanner(in),
+		out: out,
+
+		disassembler: disasm.New(nil),
+	}
+}
+
+// SetSymbols sets the symbols used for labels and breakpoints.
+func (d *Debugger) SetSymbols(table *symbols.Table) {
+	d.symbols = table
+	d.disassembler.Symbols = table
+}
+
+// Interrupt stops the CPU if it is running and gives control back to the REPL. It is safe to call it from
+// another goroutine, like a signal handler.
+func (d *Debugger) Interrupt() {
+	d.interrupted.Store(true)
+}
+
+// Run starts the REPL with the CPU paused. It returns when the user quits or the input is closed.
+func (d *Debugger) Run() {
+	d.cpu.Stepping = true
+	d.printLocation()
+
+	for {
+		fmt.Fprint(d.out, prompt)
+		if !d.in.Scan() {
+			return
+		}
+
+		line := strings.TrimSpace(d.in.Text())
+		if len(line) == 0 { // Repeat last command, like gdb does
+			line = d.lastCommand
+		}
+		d.lastCommand = line
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, ok := lookupCommand(fields[0])
+		if !ok {
+			fmt.Fprintf(d.out, "unknown command %q, type help to list them\n", fields[0])
+			continue
+		}
+
+		if err := command.run(d, fields[1:]); err != nil {
+			if errors.Is(err, errQuit) {
+				return
+			}
+			fmt.Fprintf(d.out, "error: %s\n", err)
+		}
+	}
+}
+
+// runUntil executes instructions until done returns true, a breakpoint or watchpoint is hit or the user
+// interrupts it. done is checked after every instruction.
+func (d *Debugger) runUntil(done func() bool) {
+	d.interrupted.Store(false)
+	d.cpu.Stepping = false
+	defer func() { d.cpu.Stepping = true }()
+
+	for {
+		d.cpu.Step()
+
+		if d.interrupted.Swap(false) {
+			fmt.Fprintln(d.out, "Interrupted")
+			break
+		}
+
+		if len(d.watchHit) > 0 {
+			fmt.Fprintln(d.out, d.watchHit)
+			d.watchHit = ""
+			break
+		}
+
+		if hit, ok := d.executeWatchpointHit(); ok {
+			fmt.Fprintln(d.out, hit)
+			break
+		}
+
+		if bp, ok := d.breakpointHit(); ok {
+			fmt.Fprintf(d.out, "Breakpoint %s hit\n", bp)
+			break
+		}
+
+		if done() {
+			break
+		}
+	}
+
+	d.printLocation()
+}
+
+func (d *Debugger) breakpointHit() (breakpoint, bool) {
+	if d.cpu.Halted || len(d.breakpoints) == 0 {
+		return breakpoint{}, false
+	}
+
+	pc := d.cpu.Registers().PC
+	for _, bp := range d.breakpoints {
+		if bp.address == pc && (bp.bank < 0 || bp.bank == d.bus.RomBank(pc)) {
+			return bp, true
+		}
+	}
+
+	return breakpoint{}, false
+}
+
+// disassemble decodes the instruction at address with the ROM bank currently mapped.
+func (d *Debugger) disassemble(address uint16) disasm.Instruction {
+	return d.disassembler.Decode(d.bus.Peek, address, d.bus.RomBank(disasm.RomxStart))
+}
+
+// label returns the symbol at address with the ROM bank currently mapped.
+func (d *Debugger) label(address uint16) (string, bool) {
+	return d.symbols.Lookup(d.bus.RomBank(address), address)
+}
+
+func (d *Debugger) printLocation() {
+	pc := d.cpu.Registers().PC
+	if label, ok := d.label(pc); ok {
+		fmt.Fprintf(d.out, "%s:\n", label)
+	}
+	fmt.Fprintf(d.out, "%02X:%04X  %s\n", d.bus.RomBank(pc), pc, d.disassemble(pc).Text)
+}
@@ -0,0 +1,164 @@
+package debugger
+
+import (
+	"bytes"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"github.com/mikeletux/goboy/pkg/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+// callProgram calls a routine that increments A and then loops forever
+var callProgram = map[uint16][]byte{
+	0x100: {0x00},             // NOP
+	0x101: {0xCD, 0x00, 0x02}, // CALL $0200
+	0x104: {0x00},             // NOP
+	0x105: {0x18, 0xFE},       // JR $0105
+	0x200: {0x3C},             // INC A
+	0x201: {0x3C},             // INC A
+	0x202: {0xC9},             // RET
+}
+
+// watchProgram writes 3 and then 7 to $C0A0 and then loops forever
+var watchProgram = map[uint16][]byte{
+	0x100: {0x3E, 0x03},       // LD A,$03
+	0x102: {0xEA, 0xA0, 0xC0}, // LD ($C0A0),A
+	0x105: {0x3E, 0x07},       // LD A,$07
+	0x107: {0xEA, 0xA0, 0xC0}, // LD ($C0A0),A
+	0x10A: {0x18, 0xFE},       // JR $010A
+}
+
+func newTestDebugger(program map[uint16][]byte, commands string) (*Debugger, *bytes.Buffer) {
+	memoryBus := bus.NewBus(test.NewRomMock(program), &log.NilLogger{})
+	gbCpu := cpu.Init(memoryBus, &log.NilLogger{})
+
+	var out bytes.Buffer
+	return New(gbCpu, memoryBus, strings.NewReader(commands), &out), &out
+}
+
+func TestBreakpointAndRegisters(t *testing.T) {
+	d, out := newTestDebugger(callProgram, "break 202\ncontinue\nregs\nquit\n")
+	d.Run()
+
+	expected := []string{
+		"Breakpoint 0 at $0202",
+		"Breakpoint $0202 hit",
+		"00:0202  RET",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+
+	if got := d.cpu.Registers().A; got != 0x03 { // A starts as 0x01
+		t.Errorf("expected A to be %X got %X", 0x03, got)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	d, out := newTestDebugger(callProgram, "break AddTwo\nbreak Far\ncontinue\nquit\n")
+	table, err := symbols.Parse(strings.NewReader("00:0200 AddTwo\n02:4000 Far\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetSymbols(table)
+	d.Run()
+
+	expected := []string{
+		"Breakpoint 0 at $0200 (AddTwo)",
+		"Breakpoint 1 at 02:4000 (Far)",
+		"AddTwo:\n00:0200  INC A",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}
+
+func TestStepOverAndFinish(t *testing.T) {
+	d, _ := newTestDebugger(callProgram, "step\nnext\nquit\n")
+	d.Run()
+
+	if pc := d.cpu.Registers().PC; pc != 0x104 {
+		t.Errorf("next: expected PC %X got %X", 0x104, pc)
+	}
+
+	d, _ = newTestDebugger(callProgram, "step 3\nfinish\nquit\n")
+	d.Run()
+
+	if pc := d.cpu.Registers().PC; pc != 0x104 {
+		t.Errorf("finish: expected PC %X got %X", 0x104, pc)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	d, out := newTestDebugger(callProgram, "write C000 12 34\nexamine C000 2\nquit\n")
+	d.Run()
+
+	if !strings.Contains(out.String(), "C000: 12 34\n") {
+		t.Errorf("expected memory dump, got:\n%s", out.String())
+	}
+}
+
+func TestMemoryNotReportedToHooks(t *testing.T) {
+	d, _ := newTestDebugger(callProgram, "write C000 12\nexamine C000\nlist\nquit\n")
+	accesses := 0
+	d.bus.AddHook(func(access bus.Access) { accesses++ })
+	d.Run()
+
+	if accesses != 0 {
+		t.Errorf("expected the debugger accesses not to be reported to the bus hooks, got %d", accesses)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	d, out := newTestDebugger(callProgram, "continue\nquit\n")
+
+	// Keep interrupting since continue clears any interruption made before it starts running
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+				d.Interrupt()
+			}
+		}
+	}()
+	d.Run()
+	close(done)
+
+	if !strings.Contains(out.String(), "Interrupted") {
+		t.Errorf("expected continue to be interrupted, got:\n%s", out.String())
+	}
+}
+
+func TestWatchpoints(t *testing.T) {
+	d, out := newTestDebugger(watchProgram, "watch w C0A0 > 5\ncontinue\nunwatch\nwatch x 10A\ncontinue\nquit\n")
+	d.Run()
+
+	expected := []string{
+		"Watchpoint 0: w $C0A0 if value > $05",
+		"Watchpoint 0 hit: write $07 at $C0A0 from PC $0107",
+		"Watchpoint 0 hit: execute at $010A",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+
+	if d.hookID != 0 {
+		t.Error("expected bus hook to be removed along with the read and write watchpoints")
+	}
+}
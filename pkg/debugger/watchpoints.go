@@ -0,0 +1,199 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"strconv"
+	"strings"
+)
+
+// Watchpoint kinds, used as a bitmask
+const (
+	watchRead = 1 << iota
+	watchWrite
+	watchExecute
+)
+
+var watchKinds = map[string]int{
+	"r":  watchRead,
+	"w":  watchWrite,
+	"rw": watchRead | watchWrite,
+	"x":  watchExecute,
+}
+
+// conditions compare the value read, written or executed against the watchpoint value.
+var conditions = map[string]func(a, b byte) bool{
+	"==": func(a, b byte) bool { return a == b },
+	"!=": func(a, b byte) bool { return a != b },
+	"<":  func(a, b byte) bool { return a < b },
+	"<=": func(a, b byte) bool { return a <= b },
+	">":  func(a, b byte) bool { return a > b },
+	">=": func(a, b byte) bool { return a >= b },
+}
+
+type watchpoint struct {
+	kind      int
+	kindName  string
+	start     uint16
+	end       uint16
+	condition string // Empty means any value
+	value     byte
+}
+
+func (w watchpoint) String() string {
+	s := fmt.Sprintf("%s $%04X", w.kindName, w.start)
+	if w.end != w.start {
+		s += fmt.Sprintf("-$%04X", w.end)
+	}
+	if len(w.condition) > 0 {
+		s += fmt.Sprintf(" if value %s $%02X", w.condition, w.value)
+	}
+	return s
+}
+
+func (w watchpoint) matches(kind int, address uint16, value byte) bool {
+	if w.kind&kind == 0 || address < w.start || address > w.end {
+		return false
+	}
+	return len(w.condition) == 0 || conditions[w.condition](value, w.value)
+}
+
+// parseWatchpoint parses the arguments of the watch command: kind addr[-end] [condition value]
+func parseWatchpoint(args []string) (watchpoint, error) {
+	w := watchpoint{}
+	if len(args) != 2 && len(args) != 4 {
+		return w, errors.New("usage: watch r|w|rw|x addr[-end] [==|!=|<|<=|>|>= value]")
+	}
+
+	kind, ok := watchKinds[args[0]]
+	if !ok {
+		return w, fmt.Errorf("unknown watchpoint kind %q", args[0])
+	}
+	w.kind = kind
+	w.kindName = args[0]
+
+	start, end, isRange := strings.Cut(args[1], "-")
+	var err error
+	if w.start, err = parseAddress(start); err != nil {
+		return w, err
+	}
+	w.end = w.start
+	if isRange {
+		if w.end, err = parseAddress(end); err != nil {
+			return w, err
+		}
+		if w.end < w.start {
+			return w, fmt.Errorf("invalid range %s", args[1])
+		}
+	}
+
+	if len(args) == 4 {
+		if _, ok := conditions[args[2]]; !ok {
+			return w, fmt.Errorf("unknown condition %q", args[2])
+		}
+		w.condition = args[2]
+
+		value, err := parseHex(args[3], 8)
+		if err != nil {
+			return w, err
+		}
+		w.value = byte(value)
+	}
+
+	return w, nil
+}
+
+func watchCommand(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		if len(d.watchpoints) == 0 {
+			fmt.Fprintln(d.out, "No watchpoints")
+		}
+		for i, w := range d.watchpoints {
+			fmt.Fprintf(d.out, "%d: %s\n", i, w)
+		}
+		return nil
+	}
+
+	w, err := parseWatchpoint(args)
+	if err != nil {
+		return err
+	}
+
+	d.watchpoints = append(d.watchpoints, w)
+	d.updateHook()
+	fmt.Fprintf(d.out, "Watchpoint %d: %s\n", len(d.watchpoints)-1, w)
+	return nil
+}
+
+func unwatchCommand(d *Debugger, args []string) error {
+	if len(args) == 0 {
+		d.watchpoints = nil
+		d.updateHook()
+		return nil
+	}
+
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 || index >= len(d.watchpoints) {
+		return fmt.Errorf("no watchpoint %s", args[0])
+	}
+
+	d.watchpoints = append(d.watchpoints[:index], d.watchpoints[index+1:]...)
+	d.updateHook()
+	return nil
+}
+
+// updateHook registers the bus hook only whilst there are read or write watchpoints, so the bus runs at full
+// speed otherwise.
+func (d *Debugger) updateHook() {
+	needed := false
+	for _, w := range d.watchpoints {
+		needed = needed || w.kind&(watchRead|watchWrite) != 0
+	}
+
+	switch {
+	case needed && d.hookID == 0:
+		d.bus.SetHookContext(d.cpu)
+		d.hookID = d.bus.AddHook(d.onAccess)
+	case !needed && d.hookID != 0:
+		d.bus.RemoveHook(d.hookID)
+		d.hookID = 0
+	}
+}
+
+// onAccess is the bus hook. It only records the first watchpoint hit, the CPU is stopped once the current
+// instruction is done.
+func (d *Debugger) onAccess(access bus.Access) {
+	if len(d.watchHit) > 0 {
+		return
+	}
+
+	kind := watchRead
+	if access.Type == bus.AccessWrite {
+		kind = watchWrite
+	}
+
+	for i, w := range d.watchpoints {
+		if w.matches(kind, access.Address, access.Value) {
+			d.watchHit = fmt.Sprintf("Watchpoint %d hit: %s $%02X at $%04X from PC $%04X (cycle %d)",
+				i, access.Type, access.Value, access.Address, access.PC, access.Cycle)
+			return
+		}
+	}
+}
+
+// executeWatchpointHit checks whether the instruction about to be executed is watched.
+func (d *Debugger) executeWatchpointHit() (string, bool) {
+	if d.cpu.Halted {
+		return "", false
+	}
+
+	pc := d.cpu.Registers().PC
+	for i, w := range d.watchpoints {
+		if w.kind&watchExecute != 0 && w.matches(watchExecute, pc, d.bus.Peek(pc)) {
+			return fmt.Sprintf("Watchpoint %d hit: execute at $%04X", i, pc), true
+		}
+	}
+
+	return "", false
+}
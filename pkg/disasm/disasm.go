@@ -0,0 +1,146 @@
+// Package disasm decodes SM83 machine code into text. Operands are resolved to their values, jump targets
+// and IO registers are labelled, and RGBDS symbols are used for labels when available.
+package disasm
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"strings"
+)
+
+// cbOperations and cbRegisters are used to build the CB prefixed mnemonics, which are not in the CPU table.
+var (
+	cbOperations    = [8]string{"RLC", "RRC", "RL", "RR", "SLA", "SRA", "SWAP", "SRL"}
+	cbBitOperations = [4]string{"", "BIT", "RES", "SET"}
+	cbRegisters     = [8]string{"B", "C", "D", "E", "H", "L", "(HL)", "A"}
+)
+
+// Reader reads the memory to disassemble, like bus.BusRead.
+type Reader func(address uint16) byte
+
+// Instruction is a decoded instruction.
+type Instruction struct {
+	Address uint16
+	Bank    int
+	Bytes   []byte
+	Text    string
+}
+
+// Length returns the instruction size in bytes.
+func (i Instruction) Length() uint16 {
+	return uint16(len(i.Bytes))
+}
+
+// Disassembler decodes instructions. The zero value decodes without symbols.
+type Disassembler struct {
+	Symbols *symbols.Table
+}
+
+// New returns a disassembler that uses the given symbols for labels. symbols can be nil.
+func New(symbolTable *symbols.Table) *Disassembler {
+	return &Disassembler{Symbols: symbolTable}
+}
+
+// Decode decodes the instruction at address. bank is the ROM bank mapped at 0x4000-0x7FFF, used to look
+// symbols up.
+func (d *Disassembler) Decode(read Reader, address uint16, bank int) Instruction {
+	opcode := read(address)
+	instruction := Instruction{Address: address, Bank: bank}
+
+	bytes := func(length uint16) []byte {
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = read(address + uint16(i))
+		}
+		return b
+	}
+
+	if opcode == 0xCB {
+		instruction.Bytes = bytes(2)
+		instruction.Text = decodeCB(instruction.Bytes[1])
+		return instruction
+	}
+
+	cpuInstruction, ok := cpu.LookupInstruction(opcode)
+	if !ok {
+		instruction.Bytes = bytes(1)
+		instruction.Text = fmt.Sprintf("DB $%02X", opcode)
+		return instruction
+	}
+
+	mnemonic := cpuInstruction.Mnemonic
+	switch {
+	case strings.HasPrefix(mnemonic, "STOP"): // STOP is followed by a byte that is skipped
+		instruction.Bytes = bytes(2)
+		instruction.Text = "STOP"
+
+	case strings.Contains(mnemonic, "d16"):
+		instruction.Bytes = bytes(3)
+		instruction.Text = strings.Replace(mnemonic, "d16", fmt.Sprintf("$%04X", word(instruction.Bytes)), 1)
+
+	case strings.Contains(mnemonic, "a16"):
+		instruction.Bytes = bytes(3)
+		instruction.Text = strings.Replace(mnemonic, "a16", d.label(word(instruction.Bytes), bank), 1)
+
+	case strings.Contains(mnemonic, "d8"):
+		instruction.Bytes = bytes(2)
+		instruction.Text = strings.Replace(mnemonic, "d8", fmt.Sprintf("$%02X", instruction.Bytes[1]), 1)
+
+	case strings.Contains(mnemonic, "a8"):
+		instruction.Bytes = bytes(2)
+		instruction.Text = strings.Replace(mnemonic, "a8", d.label(0xFF00|uint16(instruction.Bytes[1]), bank), 1)
+
+	case strings.HasPrefix(mnemonic, "JR"):
+		instruction.Bytes = bytes(2)
+		target := address + 2 + uint16(int8(instruction.Bytes[1]))
+		instruction.Text = strings.Replace(mnemonic, "r8", d.label(target, bank), 1)
+
+	case strings.Contains(mnemonic, "+r8"):
+		instruction.Bytes = bytes(2)
+		instruction.Text = strings.Replace(mnemonic, "+r8", fmt.Sprintf("%+d", int8(instruction.Bytes[1])), 1)
+
+	case strings.Contains(mnemonic, "r8"):
+		instruction.Bytes = bytes(2)
+		instruction.Text = strings.Replace(mnemonic, "r8", fmt.Sprintf("%d", int8(instruction.Bytes[1])), 1)
+
+	default:
+		instruction.Bytes = bytes(1)
+		instruction.Text = mnemonic
+	}
+
+	return instruction
+}
+
+// Label returns the symbol at address, if any.
+func (d *Disassembler) Label(address uint16, bank int) (string, bool) {
+	return d.Symbols.Lookup(bank, address)
+}
+
+// label returns the best name for an address: a symbol, an IO register or the address itself.
+func (d *Disassembler) label(address uint16, bank int) string {
+	if name, ok := d.Symbols.Lookup(bank, address); ok {
+		return name
+	}
+
+	if name, ok := ioRegisters[address]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("$%04X", address)
+}
+
+func word(bytes []byte) uint16 {
+	return uint16(bytes[2])<<8 | uint16(bytes[1])
+}
+
+func decodeCB(operation byte) string {
+	register := cbRegisters[operation&0b111]
+	bit := (operation >> 3) & 0b111
+
+	if bitOperation := operation >> 6; bitOperation != 0 {
+		return fmt.Sprintf("%s %d,%s", cbBitOperations[bitOperation], bit, register)
+	}
+
+	return fmt.Sprintf("%s %s", cbOperations[bit], register)
+}
@@ -0,0 +1,73 @@
+package disasm
+
+import (
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		memory         []byte
+		expectedText   string
+		expectedLength uint16
+	}{
+		{memory: []byte{0x00}, expectedText: "NOP", expectedLength: 1},
+		{memory: []byte{0x21, 0x34, 0x12}, expectedText: "LD HL,$1234", expectedLength: 3},
+		{memory: []byte{0xCD, 0x50, 0x01}, expectedText: "CALL $0150", expectedLength: 3},
+		{memory: []byte{0xE0, 0x80}, expectedText: "LDH ($FF80),A", expectedLength: 2},
+		{memory: []byte{0x3E, 0x7F}, expectedText: "LD A,$7F", expectedLength: 2},
+		{memory: []byte{0xE0, 0x40}, expectedText: "LDH (rLCDC),A", expectedLength: 2},
+		{memory: []byte{0x18, 0xFE}, expectedText: "JR $0100", expectedLength: 2},
+		{memory: []byte{0xF8, 0xFD}, expectedText: "LD HL,SP-3", expectedLength: 2},
+		{memory: []byte{0xCB, 0x7C}, expectedText: "BIT 7,H", expectedLength: 2},
+		{memory: []byte{0xCB, 0x37}, expectedText: "SWAP A", expectedLength: 2},
+		{memory: []byte{0xD3}, expectedText: "DB $D3", expectedLength: 1},
+		{memory: []byte{0x10, 0x00}, expectedText: "STOP", expectedLength: 2},
+		{memory: []byte{0xEA, 0x44, 0xFF}, expectedText: "LD (rLY),A", expectedLength: 3},
+	}
+
+	for _, testCase := range testCases {
+		read := func(address uint16) byte {
+			return testCase.memory[address-0x100]
+		}
+
+		instruction := New(nil).Decode(read, 0x100, 1)
+		if instruction.Text != testCase.expectedText || instruction.Length() != testCase.expectedLength {
+			t.Errorf("got %q (%d bytes) expected %q (%d bytes)", instruction.Text, instruction.Length(),
+				testCase.expectedText, testCase.expectedLength)
+		}
+	}
+}
+
+func TestDecodeWithSymbols(t *testing.T) {
+	table, err := symbols.Parse(strings.NewReader("; File generated by rgblink\n00:0150 Main\n01:4000 Bank1Routine\n02:4000 Bank2Routine\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rom := make([]byte, 3*0x4000)
+	copy(rom[0x100:], []byte{0xC3, 0x50, 0x01})   // JP $0150
+	copy(rom[0x103:], []byte{0xCD, 0x00, 0x40})   // CALL $4000
+	copy(rom[2*0x4000+0x10:], []byte{0x18, 0xEE}) // JR $4000 in bank 2
+
+	d := New(table)
+	testCases := []struct {
+		address  uint16
+		bank     int
+		expected string
+	}{
+		{address: 0x100, bank: 1, expected: "JP Main"},
+		{address: 0x103, bank: 1, expected: "CALL Bank1Routine"},
+		{address: 0x103, bank: 2, expected: "CALL Bank2Routine"},
+		{address: 0x4010, bank: 2, expected: "JR Bank2Routine"},
+	}
+
+	for _, testCase := range testCases {
+		instruction := d.Decode(RomReader(rom, testCase.bank), testCase.address, testCase.bank)
+		if instruction.Text != testCase.expected {
+			t.Errorf("%02X:%04X: got %q expected %q", testCase.bank, testCase.address, instruction.Text,
+				testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package disasm
+
+const (
+	romBankSize = 0x4000
+
+	// RomxStart and RomxEnd delimit the switchable ROM bank area.
+	RomxStart uint16 = 0x4000
+	RomxEnd   uint16 = 0x7FFF
+)
+
+// RomReader returns a Reader over a ROM image with the given bank mapped at 0x4000-0x7FFF. Addresses out of
+// the ROM read 0xFF.
+func RomReader(rom []byte, bank int) Reader {
+	return func(address uint16) byte {
+		offset := int(address)
+		if address >= RomxStart && address <= RomxEnd {
+			offset = bank*romBankSize + int(address-RomxStart)
+		} else if address > RomxEnd {
+			return 0xFF
+		}
+
+		if offset >= len(rom) {
+			return 0xFF
+		}
+		return rom[offset]
+	}
+}
+
+// RomBanks returns the number of 16 KiB banks in a ROM image.
+func RomBanks(rom []byte) int {
+	return (len(rom) + romBankSize - 1) / romBankSize
+}
@@ -0,0 +1,57 @@
+// Package frontend defines how the emulation loop talks to the outside world: a Video to show the frames, an
+// Audio to play the sound and an Input to read the buttons. Implementations live in their own packages, so
+// the core can be built and tested without them. Headless is a pure Go implementation that discards
+// everything.
+package frontend
+
+import (
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/ppu"
+)
+
+// FrameDuration is how long the Game Boy takes to draw a frame, a bit less than 1/60 seconds.
+const FrameDuration = ppu.FrameDuration
+
+// Video shows the frames.
+type Video interface {
+	// Draw shows a frame of width x height pixels, stored row by row as 0xAARRGGBB. The size doesn't change
+	// whilst a game is running.
+	Draw(frame []uint32, width, height int)
+}
+
+// Audio plays the sound.
+type Audio interface {
+	// Play queues signed 16 bit stereo samples, interleaved left and right, at the sample rate the frontend
+	// was created with.
+	Play(samples []int16)
+}
+
+// Input reads the buttons.
+type Input interface {
+	// Poll returns the buttons pressed and whether the user asked to quit. It is called once before each
+	// frame is run.
+	Poll() (buttons goboy.Buttons, quit bool)
+	// Hotkeys returns the emulator controls used since the last call. It is called once per frame shown,
+	// also whilst the game is paused.
+	Hotkeys() Hotkeys
+}
+
+// Hotkeys are the emulator controls, besides the joypad.
+type Hotkeys struct {
+	Pause        bool // Pause or resume
+	FrameAdvance bool // Run a single frame, pausing first if needed
+	SpeedUp      bool // Switch to the next speed multiplier
+	SpeedDown    bool // Switch to the previous speed multiplier
+	FastForward  bool // Held to run as fast as possible
+	Screenshot   bool // Save the screen as an image
+	Record       bool // Start or stop recording a video
+	Quit         bool
+}
+
+// Frontend is a complete frontend.
+type Frontend interface {
+	Video
+	Audio
+	Input
+	Close() error
+}
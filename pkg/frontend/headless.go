@@ -0,0 +1,19 @@
+package frontend
+
+import "github.com/mikeletux/goboy"
+
+// Headless is a frontend without screen, sound nor input. The buttons pressed can be set through Buttons.
+type Headless struct {
+	Buttons goboy.Buttons
+}
+
+// NewHeadless returns a Headless frontend.
+func NewHeadless() *Headless {
+	return &Headless{}
+}
+
+func (h *Headless) Draw(frame []uint32, width, height int) {}
+func (h *Headless) Play(samples []int16)                   {}
+func (h *Headless) Poll() (goboy.Buttons, bool)            { return h.Buttons, false }
+func (h *Headless) Hotkeys() Hotkeys                       { return Hotkeys{} }
+func (h *Headless) Close() error                           { return nil }
@@ -0,0 +1,73 @@
+package frontend
+
+import (
+	"github.com/mikeletux/goboy"
+	"time"
+)
+
+// Options configure Run.
+type Options struct {
+	// Speed is the speed multiplier, between MinSpeed and MaxSpeed. 0 means 1.
+	Speed float64
+	// Paused starts the game paused, to be advanced frame by frame.
+	Paused bool
+}
+
+// Run runs the machine, showing every frame and playing its sound through the frontend, until the user quits or
+// the emulator fails. The speed can be changed and the game paused with the hotkeys. The sound is muted when not
+// running at normal speed.
+func Run(machine *goboy.Machine, f Frontend, options Options) error {
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+
+	s := newSpeed(options)
+	for range ticker.C {
+		hotkeys := f.Hotkeys()
+		if hotkeys.Quit {
+			return nil
+		}
+		s.update(hotkeys)
+
+		frames := s.frames()
+		deadline := time.Now().Add(fastForwardBudget)
+		for i := 0; i < frames || (s.fastForward && time.Now().Before(deadline)); i++ {
+			buttons, quit := f.Poll()
+			if quit {
+				return nil
+			}
+			machine.SetButtons(buttons)
+			if err := machine.RunFrame(); err != nil {
+				return err
+			}
+		}
+
+		frame, width, height := machine.Screen()
+		s.drawIndicator(frame, width, height)
+		f.Draw(frame, width, height)
+
+		samples := machine.AudioSamples()
+		if s.normal() {
+			f.Play(samples)
+		}
+	}
+	return nil
+}
+
+// Present shows the machine through the frontend without running it, until the user quits. It is used when
+// something else drives the CPU, like a debugger.
+func Present(machine *goboy.Machine, f Frontend) error {
+	ticker := time.NewTicker(FrameDuration)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		buttons, quit := f.Poll()
+		if quit || f.Hotkeys().Quit {
+			return nil
+		}
+		machine.QueueButtons(buttons)
+
+		f.Draw(machine.Screen())
+		f.Play(machine.AudioSamples())
+	}
+	return nil
+}
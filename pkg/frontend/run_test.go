@@ -0,0 +1,129 @@
+package frontend
+
+import (
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/test"
+	"testing"
+)
+
+// recordingFrontend quits after a number of frames and records what it is given.
+type recordingFrontend struct {
+	Headless
+	frames  int
+	width   int
+	height  int
+	samples int
+}
+
+func (r *recordingFrontend) Draw(frame []uint32, width, height int) {
+	r.frames++
+	r.width, r.height = width, height
+}
+
+func (r *recordingFrontend) Play(samples []int16) {
+	r.samples += len(samples)
+}
+
+func (r *recordingFrontend) Poll() (goboy.Buttons, bool) {
+	return 0, r.frames == 3
+}
+
+// newTestMachine returns a machine running JR -2 forever.
+func newTestMachine(t *testing.T) *goboy.Machine {
+	machine, err := goboy.New(test.Rom("LOOP", map[uint16][]byte{0x100: {0x18, 0xFE}}), goboy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return machine
+}
+
+func TestRun(t *testing.T) {
+	machine := newTestMachine(t)
+	f := &recordingFrontend{}
+
+	if err := Run(machine, f, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if machine.FrameCount() != 3 {
+		t.Errorf("expected 3 frames to be run got %d", machine.FrameCount())
+	}
+	if f.width != goboy.ScreenWidth || f.height != goboy.ScreenHeight {
+		t.Errorf("expected %dx%d frames got %dx%d", goboy.ScreenWidth, goboy.ScreenHeight, f.width, f.height)
+	}
+	if f.samples == 0 {
+		t.Error("expected audio samples to be played")
+	}
+}
+
+func TestPresent(t *testing.T) {
+	machine := newTestMachine(t)
+	f := &recordingFrontend{}
+
+	if err := Present(machine, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.frames != 3 || machine.Cycles() != 0 {
+		t.Errorf("expected 3 frames drawn without running, got %d frames and %d cycles", f.frames, machine.Cycles())
+	}
+}
+
+func TestRunSpeed(t *testing.T) {
+	machine := newTestMachine(t)
+	f := &recordingFrontend{}
+
+	if err := Run(machine, f, Options{Speed: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if machine.FrameCount() != 6 {
+		t.Errorf("expected 2 frames run per frame shown, got %d frames", machine.FrameCount())
+	}
+	if f.samples != 0 {
+		t.Error("expected the sound to be muted at double speed")
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	s := newSpeed(Options{Speed: 0.25})
+	frames := 0
+	for i := 0; i < 8; i++ {
+		frames += s.frames()
+	}
+	if frames != 2 {
+		t.Errorf("expected 2 frames in 8 at 0.25x got %d", frames)
+	}
+
+	s.update(Hotkeys{SpeedUp: true})
+	s.update(Hotkeys{SpeedUp: true})
+	if s.multiplier != 1 || s.indicator() != "" {
+		t.Errorf("expected 1x after speeding up twice got %g", s.multiplier)
+	}
+	s.update(Hotkeys{SpeedDown: true})
+	if s.indicator() != "0.5X" {
+		t.Errorf("expected the indicator to show 0.5X got %q", s.indicator())
+	}
+
+	s = newSpeed(Options{Speed: 100})
+	if s.multiplier != MaxSpeed {
+		t.Errorf("expected the speed to be limited to %g got %g", float64(MaxSpeed), s.multiplier)
+	}
+
+	s.update(Hotkeys{FrameAdvance: true})
+	if s.frames() != 0 || s.indicator() != "PAUSED" {
+		t.Error("expected frame advance to pause a running game")
+	}
+	s.update(Hotkeys{FrameAdvance: true})
+	if s.frames() != 1 {
+		t.Error("expected frame advance to run a frame")
+	}
+	s.update(Hotkeys{FastForward: true})
+	if s.frames() != 0 || s.fastForward {
+		t.Error("expected the game to stay paused whilst fast-forwarding")
+	}
+	s.update(Hotkeys{Pause: true, FastForward: true})
+	if !s.fastForward || s.normal() {
+		t.Error("expected fast-forward once resumed")
+	}
+}
@@ -0,0 +1,129 @@
+package frontend
+
+import (
+	"github.com/mikeletux/goboy/pkg/overlay"
+	"strconv"
+)
+
+// MinSpeed and MaxSpeed are the limits of the speed multiplier.
+const (
+	MinSpeed = 0.25
+	MaxSpeed = 8.0
+)
+
+// speeds are the multipliers the speed hotkeys switch between.
+var speeds = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// fastForwardBudget is how long frames are run for in each frame shown whilst fast-forwarding. The rest is left
+// to draw.
+const fastForwardBudget = FrameDuration * 3 / 4
+
+const (
+	indicatorColor      uint32 = 0xFFFFFFFF
+	indicatorBackground uint32 = 0xC0000000
+)
+
+// speed decides how many frames are run for each frame shown.
+type speed struct {
+	multiplier  float64
+	paused      bool
+	fastForward bool
+	advance     bool    // Run a frame whilst paused
+	credit      float64 // Frames owed to reach the multiplier
+}
+
+func newSpeed(options Options) *speed {
+	multiplier := options.Speed
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	return &speed{multiplier: clampSpeed(multiplier), paused: options.Paused}
+}
+
+func clampSpeed(multiplier float64) float64 {
+	if multiplier < MinSpeed {
+		return MinSpeed
+	}
+	if multiplier > MaxSpeed {
+		return MaxSpeed
+	}
+	return multiplier
+}
+
+func (s *speed) update(hotkeys Hotkeys) {
+	if hotkeys.Pause {
+		s.paused = !s.paused
+	}
+
+	// Frame advance pauses a running game, then runs a frame each time
+	if hotkeys.FrameAdvance {
+		s.advance = s.paused
+		s.paused = true
+	}
+
+	if hotkeys.SpeedUp {
+		for _, multiplier := range speeds {
+			if multiplier > s.multiplier {
+				s.multiplier = multiplier
+				break
+			}
+		}
+	}
+	if hotkeys.SpeedDown {
+		for i := len(speeds) - 1; i >= 0; i-- {
+			if speeds[i] < s.multiplier {
+				s.multiplier = speeds[i]
+				break
+			}
+		}
+	}
+
+	s.fastForward = hotkeys.FastForward && !s.paused
+}
+
+// frames returns how many frames to run before the next one is shown. Fast-forward runs more for as long as
+// there's time.
+func (s *speed) frames() int {
+	if s.paused {
+		advance := s.advance
+		s.advance = false
+		if advance {
+			return 1
+		}
+		return 0
+	}
+
+	s.credit += s.multiplier
+	frames := int(s.credit)
+	s.credit -= float64(frames)
+	return frames
+}
+
+// normal returns whether the game runs at the Game Boy speed.
+func (s *speed) normal() bool {
+	return !s.paused && !s.fastForward && s.multiplier == 1
+}
+
+// indicator returns the text shown in the corner of the screen, empty at normal speed.
+func (s *speed) indicator() string {
+	switch {
+	case s.paused:
+		return "PAUSED"
+	case s.fastForward:
+		return ">>"
+	case s.multiplier != 1:
+		return strconv.FormatFloat(s.multiplier, 'f', -1, 64) + "X"
+	}
+	return ""
+}
+
+// drawIndicator draws the indicator in the top right corner of the frame.
+func (s *speed) drawIndicator(frame []uint32, width, height int) {
+	text := s.indicator()
+	if len(text) == 0 {
+		return
+	}
+
+	c := &overlay.Canvas{Pixels: frame, Width: width, Height: height}
+	c.Text(width-overlay.TextWidth(text)-2, 2, text, indicatorColor, indicatorBackground)
+}
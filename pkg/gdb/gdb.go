@@ -0,0 +1,238 @@
+// Package gdb exposes the CPU through the GDB Remote Serial Protocol, so any RSP client can debug the games
+// running in GoBoy.
+//
+// There is no SM83 target in GDB, so registers are sent in the order AF, BC, DE, HL, SP and PC, each of them
+// as a 16 bit little endian value.
+package gdb
+
+import (
+	"errors"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	registerCount = 6
+
+	// Stop replies
+	stopTrap      = "S05"
+	stopInterrupt = "S02"
+
+	// stepsBetweenChecks is the number of instructions run between checks for interruptions.
+	stepsBetweenChecks = 1000
+)
+
+var (
+	// errDetach is returned by the packet handlers when the client leaves.
+	errDetach = errors.New("client detached")
+	// errKill is returned by the packet handlers when the client leaves without waiting for a reply.
+	errKill = errors.New("client killed the program")
+)
+
+// Server is a GDB remote stub. It drives the CPU, so it replaces the main emulation loop when enabled.
+type Server struct {
+	cpu    *cpu.CPU
+	bus    *bus.Bus
+	logger log.Logger
+
+	breakpoints map[uint16]bool
+}
+
+// New returns a GDB server for the given CPU and bus.
+func New(c *cpu.CPU, b *bus.Bus, logger log.Logger) *Server {
+	return &Server{
+		cpu:         c,
+		bus:         b,
+		logger:      logger,
+		breakpoints: make(map[uint16]bool),
+	}
+}
+
+// ListenAndServe listens on the given TCP address and serves clients, see Serve.
+func (s *Server) ListenAndServe(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return s.Serve(listener)
+}
+
+// Serve runs the CPU at normal speed until a client connects. The CPU is stopped whilst a client is attached and
+// resumed once it detaches. It only returns when the listener is closed.
+func (s *Server) Serve(listener net.Listener) error {
+	conns := make(chan net.Conn)
+	acceptErr := make(chan error, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				acceptErr <- err
+				close(conns)
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	ticker := time.NewTicker(ppu.FrameDuration)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case conn, ok := <-conns:
+			if !ok {
+				return <-acceptErr
+			}
+			s.logger.Debugf("gdb client connected from %s", conn.RemoteAddr())
+			sess := newSession(conn)
+			s.serve(sess)
+			sess.close()
+			s.logger.Debugf("gdb client disconnected")
+
+		case <-ticker.C:
+			s.runFrame()
+		}
+	}
+}
+
+// runFrame runs the CPU for the time the PPU takes to draw a frame.
+func (s *Server) runFrame() {
+	cycles := ppu.FrameCycles
+	if s.bus.IsDoubleSpeed() {
+		cycles *= 2
+	}
+
+	for limit := s.cpu.Cycles() + cycles; s.cpu.Cycles() < limit; {
+		s.cpu.Step()
+	}
+}
+
+func (s *Server) serve(sess *session) {
+	s.cpu.Stepping = true
+
+	for packet := range sess.packets {
+		reply, err := s.handle(sess, packet)
+		if errors.Is(err, errKill) {
+			break
+		}
+		if errors.Is(err, errDetach) {
+			sess.reply(reply)
+			break
+		}
+
+		if sess.reply(reply) != nil {
+			break
+		}
+
+		if packet == "QStartNoAckMode" {
+			sess.noAck.Store(true)
+		}
+	}
+
+	s.cpu.Stepping = false
+}
+
+// handle executes a packet and returns the reply.
+func (s *Server) handle(sess *session, packet string) (string, error) {
+	if len(packet) == 0 {
+		return "", nil
+	}
+
+	args := packet[1:]
+	switch packet[0] {
+	case '?':
+		return stopTrap, nil
+	case 'g':
+		return s.readRegisters(), nil
+	case 'G':
+		return s.writeRegisters(args), nil
+	case 'p':
+		return s.readRegister(args), nil
+	case 'P':
+		return s.writeRegister(args), nil
+	case 'm':
+		return s.readMemory(args), nil
+	case 'M':
+		return s.writeMemory(args), nil
+	case 'Z', 'z':
+		return s.setBreakpoint(packet[0] == 'Z', args), nil
+	case 's':
+		if s.jumpTo(args) {
+			s.cpu.Step()
+		}
+		return stopTrap, nil
+	case 'c':
+		if s.jumpTo(args) {
+			return s.run(sess), nil
+		}
+		return stopTrap, nil
+	case 'H':
+		return "OK", nil // There's a single thread
+	case 'D':
+		return "OK", errDetach
+	case 'k':
+		return "", errKill
+	case 'q', 'Q':
+		return s.query(packet), nil
+	}
+
+	return "", nil // Empty reply means not supported
+}
+
+func (s *Server) query(packet string) string {
+	switch {
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=4000;QStartNoAckMode+"
+	case packet == "QStartNoAckMode":
+		return "OK"
+	case packet == "qAttached":
+		return "1"
+	case packet == "qC":
+		return "QC1"
+	case packet == "qfThreadInfo":
+		return "m1"
+	case packet == "qsThreadInfo":
+		return "l"
+	}
+	return ""
+}
+
+// run executes instructions until a breakpoint is hit, the client interrupts or disconnects.
+func (s *Server) run(sess *session) string {
+	s.cpu.Stepping = false
+	defer func() { s.cpu.Stepping = true }()
+
+	for i := 0; ; i++ {
+		s.cpu.Step()
+
+		if !s.cpu.Halted && s.breakpoints[s.cpu.Registers().PC] {
+			return stopTrap
+		}
+
+		if i%stepsBetweenChecks == 0 && sess.takeInterrupt() {
+			return stopInterrupt
+		}
+	}
+}
+
+// jumpTo sets PC if the resume packet has an address. It returns false if the address is not valid.
+func (s *Server) jumpTo(args string) bool {
+	if len(args) == 0 {
+		return true
+	}
+
+	address, err := strconv.ParseUint(args, 16, 16)
+	if err != nil {
+		return false
+	}
+	s.cpu.Registers().PC = uint16(address)
+	return true
+}
@@ -0,0 +1,174 @@
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"github.com/mikeletux/goboy/pkg/test"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loopProgram is an endless loop of INC A at 0x100: INC A ; JR -3
+var loopProgram = map[uint16][]byte{0x100: {0x3C, 0x18, 0xFD}}
+
+// client is a minimal RSP client
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (c *client) send(packet string) {
+	fmt.Fprintf(c.conn, "$%s#%02x", packet, packetChecksum(packet))
+}
+
+func (c *client) receive() string {
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if b != '$' { // Acks
+			continue
+		}
+
+		data, err := c.reader.ReadString('#')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		c.reader.Discard(2) // Checksum
+		return strings.TrimSuffix(data, "#")
+	}
+}
+
+func (c *client) exchange(packet string) string {
+	c.send(packet)
+	return c.receive()
+}
+
+func startServer(t *testing.T) *client {
+	memoryBus := bus.NewBus(test.NewRomMock(loopProgram), &log.NilLogger{})
+	server := New(cpu.Init(memoryBus, &log.NilLogger{}), memoryBus, &log.NilLogger{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		listener.Close()
+	})
+	return &client{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func TestRegistersAndMemory(t *testing.T) {
+	c := startServer(t)
+
+	if got := c.exchange("?"); got != stopTrap {
+		t.Errorf("expected stop reply %s got %s", stopTrap, got)
+	}
+
+	// Registers in order AF, BC, DE, HL, SP, PC
+	if got := c.exchange("G" + "b001" + "1300" + "d800" + "4d01" + "feff" + "0001"); got != "OK" {
+		t.Fatalf("expected OK writing registers got %s", got)
+	}
+	if got := c.exchange("p5"); got != "0001" {
+		t.Errorf("expected PC 0001 got %s", got)
+	}
+	if got := c.exchange("P1=3412"); got != "OK" {
+		t.Errorf("expected OK writing BC got %s", got)
+	}
+	if got := c.exchange("g"); got != "b0013412d8004d01feff0001" {
+		t.Errorf("unexpected registers %s", got)
+	}
+
+	if got := c.exchange("MC000,2:abcd"); got != "OK" {
+		t.Errorf("expected OK writing memory got %s", got)
+	}
+	if got := c.exchange("mC000,2"); got != "abcd" {
+		t.Errorf("expected abcd reading memory got %s", got)
+	}
+	if got := c.exchange("m100,3"); got != "3c18fd" {
+		t.Errorf("expected program reading ROM got %s", got)
+	}
+}
+
+func TestStepBreakpointAndInterrupt(t *testing.T) {
+	c := startServer(t)
+	c.exchange("P5=0001") // PC = 0x100
+
+	if got := c.exchange("s"); got != stopTrap {
+		t.Errorf("expected stop reply after step got %s", got)
+	}
+	if got := c.exchange("p5"); got != "0101" {
+		t.Errorf("expected PC 0101 after step got %s", got)
+	}
+
+	if got := c.exchange("Z0,101,1"); got != "OK" {
+		t.Errorf("expected OK setting breakpoint got %s", got)
+	}
+	if got := c.exchange("c"); got != stopTrap {
+		t.Errorf("expected stop reply at breakpoint got %s", got)
+	}
+	if got := c.exchange("p5"); got != "0101" {
+		t.Errorf("expected PC 0101 at breakpoint got %s", got)
+	}
+
+	c.exchange("z0,101,1")
+	c.send("c")
+	time.Sleep(10 * time.Millisecond)
+	c.conn.Write([]byte{interruptByte})
+	if got := c.receive(); got != stopInterrupt {
+		t.Errorf("expected interrupt stop reply got %s", got)
+	}
+}
+
+func TestKill(t *testing.T) {
+	c := startServer(t)
+
+	c.send("k")
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	rest, err := io.ReadAll(c.reader) // The server closes the connection
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(rest), "$") {
+		t.Errorf("expected no reply to k got %q", rest)
+	}
+}
+
+func TestServePacing(t *testing.T) {
+	memoryBus := bus.NewBus(test.NewRomMock(loopProgram), &log.NilLogger{})
+	c := cpu.Init(memoryBus, &log.NilLogger{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- New(c, memoryBus, &log.NilLogger{}).Serve(listener) }()
+	time.Sleep(10 * ppu.FrameDuration)
+	listener.Close()
+	<-done
+
+	// Without a client the CPU runs at normal speed, a frame's worth of cycles every FrameDuration
+	if frames := c.Cycles() / ppu.FrameCycles; frames == 0 || frames > 15 {
+		t.Errorf("expected up to 10 frames to run got %d", frames)
+	}
+}
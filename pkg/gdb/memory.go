@@ -0,0 +1,83 @@
+package gdb
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// parseAddressLength parses the "addr,length" arguments used by memory and breakpoint packets.
+func parseAddressLength(args string) (uint16, int, bool) {
+	addressArg, lengthArg, ok := strings.Cut(args, ",")
+	if !ok {
+		return 0, 0, false
+	}
+
+	address, err := strconv.ParseUint(addressArg, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	length, err := strconv.ParseUint(lengthArg, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return uint16(address), int(length), true
+}
+
+func (s *Server) readMemory(args string) string {
+	address, length, ok := parseAddressLength(args)
+	if !ok {
+		return errorReply
+	}
+
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = s.bus.BusRead(address + uint16(i))
+	}
+	return hex.EncodeToString(data)
+}
+
+func (s *Server) writeMemory(args string) string {
+	header, payload, ok := strings.Cut(args, ":")
+	if !ok {
+		return errorReply
+	}
+
+	address, length, ok := parseAddressLength(header)
+	if !ok {
+		return errorReply
+	}
+
+	data, err := hex.DecodeString(payload)
+	if err != nil || len(data) != length {
+		return errorReply
+	}
+
+	for i, value := range data {
+		s.bus.BusWrite(address+uint16(i), value)
+	}
+	return "OK"
+}
+
+// setBreakpoint handles Z and z packets. Software and hardware breakpoints behave the same, since both are
+// implemented by checking PC, and watchpoints are not supported.
+func (s *Server) setBreakpoint(insert bool, args string) string {
+	kind, rest, ok := strings.Cut(args, ",")
+	if !ok || (kind != "0" && kind != "1") {
+		return ""
+	}
+
+	address, _, ok := parseAddressLength(rest)
+	if !ok {
+		return errorReply
+	}
+
+	if insert {
+		s.breakpoints[address] = true
+	} else {
+		delete(s.breakpoints, address)
+	}
+	return "OK"
+}
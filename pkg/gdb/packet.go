@@ -0,0 +1,130 @@
+package gdb
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+const interruptByte byte = 0x03
+
+// session handles the packet framing of a client connection. Packets are read by a goroutine so the client
+// can interrupt the CPU whilst it runs.
+type session struct {
+	conn       net.Conn
+	writeMutex sync.Mutex
+	noAck      atomic.Bool
+
+	packets    chan string
+	interrupts chan struct{}
+	closed     chan struct{} // Closed when the connection is lost
+	stop       chan struct{} // Closed when the server is done with the session
+}
+
+func newSession(conn net.Conn) *session {
+	s := &session{
+		conn:       conn,
+		packets:    make(chan string),
+		interrupts: make(chan struct{}, 1),
+		closed:     make(chan struct{}),
+		stop:       make(chan struct{}),
+	}
+	go s.receive()
+	return s
+}
+
+// receive reads packets until the connection is closed, then it closes the packets channel.
+func (s *session) receive() {
+	defer close(s.closed)
+	defer close(s.packets)
+	reader := bufio.NewReader(s.conn)
+
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return
+		}
+
+		switch b {
+		case interruptByte:
+			select {
+			case s.interrupts <- struct{}{}:
+			default: // There's already one pending
+			}
+
+		case '$':
+			data, err := reader.ReadString('#')
+			if err != nil {
+				return
+			}
+			data = data[:len(data)-1]
+
+			var checksum [2]byte
+			for i := range checksum {
+				if checksum[i], err = reader.ReadByte(); err != nil {
+					return
+				}
+			}
+
+			expected, err := strconv.ParseUint(string(checksum[:]), 16, 8)
+			if err != nil || byte(expected) != packetChecksum(data) {
+				s.writeRaw("-")
+				continue
+			}
+
+			if !s.noAck.Load() {
+				s.writeRaw("+")
+			}
+			select {
+			case s.packets <- data:
+			case <-s.stop:
+				return
+			}
+
+			// Acks ('+' and '-') sent by the client are ignored, replies are never resent
+		}
+	}
+}
+
+// close ends the session and closes the connection.
+func (s *session) close() {
+	close(s.stop)
+	s.conn.Close()
+}
+
+func (s *session) writeRaw(data string) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	_, err := s.conn.Write([]byte(data))
+	return err
+}
+
+// reply sends a packet to the client.
+func (s *session) reply(data string) error {
+	return s.writeRaw(fmt.Sprintf("$%s#%02x", data, packetChecksum(data)))
+}
+
+// takeInterrupt returns whether the client has asked to stop the CPU, clearing the request. A closed
+// connection counts as an interruption too.
+func (s *session) takeInterrupt() bool {
+	select {
+	case <-s.interrupts:
+		return true
+	case <-s.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func packetChecksum(data string) byte {
+	var sum byte
+	for i := 0; i < len(data); i++ {
+		sum += data[i]
+	}
+	return sum
+}
@@ -0,0 +1,114 @@
+package gdb
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Register numbers
+const (
+	registerAF = iota
+	registerBC
+	registerDE
+	registerHL
+	registerSP
+	registerPC
+)
+
+const errorReply = "E01"
+
+func (s *Server) getRegister(n int) uint16 {
+	r := s.cpu.Registers()
+	switch n {
+	case registerAF:
+		return r.GetAF()
+	case registerBC:
+		return r.GetBC()
+	case registerDE:
+		return r.GetDE()
+	case registerHL:
+		return r.GetHL()
+	case registerSP:
+		return r.SP
+	default:
+		return r.PC
+	}
+}
+
+func (s *Server) setRegister(n int, value uint16) {
+	r := s.cpu.Registers()
+	switch n {
+	case registerAF:
+		r.SetAF(value & 0xFFF0) // Lower nibble of F is always 0
+	case registerBC:
+		r.SetBC(value)
+	case registerDE:
+		r.SetDE(value)
+	case registerHL:
+		r.SetHL(value)
+	case registerSP:
+		r.SP = value
+	case registerPC:
+		r.PC = value
+	}
+}
+
+// encodeRegister returns the register in target byte order, which is little endian.
+func encodeRegister(value uint16) string {
+	return hex.EncodeToString([]byte{byte(value), byte(value >> 8)})
+}
+
+func decodeRegister(data string) (uint16, bool) {
+	raw, err := hex.DecodeString(data)
+	if err != nil || len(raw) != 2 {
+		return 0, false
+	}
+	return uint16(raw[1])<<8 | uint16(raw[0]), true
+}
+
+func (s *Server) readRegisters() string {
+	var sb strings.Builder
+	for n := 0; n < registerCount; n++ {
+		sb.WriteString(encodeRegister(s.getRegister(n)))
+	}
+	return sb.String()
+}
+
+func (s *Server) writeRegisters(args string) string {
+	if len(args) != registerCount*4 {
+		return errorReply
+	}
+
+	for n := 0; n < registerCount; n++ {
+		value, ok := decodeRegister(args[n*4 : n*4+4])
+		if !ok {
+			return errorReply
+		}
+		s.setRegister(n, value)
+	}
+	return "OK"
+}
+
+func (s *Server) readRegister(args string) string {
+	n, err := strconv.ParseUint(args, 16, 8)
+	if err != nil || n >= registerCount {
+		return errorReply
+	}
+	return encodeRegister(s.getRegister(int(n)))
+}
+
+func (s *Server) writeRegister(args string) string {
+	number, data, ok := strings.Cut(args, "=")
+	n, err := strconv.ParseUint(number, 16, 8)
+	if !ok || err != nil || n >= registerCount {
+		return errorReply
+	}
+
+	value, ok := decodeRegister(data)
+	if !ok {
+		return errorReply
+	}
+	s.setRegister(int(n), value)
+	return "OK"
+}
@@ -0,0 +1,99 @@
+package gym
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"sync"
+)
+
+// Action is what an agent does in a step: hold some buttons for some frames.
+type Action struct {
+	Buttons goboy.Buttons
+	Frames  int
+}
+
+// Batch is a set of independent environments running the same ROM, stepped in parallel.
+type Batch struct {
+	envs   []*Environment
+	config Config
+}
+
+// NewBatch returns a batch of size powered on environments.
+func NewBatch(rom []byte, config Config, size int) (*Batch, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("a batch needs at least 1 environment, got %d", size)
+	}
+
+	b := &Batch{config: config}
+	for i := 0; i < size; i++ {
+		env, err := NewEnvironment(rom, config)
+		if err != nil {
+			return nil, err
+		}
+		b.envs = append(b.envs, env)
+	}
+	return b, nil
+}
+
+// Len returns the number of environments.
+func (b *Batch) Len() int {
+	return len(b.envs)
+}
+
+// Env returns the environment i.
+func (b *Batch) Env(i int) (*Environment, error) {
+	if i < 0 || i >= len(b.envs) {
+		return nil, fmt.Errorf("environment %d doesn't exist, there are %d", i, len(b.envs))
+	}
+	return b.envs[i], nil
+}
+
+// Step runs an action on every environment, actions[i] on environment i, in parallel.
+func (b *Batch) Step(actions []Action) ([]Observation, error) {
+	if len(actions) != len(b.envs) {
+		return nil, fmt.Errorf("expected %d actions got %d", len(b.envs), len(actions))
+	}
+
+	return b.parallel(func(i int, env *Environment) (Observation, error) {
+		return env.Step(actions[i].Buttons, actions[i].Frames)
+	})
+}
+
+// Reset resets every environment in parallel, environment i to states[i]. states can be nil to power all of
+// them on again.
+func (b *Batch) Reset(states [][]byte) ([]Observation, error) {
+	if states != nil && len(states) != len(b.envs) {
+		return nil, fmt.Errorf("expected %d states got %d", len(b.envs), len(states))
+	}
+
+	return b.parallel(func(i int, env *Environment) (Observation, error) {
+		var state []byte
+		if states != nil {
+			state = states[i]
+		}
+		return env.Reset(state)
+	})
+}
+
+// parallel calls run for every environment in its own goroutine. The first error is returned.
+func (b *Batch) parallel(run func(i int, env *Environment) (Observation, error)) ([]Observation, error) {
+	observations := make([]Observation, len(b.envs))
+	errs := make([]error, len(b.envs))
+
+	var wg sync.WaitGroup
+	for i, env := range b.envs {
+		wg.Add(1)
+		go func(i int, env *Environment) {
+			defer wg.Done()
+			observations[i], errs[i] = run(i, env)
+		}(i, env)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("environment %d: %w", i, err)
+		}
+	}
+	return observations, nil
+}
@@ -0,0 +1,170 @@
+// Package gym runs Game Boy games as reinforcement learning environments, in the style of OpenAI Gym: an
+// agent resets an environment to a saved state and then steps it, pressing buttons for some frames and
+// observing the screen and chosen RAM addresses. Many independent environments can run in a process, in
+// parallel, and be served over TCP to agents written in other languages.
+package gym
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Env is a reinforcement learning environment.
+type Env interface {
+	// Reset powers the game on again, or loads a state saved with SaveState if it is not nil.
+	Reset(state []byte) (Observation, error)
+	// Step holds the buttons for the given number of frames and returns what the agent sees afterwards.
+	Step(buttons goboy.Buttons, frames int) (Observation, error)
+	// Observe returns what the agent sees without running the game.
+	Observe() Observation
+}
+
+// Config configures the environments.
+type Config struct {
+	// Ram are the addresses read into Observation.Ram, like the score or the player position.
+	Ram []uint16
+}
+
+// Observation is what the agent sees after a step.
+type Observation struct {
+	// Frame is the screen as RGB bytes, row by row.
+	Frame  []byte `json:"frame"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Ram has the values of the Config.Ram addresses, in the same order.
+	Ram        []byte `json:"ram"`
+	FrameCount uint64 `json:"frameCount"`
+}
+
+// Environment is an Env running a ROM in this process. It is safe for concurrent use.
+type Environment struct {
+	rom    []byte
+	config Config
+
+	mutex   sync.Mutex
+	machine *goboy.Machine
+}
+
+// NewEnvironment returns a powered on Environment running the ROM.
+func NewEnvironment(rom []byte, config Config) (*Environment, error) {
+	machine, err := goboy.New(rom, goboy.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &Environment{rom: rom, config: config, machine: machine}, nil
+}
+
+// Reset powers the game on again, with a blank cartridge RAM, or loads a state saved with SaveState.
+func (e *Environment) Reset(state []byte) (Observation, error) {
+	machine, err := goboy.New(e.rom, goboy.Options{})
+	if err != nil {
+		return Observation{}, err
+	}
+	if state != nil {
+		if err := machine.LoadState(bytes.NewReader(state)); err != nil {
+			return Observation{}, err
+		}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.machine = machine
+	return e.observe(), nil
+}
+
+// Step holds the buttons for the given number of frames, at least 1.
+func (e *Environment) Step(buttons goboy.Buttons, frames int) (Observation, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.machine.SetButtons(buttons)
+	for i := 0; i < frames || i == 0; i++ {
+		if err := e.machine.RunFrame(); err != nil {
+			return Observation{}, err
+		}
+	}
+	e.machine.AudioSamples() // Drop the sound, it would be kept up to a second otherwise
+	return e.observe(), nil
+}
+
+// Observe returns what the agent sees without running the game.
+func (e *Environment) Observe() Observation {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.observe()
+}
+
+func (e *Environment) observe() Observation {
+	framebuffer := e.machine.Framebuffer()
+	frame := make([]byte, 0, len(framebuffer)*3)
+	for _, pixel := range framebuffer {
+		frame = append(frame, byte(pixel>>16), byte(pixel>>8), byte(pixel))
+	}
+
+	ram := make([]byte, len(e.config.Ram))
+	for i, address := range e.config.Ram {
+		ram[i] = e.machine.Bus().Peek(address)
+	}
+
+	return Observation{
+		Frame:      frame,
+		Width:      goboy.ScreenWidth,
+		Height:     goboy.ScreenHeight,
+		Ram:        ram,
+		FrameCount: e.machine.FrameCount(),
+	}
+}
+
+// SaveState returns the state of the game, to be passed to Reset.
+func (e *Environment) SaveState() ([]byte, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var state bytes.Buffer
+	if err := e.machine.SaveState(&state); err != nil {
+		return nil, err
+	}
+	return state.Bytes(), nil
+}
+
+// ParseAddresses parses a comma separated list of hexadecimal addresses and ranges, like C000-C00F,D000.
+func ParseAddresses(s string) ([]uint16, error) {
+	parse := func(field string) (uint16, error) {
+		value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(field)), "0x"), 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid address %q", field)
+		}
+		return uint16(value), nil
+	}
+
+	var addresses []uint16
+	for _, field := range strings.Split(s, ",") {
+		if len(strings.TrimSpace(field)) == 0 {
+			continue
+		}
+
+		fromField, toField, isRange := strings.Cut(field, "-")
+		from, err := parse(fromField)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parse(toField); err != nil {
+				return nil, err
+			}
+			if from > to {
+				return nil, fmt.Errorf("invalid range %q, start is after the end", field)
+			}
+		}
+
+		for address := int(from); address <= int(to); address++ {
+			addresses = append(addresses, uint16(address))
+		}
+	}
+	return addresses, nil
+}
@@ -0,0 +1,186 @@
+package gym
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/test"
+	"net"
+	"testing"
+)
+
+// joypadProgram stores the action buttons in C001 and counts loops in C000:
+// JP 0150 ; LD A,10 ; LDH (00),A ; LDH A,(00) ; LD (C001),A ; LD HL,C000 ; INC (HL) ; JR 0150
+var joypadProgram = map[uint16][]byte{
+	0x100: {0xC3, 0x50, 0x01},
+	0x150: {0x3E, 0x10, 0xE0, 0x00, 0xF0, 0x00, 0xEA, 0x01, 0xC0, 0x21, 0x00, 0xC0, 0x34, 0x18, 0xF1},
+}
+
+var testConfig = Config{Ram: []uint16{0xC000, 0xC001}}
+
+func newTestEnvironment(t *testing.T) *Environment {
+	env, err := NewEnvironment(test.Rom("JOYPAD", joypadProgram), testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+func TestStep(t *testing.T) {
+	env := newTestEnvironment(t)
+
+	observation, err := env.Step(goboy.ButtonStart|goboy.ButtonA, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if observation.FrameCount != 2 {
+		t.Errorf("expected 2 frames got %d", observation.FrameCount)
+	}
+	if len(observation.Frame) != goboy.ScreenWidth*goboy.ScreenHeight*3 {
+		t.Errorf("expected an RGB frame got %d bytes", len(observation.Frame))
+	}
+	if observation.Ram[0] == 0 || observation.Ram[1]&0x0F != 0b0110 {
+		t.Errorf("expected the program to read Start and A, RAM is % X", observation.Ram)
+	}
+}
+
+func TestReset(t *testing.T) {
+	env := newTestEnvironment(t)
+	if _, err := env.Step(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	state, err := env.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := env.Step(goboy.ButtonB, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if observation, err := env.Reset(nil); err != nil || observation.FrameCount != 0 {
+		t.Fatalf("expected the game to be powered on again, got frame %d, %v", observation.FrameCount, err)
+	}
+
+	if _, err := env.Reset(state); err != nil {
+		t.Fatal(err)
+	}
+	observation, err := env.Step(goboy.ButtonB, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if observation.FrameCount != expected.FrameCount || string(observation.Ram) != string(expected.Ram) {
+		t.Errorf("expected the same observation after loading the state, RAM % X and % X", observation.Ram,
+			expected.Ram)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	batch, err := NewBatch(test.Rom("JOYPAD", joypadProgram), testConfig, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each environment presses a different button, none of them must see the others'
+	actions := []Action{
+		{Buttons: goboy.ButtonA, Frames: 2},
+		{Buttons: goboy.ButtonB, Frames: 2},
+		{Buttons: goboy.ButtonSelect, Frames: 2},
+		{Buttons: goboy.ButtonStart, Frames: 2},
+	}
+	observations, err := batch.Step(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, observation := range observations {
+		if expected := ^byte(1<<i) & 0x0F; observation.Ram[1]&0x0F != expected {
+			t.Errorf("environment %d: expected P1 %04b got %04b", i, expected, observation.Ram[1]&0x0F)
+		}
+	}
+
+	if _, err := batch.Step(actions[:1]); err == nil {
+		t.Error("expected an error with fewer actions than environments")
+	}
+}
+
+func TestParseAddresses(t *testing.T) {
+	addresses, err := ParseAddresses("C000-C002, 0xD000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(addresses) != fmt.Sprint([]uint16{0xC000, 0xC001, 0xC002, 0xD000}) {
+		t.Errorf("unexpected addresses %X", addresses)
+	}
+
+	for _, invalid := range []string{"C0000", "C002-C000", "XYZ"} {
+		if _, err := ParseAddresses(invalid); err == nil {
+			t.Errorf("expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	batch, err := NewBatch(test.Rom("JOYPAD", joypadProgram), testConfig, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go NewServer(batch).Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	call := func(req string) response {
+		fmt.Fprintln(conn, req)
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp response
+		if err := json.Unmarshal(line, &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := call(`{"method":"info"}`); resp.Info == nil || resp.Info.Envs != 2 || len(resp.Info.Ram) != 2 {
+		t.Errorf("unexpected info %+v", resp.Info)
+	}
+
+	resp := call(`{"method":"step","env":1,"buttons":["start","a"],"frames":2}`)
+	if resp.Observation == nil || resp.Observation.Ram[1]&0x0F != 0b0110 {
+		t.Fatalf("unexpected step response %+v", resp)
+	}
+
+	resp = call(`{"method":"step_batch","actions":[{"buttons":["b"],"frames":1},{"buttons":[],"frames":1}]}`)
+	if len(resp.Observations) != 2 || resp.Observations[1].FrameCount != 3 {
+		t.Fatalf("unexpected batch response %+v", resp.Error)
+	}
+
+	state := call(`{"method":"save","env":1}`).State
+	if len(state) == 0 {
+		t.Fatal("expected a state")
+	}
+	request, _ := json.Marshal(request{Method: "reset", Env: 0, State: state})
+	if resp := call(string(request)); resp.Observation == nil || resp.Observation.FrameCount != 3 {
+		t.Errorf("expected environment 0 to load the state of 1 got %+v", resp.Error)
+	}
+
+	if resp := call(`{"method":"step","env":5}`); len(resp.Error) == 0 {
+		t.Error("expected an error for an environment that doesn't exist")
+	}
+	if resp := call(`{"method":"step","env":0,"buttons":["turbo"]}`); len(resp.Error) == 0 {
+		t.Error("expected an error for an unknown button")
+	}
+}
@@ -0,0 +1,152 @@
+package gym
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"io"
+	"net"
+)
+
+// The server speaks JSON over TCP: a request per line and a response per line, in the same order. Byte slices,
+// like frames and states, are base64 strings as encoding/json does. For example:
+//
+//	{"method":"info"}
+//	{"method":"reset","env":0,"state":"..."}
+//	{"method":"step","env":0,"buttons":["a","right"],"frames":4}
+//	{"method":"step_batch","actions":[{"buttons":["a"],"frames":4},{"buttons":[],"frames":4}]}
+//	{"method":"observe","env":0}
+//	{"method":"save","env":0}
+
+type request struct {
+	Method  string          `json:"method"`
+	Env     int             `json:"env"`
+	State   []byte          `json:"state,omitempty"`
+	Buttons []string        `json:"buttons,omitempty"`
+	Frames  int             `json:"frames,omitempty"`
+	Actions []requestAction `json:"actions,omitempty"`
+}
+
+type requestAction struct {
+	Buttons []string `json:"buttons"`
+	Frames  int      `json:"frames"`
+}
+
+type response struct {
+	Error        string        `json:"error,omitempty"`
+	Observation  *Observation  `json:"observation,omitempty"`
+	Observations []Observation `json:"observations,omitempty"`
+	State        []byte        `json:"state,omitempty"`
+	Info         *info         `json:"info,omitempty"`
+}
+
+// info describes the environments served.
+type info struct {
+	Envs int      `json:"envs"`
+	Ram  []uint16 `json:"ram"`
+}
+
+// Server serves a batch of environments over TCP.
+type Server struct {
+	batch *Batch
+}
+
+// NewServer returns a Server for the environments of the batch.
+func NewServer(batch *Batch) *Server {
+	return &Server{batch: batch}
+}
+
+// Serve accepts connections until the listener is closed. Connections are served concurrently, and can share
+// the environments.
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	writer := bufio.NewWriter(conn)
+	encoder := json.NewEncoder(writer)
+
+	for {
+		var req request
+		var resp response
+		if err := decoder.Decode(&req); err == io.EOF {
+			return
+		} else if err != nil {
+			// The stream can't be resynchronised after a syntax error
+			encoder.Encode(response{Error: fmt.Sprintf("invalid request: %s", err)})
+			writer.Flush()
+			return
+		}
+
+		if err := s.handle(req, &resp); err != nil {
+			resp = response{Error: err.Error()}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handle(req request, resp *response) error {
+	if req.Method == "info" {
+		resp.Info = &info{Envs: s.batch.Len(), Ram: s.batch.config.Ram}
+		return nil
+	}
+
+	if req.Method == "step_batch" {
+		actions := make([]Action, len(req.Actions))
+		for i, action := range req.Actions {
+			buttons, err := goboy.ParseButtons(action.Buttons...)
+			if err != nil {
+				return err
+			}
+			actions[i] = Action{Buttons: buttons, Frames: action.Frames}
+		}
+
+		observations, err := s.batch.Step(actions)
+		resp.Observations = observations
+		return err
+	}
+
+	env, err := s.batch.Env(req.Env)
+	if err != nil {
+		return err
+	}
+
+	var observation Observation
+	switch req.Method {
+	case "reset":
+		observation, err = env.Reset(req.State)
+	case "step":
+		var buttons goboy.Buttons
+		if buttons, err = goboy.ParseButtons(req.Buttons...); err == nil {
+			observation, err = env.Step(buttons, req.Frames)
+		}
+	case "observe":
+		observation = env.Observe()
+	case "save":
+		resp.State, err = env.SaveState()
+		return err
+	default:
+		return fmt.Errorf("unknown method %q", req.Method)
+	}
+
+	if err != nil {
+		return err
+	}
+	resp.Observation = &observation
+	return nil
+}
@@ -1,50 +1,47 @@
+//go:build sdl
+
 package lcd
 
 import (
-	"github.com/mikeletux/goboy/pkg/bus"
 	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/vram"
 	"github.com/veandco/go-sdl2/sdl"
 	"unsafe"
 )
 
+// debugScale is how many times the VRAM viewer is enlarged.
+const debugScale = 2
+
+// GameboyDebugWindow shows the VRAM viewer. The mouse positions it gets are divided by debugScale.
 type GameboyDebugWindow struct {
 	sdlWindow   *sdl.Window
 	sdlRenderer *sdl.Renderer
 	sdlTexture  *sdl.Texture
-	sdlScreen   *sdl.Surface
+	windowID    uint32
 
 	logger log.Logger
-	bus    bus.DataBusInterface
+	viewer *vram.Viewer
 }
 
-func InitGameBoyDebugWindow(logger log.Logger, bus bus.DataBusInterface) (*GameboyDebugWindow, error) {
-	sdlWindow, sdlRenderer, err := sdl.CreateWindowAndRenderer(16*8*scale, 32*8*scale, 0)
+func InitGameBoyDebugWindow(logger log.Logger, memory vram.Memory) (*GameboyDebugWindow, error) {
+	sdlWindow, sdlRenderer, err := sdl.CreateWindowAndRenderer(vram.Width*debugScale, vram.Height*debugScale, 0)
 	if err != nil {
 		return nil, err
 	}
+	sdlWindow.SetTitle("VRAM")
 
-	sdlTexture, err := sdlRenderer.CreateTexture(
-		sdl.PIXELFORMAT_ABGR8888,
-		sdl.TEXTUREACCESS_STREAMING,
-		(16*8*scale)+(16*scale),
-		(32*8*scale)+(64*scale),
-	)
-
+	windowID, err := sdlWindow.GetID()
 	if err != nil {
 		return nil, err
 	}
 
-	sdlSurface, err := sdl.CreateRGBSurface(
-		0,
-		(16*8*scale)+(16*scale),
-		(32*8*scale)+(64*scale),
-		32,
-		0x00FF0000,
-		0x0000FF00,
-		0x000000FF,
-		0xFF000000,
+	// Viewer pixels are 0xAARRGGBB, which matches ARGB8888 packed format.
+	sdlTexture, err := sdlRenderer.CreateTexture(
+		sdl.PIXELFORMAT_ARGB8888,
+		sdl.TEXTUREACCESS_STREAMING,
+		vram.Width,
+		vram.Height,
 	)
-
 	if err != nil {
 		return nil, err
 	}
@@ -53,40 +50,39 @@ func InitGameBoyDebugWindow(logger log.Logger, bus bus.DataBusInterface) (*Gameb
 		sdlWindow:   sdlWindow,
 		sdlRenderer: sdlRenderer,
 		sdlTexture:  sdlTexture,
-		sdlScreen:   sdlSurface,
+		windowID:    windowID,
 		logger:      logger,
-		bus:         bus,
+		viewer:      vram.New(memory),
 	}, nil
 }
 
-func (g *GameboyDebugWindow) updateWindow() {
-	xDraw := 0
-	yDraw := 0
-	tileNum := 0
-
-	var rc sdl.Rect
-	rc.X = 0
-	rc.Y = 0
-	rc.W = g.sdlScreen.W
-	rc.H = g.sdlScreen.H
-	g.sdlScreen.FillRect(&rc, 0xFF111111) // HANDLE ERROR HERE
-
-	var addr uint16 = 0x8000
-
-	//384 tiles, 24 x 16
-	for y := 0; y < 24; y++ {
-		for x := 0; x < 16; x++ {
-			displayTile(g.sdlScreen, g.bus, addr, uint16(tileNum), xDraw+(x*scale), yDraw+(y*scale))
-			xDraw += 8 * scale
-			tileNum++
+// handleEvent passes the mouse events of the window to the viewer and returns whether the event was for it.
+func (g *GameboyDebugWindow) handleEvent(event sdl.Event) bool {
+	switch e := event.(type) {
+	case *sdl.MouseMotionEvent:
+		if e.WindowID == g.windowID {
+			g.viewer.Hover(int(e.X)/debugScale, int(e.Y)/debugScale)
+			return true
+		}
+	case *sdl.MouseButtonEvent:
+		if e.WindowID == g.windowID {
+			if e.Button == sdl.BUTTON_LEFT && e.State == sdl.PRESSED {
+				g.viewer.Click(int(e.X)/debugScale, int(e.Y)/debugScale)
+			}
+			return true
+		}
+	case *sdl.WindowEvent:
+		if e.WindowID == g.windowID && e.Event == sdl.WINDOWEVENT_LEAVE {
+			g.viewer.Leave()
+			return true
 		}
-
-		yDraw += 8 * scale
-		xDraw = 0
 	}
+	return false
+}
 
-	pixels := g.sdlScreen.Pixels()
-	g.sdlTexture.Update(nil, unsafe.Pointer(&pixels[0]), int(g.sdlScreen.Pitch))
+func (g *GameboyDebugWindow) updateWindow() {
+	frame := g.viewer.Render()
+	g.sdlTexture.Update(nil, unsafe.Pointer(&frame[0]), vram.Width*4)
 	g.sdlRenderer.Clear()
 	g.sdlRenderer.Copy(g.sdlTexture, nil, nil)
 	g.sdlRenderer.Present()
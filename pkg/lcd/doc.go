@@ -0,0 +1,4 @@
+// Package lcd is the SDL frontend: a window with the game screen, another one with the VRAM viewer, the
+// keyboard as joypad and the sound. It needs the SDL2 libraries and cgo, so it is only built with the sdl
+// build tag.
+package lcd
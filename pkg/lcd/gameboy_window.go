@@ -1,16 +1,34 @@
+//go:build sdl
+
 package lcd
 
-import "github.com/veandco/go-sdl2/sdl"
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"unsafe"
+)
 
 type GameboyWindow struct {
 	sdlWindow   *sdl.Window
 	sdlRenderer *sdl.Renderer
 	sdlTexture  *sdl.Texture
 	sdlScreen   *sdl.Surface
+
+	width int32
 }
 
-func InitGameBoyWindow() (*GameboyWindow, error) {
-	sdlWindow, sdlRenderer, err := sdl.CreateWindowAndRenderer(gameBoyScreenWidth, gameBoyScreenHeight, 0)
+func InitGameBoyWindow(width, height int32) (*GameboyWindow, error) {
+	sdlWindow, sdlRenderer, err := sdl.CreateWindowAndRenderer(width*scale, height*scale, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Frame pixels are 0xAARRGGBB, which matches ARGB8888 packed format.
+	sdlTexture, err := sdlRenderer.CreateTexture(
+		sdl.PIXELFORMAT_ARGB8888,
+		sdl.TEXTUREACCESS_STREAMING,
+		width,
+		height,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -18,5 +36,14 @@ func InitGameBoyWindow() (*GameboyWindow, error) {
 	return &GameboyWindow{
 		sdlWindow:   sdlWindow,
 		sdlRenderer: sdlRenderer,
+		sdlTexture:  sdlTexture,
+		width:       width,
 	}, nil
 }
+
+func (g *GameboyWindow) updateWindow(frame []uint32) {
+	g.sdlTexture.Update(nil, unsafe.Pointer(&frame[0]), int(g.width)*4)
+	g.sdlRenderer.Clear()
+	g.sdlRenderer.Copy(g.sdlTexture, nil, nil)
+	g.sdlRenderer.Present()
+}
@@ -1,98 +1,185 @@
+//go:build sdl
+
 package lcd
 
 import (
-	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
 	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/vram"
 	"github.com/veandco/go-sdl2/sdl"
+	"unsafe"
 )
 
-const (
-	gameBoyScreenWidth  = 800
-	gameBoyScreenHeight = 600
-	scale               = 4
-)
+const scale = 4
+
+// maxQueuedAudio is the sound queued in SDL, in seconds, above which new samples are dropped to keep the
+// latency low.
+const maxQueuedAudio = 0.1
+
+// keyButtons maps the keyboard to the joypad.
+var keyButtons = map[sdl.Keycode]goboy.Buttons{
+	sdl.K_RIGHT:     goboy.ButtonRight,
+	sdl.K_LEFT:      goboy.ButtonLeft,
+	sdl.K_UP:        goboy.ButtonUp,
+	sdl.K_DOWN:      goboy.ButtonDown,
+	sdl.K_x:         goboy.ButtonA,
+	sdl.K_z:         goboy.ButtonB,
+	sdl.K_BACKSPACE: goboy.ButtonSelect,
+	sdl.K_RETURN:    goboy.ButtonStart,
+}
 
+// GameboyScreen is the SDL frontend. It implements frontend.Frontend.
 type GameboyScreen struct {
 	window      *GameboyWindow
 	debugWindow *GameboyDebugWindow
+
+	audio          sdl.AudioDeviceID
+	maxQueuedBytes uint32
+	buttons        goboy.Buttons
+	hotkeys        frontend.Hotkeys // Pressed since the last call to Hotkeys
+	fastForward    bool
+	quit           bool
 }
 
-func NewGameboyScreen(logger log.Logger, bus bus.DataBusInterface) *GameboyScreen {
-	if err := sdl.Init(sdl.INIT_VIDEO); err != nil {
-		panic(err)
+// NewGameboyScreen creates the game window, of width x height pixels, the debug window with the VRAM viewer of
+// memory and the audio device.
+func NewGameboyScreen(logger log.Logger, memory vram.Memory, width, height, sampleRate int) (*GameboyScreen, error) {
+	if err := sdl.Init(sdl.INIT_VIDEO | sdl.INIT_AUDIO); err != nil {
+		return nil, err
 	}
 
-	/*	gameBoyWindow, err := InitGameBoyWindow()
-		if err != nil {
-			panic(err) // Handle better
-		}*/
+	gameBoyWindow, err := InitGameBoyWindow(int32(width), int32(height))
+	if err != nil {
+		return nil, err
+	}
 
-	gameBoyDebugWindow, err := InitGameBoyDebugWindow(logger, bus)
+	gameBoyDebugWindow, err := InitGameBoyDebugWindow(logger, memory)
 	if err != nil {
-		panic(err) // Handle better
+		return nil, err
 	}
 
 	// Set one window aside the other one
-	//	x, y := gameBoyWindow.sdlWindow.GetPosition()
-	//	gameBoyDebugWindow.sdlWindow.SetPosition(x+gameBoyScreenWidth+10, y)
+	x, y := gameBoyWindow.sdlWindow.GetPosition()
+	gameBoyDebugWindow.sdlWindow.SetPosition(x+gameBoyWindow.width*scale+10, y)
 
-	return &GameboyScreen{
-		//		window:      gameBoyWindow,
-		debugWindow: gameBoyDebugWindow,
+	spec := &sdl.AudioSpec{Freq: int32(sampleRate), Format: sdl.AUDIO_S16SYS, Channels: 2, Samples: 1024}
+	audio, err := sdl.OpenAudioDevice("", false, spec, nil, 0)
+	if err != nil {
+		return nil, err
 	}
+	sdl.PauseAudioDevice(audio, false)
+
+	return &GameboyScreen{
+		window:         gameBoyWindow,
+		debugWindow:    gameBoyDebugWindow,
+		audio:          audio,
+		maxQueuedBytes: uint32(float64(sampleRate) * maxQueuedAudio * 4), // 2 channels of 2 bytes
+	}, nil
 }
 
-func (g *GameboyScreen) UpdateUI() {
+// Draw shows a frame in the game window and refreshes the debug window.
+func (g *GameboyScreen) Draw(frame []uint32, width, height int) {
+	g.window.updateWindow(frame)
 	g.debugWindow.updateWindow()
 }
 
-func (g *GameboyScreen) DestroyWindow() {
-	g.window.sdlWindow.Destroy()
-	sdl.Quit()
+// Play queues the samples in the audio device.
+func (g *GameboyScreen) Play(samples []int16) {
+	if len(samples) == 0 || sdl.GetQueuedAudioSize(g.audio) > g.maxQueuedBytes {
+		return
+	}
+
+	data := unsafe.Slice((*byte)(unsafe.Pointer(&samples[0])), len(samples)*2)
+	if err := sdl.QueueAudio(g.audio, data); err != nil {
+		return // Sound is not worth stopping the game
+	}
 }
 
-func displayTile(surface *sdl.Surface, bus bus.DataBusInterface, startLocation uint16, tileNum uint16, x, y int) {
-	for tileY := uint16(0); tileY < 16; tileY += 2 {
-		b1 := bus.BusRead(startLocation + tileNum*16 + tileY)
-		b2 := bus.BusRead(startLocation + tileNum*16 + tileY + 1)
-
-		for bit := 7; bit >= 0; bit-- {
-			// hi := !!(b1 & (1 << bit)) << 1
-			var hi byte
-			if b1&(1<<bit) > 0 {
-				hi = 1 << 1
-			} else {
-				hi = 0
-			}
-			// lo := !!(b2 & (1 << bit))
+// Poll returns the buttons held and whether any window was closed.
+func (g *GameboyScreen) Poll() (goboy.Buttons, bool) {
+	g.handleEvents()
+	return g.buttons, g.quit
+}
 
-			var lo byte
-			if b2&(1<<bit) > 0 {
-				lo = 1
-			} else {
-				lo = 0
-			}
-			color := hi | lo
+// Hotkeys returns the hotkeys pressed since the last call: P pauses, N advances a frame, + and - change the
+// speed, Tab is held to fast-forward, C takes a screenshot and V starts or stops recording. 1 to 5 show the
+// tabs of the VRAM viewer and [ and ] change the palette of its tiles.
+func (g *GameboyScreen) Hotkeys() frontend.Hotkeys {
+	g.handleEvents()
+
+	hotkeys := g.hotkeys
+	g.hotkeys = frontend.Hotkeys{}
+	hotkeys.FastForward = g.fastForward
+	hotkeys.Quit = g.quit
+	return hotkeys
+}
+
+// handleEvents handles the pending SDL events. Closing any window quits.
+func (g *GameboyScreen) handleEvents() {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		if g.debugWindow.handleEvent(event) {
+			continue
+		}
 
-			rc := &sdl.Rect{
-				X: int32(x + ((7 - bit) * scale)),
-				Y: int32(y + (int(tileY) / 2 * scale)),
-				W: scale,
-				H: scale,
+		switch e := event.(type) {
+		case *sdl.QuitEvent:
+			g.quit = true
+		case *sdl.WindowEvent:
+			if e.Event == sdl.WINDOWEVENT_CLOSE {
+				g.quit = true
 			}
+		case *sdl.KeyboardEvent:
+			g.handleKey(e.Keysym.Sym, e.State == sdl.PRESSED, e.Repeat != 0)
+		}
+	}
+}
 
-			surface.FillRect(rc, getPixelColor(color))
+func (g *GameboyScreen) handleKey(key sdl.Keycode, pressed, repeat bool) {
+	if button, ok := keyButtons[key]; ok {
+		if pressed {
+			g.buttons |= button
+		} else {
+			g.buttons &^= button
 		}
+		return
+	}
+
+	if key == sdl.K_TAB {
+		g.fastForward = pressed
+		return
+	}
+	if !pressed || repeat {
+		return
+	}
+
+	switch key {
+	case sdl.K_p:
+		g.hotkeys.Pause = true
+	case sdl.K_n:
+		g.hotkeys.FrameAdvance = true
+	case sdl.K_PLUS, sdl.K_EQUALS, sdl.K_KP_PLUS:
+		g.hotkeys.SpeedUp = true
+	case sdl.K_MINUS, sdl.K_KP_MINUS:
+		g.hotkeys.SpeedDown = true
+	case sdl.K_c:
+		g.hotkeys.Screenshot = true
+	case sdl.K_v:
+		g.hotkeys.Record = true
+	case sdl.K_1, sdl.K_2, sdl.K_3, sdl.K_4, sdl.K_5:
+		g.debugWindow.viewer.SetTab(vram.Tab(key - sdl.K_1))
+	case sdl.K_LEFTBRACKET:
+		g.debugWindow.viewer.NextPalette(-1)
+	case sdl.K_RIGHTBRACKET:
+		g.debugWindow.viewer.NextPalette(1)
 	}
 }
 
-func getPixelColor(code byte) uint32 {
-	/*
-		0xFFFFFFFF -> White
-		0xFFAAAAAA -> Light grey
-		0xFF5555555 -> Dark grey
-		0xFF000000 -> Black
-	*/
-	colors := []uint32{0xFFFFFFFF, 0xFFAAAAAA, 0xFF555555, 0xFF000000}
-	return colors[code]
+// Close destroys the windows and releases SDL.
+func (g *GameboyScreen) Close() error {
+	sdl.CloseAudioDevice(g.audio)
+	g.debugWindow.sdlWindow.Destroy()
+	g.window.sdlWindow.Destroy()
+	sdl.Quit()
+	return nil
 }
@@ -0,0 +1,232 @@
+// Package link emulates the link cable between two GoBoy instances over TCP.
+//
+// Both sides exchange fixed size messages made of a kind byte, a 32 bit big endian number and a data byte,
+// and run in lockstep: time is split in periods of syncCycles machine cycles, and at the end of each period a
+// Game Boy sends a sync message and waits for the sync of its peer before starting the next one. Neither side
+// can get a period ahead of the other, whatever the speed of each machine or of the network.
+//
+// The Game Boy that clocks a transfer sends a transfer message and waits for the reply. Its peer handles the
+// transfers of a period when it reaches the end of that period, which it can always do since the side clocking
+// is at most in the same period. The peer receives the byte if its game waits for an externally clocked
+// transfer and answers 0xFF otherwise, so the result only depends on the cycle at which each game does what,
+// never on how long messages take.
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/mikeletux/goboy/pkg/log"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	messageSize = 6
+
+	kindTransfer byte = 'T'
+	kindReply    byte = 'R'
+	kindSync     byte = 'S'
+
+	// syncCycles is the length of the periods both Game Boys run in lockstep, in machine cycles. A transfer at
+	// the 8192Hz clock lasts 1024.
+	syncCycles = 1024
+
+	// disconnectedByte is what the Game Boy receives when nothing answers on the other side of the cable.
+	disconnectedByte byte = 0xFF
+)
+
+type message struct {
+	kind   byte
+	number uint32 // Transfer sequence number or period
+	data   byte
+}
+
+// Cable is one end of the link cable. It implements bus.SerialDevice so it can be plugged into the bus. Its
+// methods must be called from the goroutine running the CPU.
+type Cable struct {
+	conn   net.Conn
+	logger log.Logger
+
+	cycles    int    // Machine cycles run in the current period
+	period    uint32 // Number of the current period
+	peerSyncs uint32 // Number of sync messages received
+	sequence  uint32 // Number of the last transfer clocked by this side
+	incoming  chan message
+	closed    chan struct{} // Closed once the connection is lost
+	done      chan struct{} // Closed by Close
+	closeOnce sync.Once
+}
+
+// Listen waits for a peer to connect on the given address and returns the cable once connected.
+func Listen(address string, logger log.Logger) (*Cable, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+
+	logger.Debugf("waiting for link cable peer on %s", listener.Addr())
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return newCable(conn, logger), nil
+}
+
+// Dial connects to a peer listening on the given address.
+func Dial(address string, logger log.Logger) (*Cable, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCable(conn, logger), nil
+}
+
+func newCable(conn net.Conn, logger log.Logger) *Cable {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetNoDelay(true) // Every transfer is a single small round trip
+	}
+
+	c := &Cable{
+		conn:     conn,
+		logger:   logger,
+		incoming: make(chan message, 16),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.read()
+
+	logger.Debugf("link cable connected to %s", conn.RemoteAddr())
+	return c
+}
+
+// Close unplugs the cable. Further transfers behave as if there was no peer.
+func (c *Cable) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return c.conn.Close()
+}
+
+// Transfer sends a byte clocked by this Game Boy and waits for the byte sent back by the peer, which answers once
+// it reaches the end of the current period.
+func (c *Cable) Transfer(out byte) byte {
+	c.sequence++
+	if err := c.send(message{kind: kindTransfer, number: c.sequence, data: out}); err != nil {
+		return disconnectedByte
+	}
+
+	for {
+		m, ok := c.receive()
+		if !ok {
+			return disconnectedByte
+		}
+
+		switch m.kind {
+		case kindReply:
+			if m.number == c.sequence {
+				return m.data
+			}
+		case kindTransfer:
+			// Both sides are driving the clock in the same period, so neither of them receives valid data
+			c.send(message{kind: kindReply, number: m.number, data: disconnectedByte})
+		case kindSync:
+			c.peerSyncs++
+		}
+	}
+}
+
+// Tick counts the machine cycles of the period. At the end of the period it answers the transfers clocked by the
+// peer and waits for the peer to finish the period too.
+func (c *Cable) Tick(out byte, waiting bool) (byte, bool) {
+	c.cycles++
+	if c.cycles < syncCycles {
+		return 0, false
+	}
+	c.cycles = 0
+
+	if err := c.send(message{kind: kindSync, number: c.period}); err != nil {
+		return 0, false
+	}
+	c.period++
+
+	// The peer waits for the answer to its transfers, so they are answered as they come. It can clock several
+	// in a period at the CGB fast clock, only the first one finds the game waiting.
+	var in byte
+	received := false
+	for c.peerSyncs != c.period {
+		m, ok := c.receive()
+		if !ok {
+			return 0, false // Unplugged, there's nobody to wait for
+		}
+
+		switch m.kind {
+		case kindTransfer:
+			answer := disconnectedByte
+			if waiting && !received {
+				answer, in, received = out, m.data, true
+			}
+			c.send(message{kind: kindReply, number: m.number, data: answer})
+		case kindSync:
+			c.peerSyncs++
+		}
+	}
+	return in, received
+}
+
+// receive waits for the next message from the peer. It returns false once the cable is unplugged.
+func (c *Cable) receive() (message, bool) {
+	select {
+	case m := <-c.incoming:
+		return m, true
+	case <-c.closed:
+		// Messages received before the connection was closed are still handled
+		select {
+		case m := <-c.incoming:
+			return m, true
+		default:
+			return message{}, false
+		}
+	}
+}
+
+func (c *Cable) send(m message) error {
+	var buf [messageSize]byte
+	buf[0] = m.kind
+	binary.BigEndian.PutUint32(buf[1:5], m.number)
+	buf[5] = m.data
+
+	_, err := c.conn.Write(buf[:])
+	return err
+}
+
+func (c *Cable) read() {
+	defer close(c.closed)
+
+	var buf [messageSize]byte
+	for {
+		if _, err := io.ReadFull(c.conn, buf[:]); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.logger.Debugf("link cable disconnected: %s", err)
+			}
+			return
+		}
+
+		m := message{
+			kind:   buf[0],
+			number: binary.BigEndian.Uint32(buf[1:5]),
+			data:   buf[5],
+		}
+		if m.kind != kindTransfer && m.kind != kindReply && m.kind != kindSync {
+			c.logger.Debugf("link cable received unknown message kind 0x%02X", m.kind)
+			continue
+		}
+
+		select {
+		case c.incoming <- m:
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package link
+
+import (
+	"github.com/mikeletux/goboy/pkg/log"
+	"net"
+	"testing"
+)
+
+// connectedCables returns both ends of a cable connected through localhost
+func connectedCables(t *testing.T) (*Cable, *Cable) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- conn
+	}()
+
+	master, err := Dial(listener.Addr().String(), &log.NilLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slave := newCable(<-accepted, &log.NilLogger{})
+
+	t.Cleanup(func() {
+		master.Close()
+		slave.Close()
+	})
+	return master, slave
+}
+
+// runCycles ticks a cable for the given machine cycles, calling transfer on the cycles it has an entry for, and
+// returns the cycle at which each externally clocked byte was received.
+func runCycles(cable *Cable, cycles int, waiting func(cycle int) bool, transfer map[int]func()) map[int]byte {
+	received := make(map[int]byte)
+	for cycle := 0; cycle < cycles; cycle++ {
+		if fn, ok := transfer[cycle]; ok {
+			fn()
+		}
+		if in, ok := cable.Tick(0x55, waiting(cycle)); ok {
+			received[cycle] = in
+		}
+	}
+	return received
+}
+
+func never(int) bool  { return false }
+func always(int) bool { return true }
+
+func TestTransfer(t *testing.T) {
+	master, slave := connectedCables(t)
+
+	slaveReceived := make(chan map[int]byte)
+	go func() {
+		slaveReceived <- runCycles(slave, 3*syncCycles, always, nil)
+	}()
+
+	var got byte
+	runCycles(master, 3*syncCycles, never, map[int]func(){
+		100: func() { got = master.Transfer(0x29) },
+	})
+
+	if got != 0x55 {
+		t.Errorf("expected master to receive %X got %X", 0x55, got)
+	}
+	// The slave handles the transfer at the end of the period it was clocked in
+	received := <-slaveReceived
+	if len(received) != 1 || received[syncCycles-1] != 0x29 {
+		t.Errorf("expected slave to receive %X on cycle %d got %v", 0x29, syncCycles-1, received)
+	}
+}
+
+func TestTransferSlaveNotReady(t *testing.T) {
+	master, slave := connectedCables(t)
+
+	// The slave only waits from the second period on, so the transfer of the first one is lost instead of
+	// being received late
+	slaveReceived := make(chan map[int]byte)
+	go func() {
+		slaveReceived <- runCycles(slave, 3*syncCycles, func(cycle int) bool { return cycle >= syncCycles }, nil)
+	}()
+
+	var got byte
+	runCycles(master, 3*syncCycles, never, map[int]func(){
+		100: func() { got = master.Transfer(0x29) },
+	})
+
+	if got != disconnectedByte {
+		t.Errorf("expected %X when the slave isn't ready, got %X", disconnectedByte, got)
+	}
+	if received := <-slaveReceived; len(received) != 0 {
+		t.Errorf("expected the slave not to receive the transfer got %v", received)
+	}
+}
+
+func TestTransferBothClocks(t *testing.T) {
+	master, slave := connectedCables(t)
+
+	slaveGot := make(chan byte)
+	go func() {
+		var got byte
+		runCycles(slave, 2*syncCycles, always, map[int]func(){
+			500: func() { got = slave.Transfer(0x42) },
+		})
+		slaveGot <- got
+	}()
+
+	var got byte
+	runCycles(master, 2*syncCycles, always, map[int]func(){
+		100: func() { got = master.Transfer(0x29) },
+	})
+
+	if got != disconnectedByte {
+		t.Errorf("expected master to receive %X when both sides clock, got %X", disconnectedByte, got)
+	}
+	if got := <-slaveGot; got != disconnectedByte {
+		t.Errorf("expected slave to receive %X when both sides clock, got %X", disconnectedByte, got)
+	}
+}
+
+func TestTransferDisconnected(t *testing.T) {
+	master, slave := connectedCables(t)
+	slave.Close()
+
+	if got := master.Transfer(0x29); got != disconnectedByte {
+		t.Errorf("expected %X when the peer is gone, got %X", disconnectedByte, got)
+	}
+	runCycles(master, 2*syncCycles, always, nil) // Doesn't wait for the peer
+}
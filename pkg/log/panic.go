@@ -0,0 +1,52 @@
+package log
+
+import "fmt"
+
+// FatalError is raised by PanicLogger instead of exiting.
+type FatalError string
+
+func (f FatalError) Error() string {
+	return string(f)
+}
+
+// PanicLogger forwards the debug logs to Logger, if set, and turns fatal ones into a FatalError panic. It is
+// used when the emulator runs inside another program, which recovers from the panic instead of exiting.
+type PanicLogger struct {
+	Logger Logger
+}
+
+func (p *PanicLogger) Debug(args ...any) {
+	if p.Logger != nil {
+		p.Logger.Debug(args...)
+	}
+}
+
+func (p *PanicLogger) Debugf(format string, args ...any) {
+	if p.Logger != nil {
+		p.Logger.Debugf(format, args...)
+	}
+}
+
+func (p *PanicLogger) Fatal(args ...any) { panic(FatalError(fmt.Sprint(args...))) }
+func (p *PanicLogger) Fatalf(format string, args ...any) {
+	panic(FatalError(fmt.Sprintf(format, args...)))
+}
+
+func (p *PanicLogger) Close() {
+	if p.Logger != nil {
+		p.Logger.Close()
+	}
+}
+
+// Recover turns a FatalError panic into an error. It must be deferred, other panics are not recovered:
+//
+//	defer log.Recover(&err)
+func Recover(err *error) {
+	if r := recover(); r != nil {
+		fatal, ok := r.(FatalError)
+		if !ok {
+			panic(r)
+		}
+		*err = fatal
+	}
+}
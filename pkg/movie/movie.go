@@ -0,0 +1,220 @@
+// Package movie records and replays the input of a game, frame by frame, to reproduce bugs and for tool
+// assisted speedruns. Replaying a movie on the same ROM gives the same frames, as the emulator is
+// deterministic.
+//
+// Movies are stored in this binary format, with little endian numbers:
+//
+//	Offset  Size  Contents
+//	0       4     Magic "GBMV"
+//	4       2     Format version, 1
+//	6       2     Flags, bit 0 is set if the movie starts from a save state instead of power on
+//	8       16    ROM title, from the cartridge header
+//	24      1     ROM header checksum
+//	25      2     ROM global checksum
+//	27      1     Reserved, 0
+//	28      4     Rerecord count
+//	32      4     Number of frames
+//	36      4     Save state length, 0 if the movie starts from power on
+//	40      ...   Save state, as written by goboy.Machine.SaveState
+//	...     ...   A byte per frame with the buttons held: bit 0 Right, 1 Left, 2 Up, 3 Down, 4 A, 5 B, 6 Select
+//	              and 7 Start
+//
+// Movies starting from power on start with a blank cartridge RAM, as when the ROM is loaded.
+package movie
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"io"
+	"os"
+)
+
+const (
+	magic   = "GBMV"
+	version = 1
+
+	flagFromState = 1 << 0
+)
+
+// header is the fixed size start of a movie file.
+type header struct {
+	Magic          [4]byte
+	Version        uint16
+	Flags          uint16
+	Title          [16]byte
+	HeaderChecksum byte
+	GlobalChecksum [2]byte
+	Reserved       byte
+	Rerecords      uint32
+	Frames         uint32
+	StateLength    uint32
+}
+
+// Movie is the input recorded for a ROM.
+type Movie struct {
+	// ROM the movie was recorded on
+	Title          [16]byte
+	HeaderChecksum byte
+	GlobalChecksum [2]byte
+
+	// Rerecords counts how many times the movie was recorded again from the middle.
+	Rerecords uint32
+	// State is the save state the movie starts from, nil to start from power on.
+	State []byte
+	// Frames are the buttons held in each frame.
+	Frames []goboy.Buttons
+}
+
+// New returns an empty movie for the ROM of the machine. If fromState is set the movie starts from the current
+// state of the machine, otherwise from power on.
+func New(machine *goboy.Machine, fromState bool) (*Movie, error) {
+	cartridgeHeader := machine.Cartridge().CartridgeHeader
+	m := &Movie{
+		Title:          cartridgeHeader.Title,
+		HeaderChecksum: cartridgeHeader.HeaderCheckSum,
+		GlobalChecksum: cartridgeHeader.GlobalChecksum,
+	}
+
+	if fromState {
+		var state bytes.Buffer
+		if err := machine.SaveState(&state); err != nil {
+			return nil, err
+		}
+		m.State = state.Bytes()
+	}
+	return m, nil
+}
+
+// CheckRom returns an error describing the difference if the machine runs another ROM than the one the movie was
+// recorded on. The movie can still be played, but it will most likely desync.
+func (m *Movie) CheckRom(machine *goboy.Machine) error {
+	cartridgeHeader := machine.Cartridge().CartridgeHeader
+	if m.Title != cartridgeHeader.Title {
+		return fmt.Errorf("movie was recorded on %q but the ROM is %q", title(m.Title), title(cartridgeHeader.Title))
+	}
+	if m.HeaderChecksum != cartridgeHeader.HeaderCheckSum || m.GlobalChecksum != cartridgeHeader.GlobalChecksum {
+		return fmt.Errorf("movie was recorded on another version of %q, the ROM checksums differ", title(m.Title))
+	}
+	return nil
+}
+
+func title(raw [16]byte) string {
+	return string(bytes.TrimRight(raw[:], "\x00"))
+}
+
+// Start puts the machine in the state the movie starts from. A machine that hasn't run yet is not reset, so the
+// tracers and devices plugged to it are kept.
+func (m *Movie) Start(machine *goboy.Machine) error {
+	if m.State != nil {
+		return machine.LoadState(bytes.NewReader(m.State))
+	}
+
+	if machine.Cycles() != 0 {
+		machine.Reset()
+	}
+	cartridge := machine.Cartridge()
+	state := cartridge.State()
+	state.Ram = make([]byte, len(state.Ram))
+	return cartridge.LoadState(state)
+}
+
+// Write writes the movie in the binary format.
+func (m *Movie) Write(w io.Writer) error {
+	h := header{
+		Version:        version,
+		Title:          m.Title,
+		HeaderChecksum: m.HeaderChecksum,
+		GlobalChecksum: m.GlobalChecksum,
+		Rerecords:      m.Rerecords,
+		Frames:         uint32(len(m.Frames)),
+		StateLength:    uint32(len(m.State)),
+	}
+	copy(h.Magic[:], magic)
+	if m.State != nil {
+		h.Flags |= flagFromState
+	}
+
+	buffered := bufio.NewWriter(w)
+	binary.Write(buffered, binary.LittleEndian, h)
+	buffered.Write(m.State)
+	for _, buttons := range m.Frames {
+		buffered.WriteByte(byte(buttons))
+	}
+	return buffered.Flush()
+}
+
+// Read reads a movie in the binary format.
+func Read(r io.Reader) (*Movie, error) {
+	var h header
+	if err := binary.Read(r, binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("error reading movie header: %w", err)
+	}
+	if string(h.Magic[:]) != magic {
+		return nil, errors.New("not a movie file")
+	}
+	if h.Version != version {
+		return nil, fmt.Errorf("movie version %d is not supported, expected %d", h.Version, version)
+	}
+
+	m := &Movie{
+		Title:          h.Title,
+		HeaderChecksum: h.HeaderChecksum,
+		GlobalChecksum: h.GlobalChecksum,
+		Rerecords:      h.Rerecords,
+	}
+
+	if h.Flags&flagFromState != 0 {
+		state, err := readBytes(r, h.StateLength)
+		if err != nil {
+			return nil, fmt.Errorf("error reading movie state: %w", err)
+		}
+		m.State = state
+	}
+
+	frames, err := readBytes(r, h.Frames)
+	if err != nil {
+		return nil, fmt.Errorf("error reading movie frames: %w", err)
+	}
+	m.Frames = make([]goboy.Buttons, len(frames))
+	for i, buttons := range frames {
+		m.Frames[i] = goboy.Buttons(buttons)
+	}
+	return m, nil
+}
+
+// readBytes reads length bytes. Memory is allocated as they are read, so a corrupt length doesn't allocate
+// gigabytes.
+func readBytes(r io.Reader, length uint32) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err == nil && len(data) != int(length) {
+		err = io.ErrUnexpectedEOF
+	}
+	return data, err
+}
+
+// Load reads a movie file.
+func Load(path string) (*Movie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(bufio.NewReader(file))
+}
+
+// Save writes a movie file.
+func (m *Movie) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,139 @@
+package movie
+
+import (
+	"bytes"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/test"
+	"testing"
+)
+
+// sumProgram adds the action buttons read from P1 to C000 forever:
+// JP 0150 ; LD A,10 ; LDH (00),A ; LDH A,(00) ; LD HL,C000 ; ADD A,(HL) ; LD (HL),A ; JR 0150
+var sumProgram = map[uint16][]byte{
+	0x100: {0xC3, 0x50, 0x01},
+	0x150: {0x3E, 0x10, 0xE0, 0x00, 0xF0, 0x00, 0x21, 0x00, 0xC0, 0x86, 0x77, 0x18, 0xF3},
+}
+
+func newTestMachine(t *testing.T, title string) *goboy.Machine {
+	machine, err := goboy.New(test.Rom(title, sumProgram), goboy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return machine
+}
+
+// runSession runs the machine as frontend.Run does, with the player pressing the given buttons.
+func runSession(t *testing.T, machine *goboy.Machine, session *Session, input []goboy.Buttons) {
+	for _, buttons := range input {
+		machine.SetButtons(session.Frame(buttons))
+		if err := machine.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var testInput = []goboy.Buttons{0, goboy.ButtonA, goboy.ButtonA | goboy.ButtonB, 0, goboy.ButtonStart, 0,
+	goboy.ButtonSelect, goboy.ButtonA}
+
+func TestReplay(t *testing.T) {
+	for _, fromState := range []bool{false, true} {
+		machine := newTestMachine(t, "SUM")
+		if fromState {
+			machine.SetButtons(goboy.ButtonB)
+			machine.RunFrame()
+		}
+
+		movie, err := New(machine, fromState)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session, err := Record(machine, movie)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runSession(t, machine, session, testInput)
+		expectedSum, expectedCycles := machine.Bus().Peek(0xC000), machine.Cycles()
+
+		var file bytes.Buffer
+		if err := movie.Write(&file); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Read(&file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		idle := newTestMachine(t, "SUM")
+		movie.Start(idle)
+		for range testInput {
+			idle.RunFrame()
+		}
+		if idle.Bus().Peek(0xC000) == expectedSum {
+			t.Fatal("expected the input to change the sum")
+		}
+
+		// The player's input is ignored whilst playing
+		replay := newTestMachine(t, "SUM")
+		session, err = Play(replay, loaded, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runSession(t, replay, session, make([]goboy.Buttons, len(testInput)))
+
+		if sum, cycles := replay.Bus().Peek(0xC000), replay.Cycles(); sum != expectedSum || cycles != expectedCycles {
+			t.Errorf("from state %t: expected sum %02X after %d cycles, got %02X after %d", fromState, expectedSum,
+				expectedCycles, sum, cycles)
+		}
+
+		if buttons := session.Frame(goboy.ButtonUp); session.Mode() != Finished || buttons != goboy.ButtonUp {
+			t.Errorf("expected the player to take over once the movie ends, mode %s", session.Mode())
+		}
+	}
+}
+
+func TestRerecord(t *testing.T) {
+	machine := newTestMachine(t, "SUM")
+	movie, _ := New(machine, false)
+	movie.Frames = append(movie.Frames, testInput...)
+
+	session, err := Play(machine, movie, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runSession(t, machine, session, []goboy.Buttons{0, 0, goboy.ButtonDown, goboy.ButtonUp})
+
+	if session.Mode() != Recording || movie.Rerecords != 1 {
+		t.Fatalf("expected to record again, mode %s with %d rerecords", session.Mode(), movie.Rerecords)
+	}
+	expected := append(append([]goboy.Buttons{}, testInput[:2]...), goboy.ButtonDown, goboy.ButtonUp)
+	if len(movie.Frames) != len(expected) || movie.Frames[2] != goboy.ButtonDown || movie.Frames[3] != goboy.ButtonUp {
+		t.Errorf("expected frames %v got %v", expected, movie.Frames)
+	}
+}
+
+func TestCheckRom(t *testing.T) {
+	movie, _ := New(newTestMachine(t, "SUM"), false)
+
+	if err := movie.CheckRom(newTestMachine(t, "SUM")); err != nil {
+		t.Error(err)
+	}
+	if err := movie.CheckRom(newTestMachine(t, "OTHER")); err == nil {
+		t.Error("expected an error checking another ROM")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	movie, _ := New(newTestMachine(t, "SUM"), false)
+	movie.Frames = testInput
+
+	var file bytes.Buffer
+	movie.Write(&file)
+	data := file.Bytes()
+
+	if _, err := Read(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("expected an error reading a truncated movie")
+	}
+	if _, err := Read(bytes.NewReader(append([]byte("NOPE"), data[4:]...))); err == nil {
+		t.Error("expected an error reading another file")
+	}
+}
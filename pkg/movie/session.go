@@ -0,0 +1,117 @@
+package movie
+
+import (
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"sync"
+)
+
+// Mode is what a Session does with the buttons of each frame.
+type Mode int
+
+const (
+	// Recording appends the buttons pressed to the movie.
+	Recording Mode = iota
+	// Playing replaces the buttons pressed with the ones of the movie.
+	Playing
+	// Finished lets the buttons pressed through, once a read-only movie has been played.
+	Finished
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Recording:
+		return "recording"
+	case Playing:
+		return "playing"
+	default:
+		return "finished"
+	}
+}
+
+// Session records or plays a movie on a machine. Frame must be called before each frame is run, Save can be
+// called from other goroutines.
+type Session struct {
+	mutex    sync.Mutex
+	movie    *Movie
+	mode     Mode
+	readOnly bool
+	frame    int // Next frame of the movie
+}
+
+// Record starts recording the movie from its start, which replaces its frames.
+func Record(machine *goboy.Machine, movie *Movie) (*Session, error) {
+	if err := movie.Start(machine); err != nil {
+		return nil, err
+	}
+	movie.Frames = nil
+	return &Session{movie: movie, mode: Recording}, nil
+}
+
+// Play starts playing the movie. Read-only movies are played until the end and then the player takes over.
+// Otherwise, the movie is recorded again from the frame where the player presses a button, or from its end.
+func Play(machine *goboy.Machine, movie *Movie, readOnly bool) (*Session, error) {
+	if err := movie.Start(machine); err != nil {
+		return nil, err
+	}
+	return &Session{movie: movie, mode: Playing, readOnly: readOnly}, nil
+}
+
+// Frame returns the buttons to press in the next frame, given the ones pressed by the player.
+func (s *Session) Frame(buttons goboy.Buttons) goboy.Buttons {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch s.mode {
+	case Finished:
+		return buttons
+	case Playing:
+		if s.frame < len(s.movie.Frames) && (s.readOnly || buttons == 0) {
+			s.frame++
+			return s.movie.Frames[s.frame-1]
+		}
+
+		if s.readOnly {
+			s.mode = Finished
+			return buttons
+		}
+		if s.frame < len(s.movie.Frames) {
+			s.movie.Frames = s.movie.Frames[:s.frame]
+			s.movie.Rerecords++
+		}
+		s.mode = Recording
+	}
+
+	s.movie.Frames = append(s.movie.Frames, buttons)
+	s.frame++
+	return buttons
+}
+
+// Mode returns what the session is doing.
+func (s *Session) Mode() Mode {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.mode
+}
+
+// Save writes the movie file, with the frames recorded so far.
+func (s *Session) Save(path string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.movie.Save(path)
+}
+
+// Wrap returns a frontend that records or plays the movie with the buttons polled from f.
+func (s *Session) Wrap(f frontend.Frontend) frontend.Frontend {
+	return &sessionFrontend{Frontend: f, session: s}
+}
+
+type sessionFrontend struct {
+	frontend.Frontend
+	session *Session
+}
+
+func (f *sessionFrontend) Poll() (goboy.Buttons, bool) {
+	buttons, quit := f.Frontend.Poll()
+	return f.session.Frame(buttons), quit
+}
@@ -0,0 +1,131 @@
+// Package overlay draws text and shapes over the frames, like the speed indicator and what Lua scripts draw.
+package overlay
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Canvas is a frame being drawn on. Pixels are stored row by row as 0xAARRGGBB.
+type Canvas struct {
+	Pixels []uint32
+	Width  int
+	Height int
+}
+
+// Set blends a pixel using the alpha of the color. Pixels out of the frame are ignored.
+func (c *Canvas) Set(x, y int, color uint32) {
+	if x < 0 || y < 0 || x >= c.Width || y >= c.Height {
+		return
+	}
+
+	alpha := color >> 24
+	if alpha == 0 {
+		return
+	}
+
+	i := y*c.Width + x
+	if alpha == 0xFF {
+		c.Pixels[i] = color
+		return
+	}
+
+	under := c.Pixels[i]
+	blended := uint32(0xFF000000)
+	for shift := 0; shift < 24; shift += 8 {
+		top, bottom := color>>shift&0xFF, under>>shift&0xFF
+		blended |= (top*alpha + bottom*(0xFF-alpha)) / 0xFF << shift
+	}
+	c.Pixels[i] = blended
+}
+
+// Box draws a box with its outline, from corner to corner.
+func (c *Canvas) Box(x1, y1, x2, y2 int, fill, outline uint32) {
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			if x == x1 || x == x2 || y == y1 || y == y2 {
+				c.Set(x, y, outline)
+			} else {
+				c.Set(x, y, fill)
+			}
+		}
+	}
+}
+
+// Line draws a line with Bresenham's algorithm.
+func (c *Canvas) Line(x1, y1, x2, y2 int, color uint32) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		c.Set(x1, y1, color)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y1 += sy
+		}
+	}
+}
+
+// Text draws each character in a cell one pixel larger than the glyphs, filled with the background. The cell
+// of the first character starts at x-1, y-1. Newlines start a new line below.
+func (c *Canvas) Text(x, y int, text string, color, background uint32) {
+	left := x
+	for _, char := range text {
+		if char == '\n' {
+			x, y = left, y+GlyphHeight+1
+			continue
+		}
+
+		g := glyph(char)
+		for row := -1; row < GlyphHeight; row++ {
+			for column := -1; column < GlyphWidth; column++ {
+				if row >= 0 && column >= 0 && pixelSet(g, column, row) {
+					c.Set(x+column, y+row, color)
+				} else {
+					c.Set(x+column, y+row, background)
+				}
+			}
+		}
+		x += GlyphWidth + 1
+	}
+}
+
+// TextWidth returns the width of the widest line of the text drawn by Text, without the background border.
+func TextWidth(text string) int {
+	widest := 0
+	for _, line := range strings.Split(text, "\n") {
+		if width := utf8.RuneCountInString(line)*(GlyphWidth+1) - 1; width > widest {
+			widest = width
+		}
+	}
+	return widest
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,42 @@
+package overlay
+
+// Glyphs are 3x5 pixels. Each row is an octal digit, the leftmost pixel being the highest bit, so 'A' is
+// 010 101 111 101 101.
+const (
+	GlyphWidth  = 3
+	GlyphHeight = 5
+)
+
+var font = map[rune]uint16{
+	'0': 0o75557, '1': 0o26227, '2': 0o71747, '3': 0o71717, '4': 0o55711,
+	'5': 0o74717, '6': 0o74757, '7': 0o71122, '8': 0o75757, '9': 0o75717,
+	'A': 0o25755, 'B': 0o65656, 'C': 0o34443, 'D': 0o65556, 'E': 0o74647,
+	'F': 0o74644, 'G': 0o34553, 'H': 0o55755, 'I': 0o72227, 'J': 0o11152,
+	'K': 0o55655, 'L': 0o44447, 'M': 0o57755, 'N': 0o65555, 'O': 0o25552,
+	'P': 0o65644, 'Q': 0o25563, 'R': 0o65655, 'S': 0o34216, 'T': 0o72222,
+	'U': 0o55557, 'V': 0o55552, 'W': 0o55775, 'X': 0o55255, 'Y': 0o55222,
+	'Z': 0o71247,
+	' ': 0o00000, '!': 0o22202, '"': 0o55000, '#': 0o57575, '$': 0o36236,
+	'%': 0o51245, '\'': 0o22000, '(': 0o12221, ')': 0o42224, '*': 0o05250,
+	'+': 0o02720, ',': 0o00024, '-': 0o00700, '.': 0o00002, '/': 0o11244,
+	':': 0o02020, ';': 0o02024, '<': 0o12421, '=': 0o07070, '>': 0o42124,
+	'?': 0o71302, '[': 0o32223, ']': 0o62226, '_': 0o00007,
+}
+
+// glyph returns the glyph of a character. Lowercase letters use the uppercase glyphs and unknown characters
+// are shown as '?'.
+func glyph(char rune) uint16 {
+	if char >= 'a' && char <= 'z' {
+		char -= 'a' - 'A'
+	}
+	if g, ok := font[char]; ok {
+		return g
+	}
+	return font['?']
+}
+
+// pixelSet returns whether the pixel at column x and row y of a glyph is set.
+func pixelSet(g uint16, x, y int) bool {
+	row := g >> (3 * (GlyphHeight - 1 - y)) & 7
+	return row&(1<<(GlyphWidth-1-x)) != 0
+}
@@ -0,0 +1,56 @@
+package ppu
+
+const (
+	cgbPaletteAttrMask       = 0b111
+	vramBankAttrFlagBitPos   = 3
+	bgPriorityAttrFlagBitPos = 7
+	bgXFlipAttrFlagBitPos    = 5
+	bgYFlipAttrFlagBitPos    = 6
+)
+
+// BgMapAttributes models the CGB BG map attributes stored in VRAM bank 1. Each byte refers to the tile
+// index stored in the same position of VRAM bank 0.
+type BgMapAttributes struct {
+	/*
+			attributesFlag
+		 Bit7   BG-to-OAM Priority (0=Use OAM Priority bit, 1=BG Priority)
+		 Bit6   Vertical Flip      (0=Normal, 1=Mirror vertically)
+		 Bit5   Horizontal Flip    (0=Normal, 1=Mirror horizontally)
+		 Bit4   Not used
+		 Bit3   Tile VRAM Bank     (0=Bank 0, 1=Bank 1)
+		 Bit2-0 Background Palette number (BGP0-7)
+	*/
+	attributesFlag byte
+}
+
+// NewBgMapAttributes returns the BG map attributes given the byte read from VRAM bank 1.
+func NewBgMapAttributes(attributesFlag byte) BgMapAttributes {
+	return BgMapAttributes{attributesFlag: attributesFlag}
+}
+
+func (b *BgMapAttributes) GetCGBPalette() byte {
+	return b.attributesFlag & cgbPaletteAttrMask
+}
+
+func (b *BgMapAttributes) GetVramBank() byte {
+	if b.getBitAttributesFlag(vramBankAttrFlagBitPos) {
+		return 1
+	}
+	return 0
+}
+
+func (b *BgMapAttributes) GetXFlip() bool {
+	return b.getBitAttributesFlag(bgXFlipAttrFlagBitPos)
+}
+
+func (b *BgMapAttributes) GetYFlip() bool {
+	return b.getBitAttributesFlag(bgYFlipAttrFlagBitPos)
+}
+
+func (b *BgMapAttributes) GetPriority() bool {
+	return b.getBitAttributesFlag(bgPriorityAttrFlagBitPos)
+}
+
+func (b *BgMapAttributes) getBitAttributesFlag(position int) bool {
+	return (b.attributesFlag>>position)&1 == 1
+}
@@ -0,0 +1,63 @@
+package ppu
+
+import (
+	"testing"
+)
+
+func TestBgMapAttributes(t *testing.T) {
+	testCases := []struct {
+		attributesFlag byte
+		palette        byte
+		vramBank       byte
+		xFlip          bool
+		yFlip          bool
+		priority       bool
+	}{
+		{attributesFlag: 0b00000000, palette: 0, vramBank: 0},
+		{attributesFlag: 0b00000101, palette: 5, vramBank: 0},
+		{attributesFlag: 0b00001111, palette: 7, vramBank: 1},
+		{attributesFlag: 0b00100000, xFlip: true},
+		{attributesFlag: 0b01000000, yFlip: true},
+		{attributesFlag: 0b10000010, palette: 2, priority: true},
+	}
+
+	for _, v := range testCases {
+		attributes := NewBgMapAttributes(v.attributesFlag)
+
+		if attributes.GetCGBPalette() != v.palette {
+			t.Errorf("%08b: expected palette %d got %d", v.attributesFlag, v.palette, attributes.GetCGBPalette())
+		}
+		if attributes.GetVramBank() != v.vramBank {
+			t.Errorf("%08b: expected VRAM bank %d got %d", v.attributesFlag, v.vramBank, attributes.GetVramBank())
+		}
+		if attributes.GetXFlip() != v.xFlip {
+			t.Errorf("%08b: expected X flip %t got %t", v.attributesFlag, v.xFlip, attributes.GetXFlip())
+		}
+		if attributes.GetYFlip() != v.yFlip {
+			t.Errorf("%08b: expected Y flip %t got %t", v.attributesFlag, v.yFlip, attributes.GetYFlip())
+		}
+		if attributes.GetPriority() != v.priority {
+			t.Errorf("%08b: expected priority %t got %t", v.attributesFlag, v.priority, attributes.GetPriority())
+		}
+	}
+}
+
+func TestCgbColorToARGB(t *testing.T) {
+	testCases := []struct {
+		color    uint16
+		expected uint32
+	}{
+		{color: 0x0000, expected: 0xFF000000},
+		{color: 0x7FFF, expected: 0xFFFFFFFF},
+		{color: 0x001F, expected: 0xFFFF0000}, // Red
+		{color: 0x03E0, expected: 0xFF00FF00}, // Green
+		{color: 0x7C00, expected: 0xFF0000FF}, // Blue
+	}
+
+	for _, v := range testCases {
+		got := CgbColorToARGB(v.color)
+		if got != v.expected {
+			t.Errorf("color %04X: expected %08X got %08X", v.color, v.expected, got)
+		}
+	}
+}
@@ -23,6 +23,44 @@ type OamEntry struct {
 	attributesFlag byte
 }
 
+// NewOamEntry decodes the 4 bytes of an object in OAM.
+func NewOamEntry(y, x, tileIndex, attributesFlag byte) OamEntry {
+	return OamEntry{y: y, x: x, tileIndex: tileIndex, attributesFlag: attributesFlag}
+}
+
+// GetY returns the vertical position of the object plus 16, so 0 hides it above the screen.
+func (o *OamEntry) GetY() byte {
+	return o.y
+}
+
+// GetX returns the horizontal position of the object plus 8, so 0 hides it left of the screen.
+func (o *OamEntry) GetX() byte {
+	return o.x
+}
+
+// GetTileIndex returns the tile of the object, from 0x8000. 8x16 objects ignore bit 0.
+func (o *OamEntry) GetTileIndex() byte {
+	return o.tileIndex
+}
+
+// GetAttributesFlag returns the raw attributes byte.
+func (o *OamEntry) GetAttributesFlag() byte {
+	return o.attributesFlag
+}
+
+// GetCGBPalette returns the object palette number used in CGB mode (OBP0-7).
+func (o *OamEntry) GetCGBPalette() byte {
+	return o.attributesFlag & cgbPaletteAttrMask
+}
+
+// GetCGBVramBank returns the VRAM bank the object tile is read from in CGB mode.
+func (o *OamEntry) GetCGBVramBank() byte {
+	if o.getBitAttributesFlag(vramBankAttrFlagBitPos) {
+		return 1
+	}
+	return 0
+}
+
 func (o *OamEntry) GetDMGPalette() bool {
 	return o.getBitAttributesFlag(dmgPaletteAttrFlagBitPos)
 }
@@ -95,3 +95,38 @@ func TestOamEntryAttributesFlagPriority(t *testing.T) {
 		t.Error("oamEntry.GetPriority didn't get the needed bit")
 	}
 }
+
+func TestOamEntryAttributesFlagCGB(t *testing.T) {
+	oamEntry := OamEntry{
+		attributesFlag: 0b00001110,
+	}
+
+	if oamEntry.GetCGBPalette() != 6 {
+		t.Errorf("oamEntry.GetCGBPalette returned %d instead of 6", oamEntry.GetCGBPalette())
+	}
+	if oamEntry.GetCGBVramBank() != 1 {
+		t.Errorf("oamEntry.GetCGBVramBank returned %d instead of 1", oamEntry.GetCGBVramBank())
+	}
+
+	oamEntry.attributesFlag = 0b11110001
+	if oamEntry.GetCGBPalette() != 1 {
+		t.Errorf("oamEntry.GetCGBPalette returned %d instead of 1", oamEntry.GetCGBPalette())
+	}
+	if oamEntry.GetCGBVramBank() != 0 {
+		t.Errorf("oamEntry.GetCGBVramBank returned %d instead of 0", oamEntry.GetCGBVramBank())
+	}
+}
+
+func TestNewOamEntry(t *testing.T) {
+	oamEntry := NewOamEntry(0x10, 0x08, 0x3F, 0b00110000)
+
+	if oamEntry.GetY() != 0x10 || oamEntry.GetX() != 0x08 {
+		t.Errorf("NewOamEntry returned position %02X, %02X instead of 08, 10", oamEntry.GetX(), oamEntry.GetY())
+	}
+	if oamEntry.GetTileIndex() != 0x3F {
+		t.Errorf("NewOamEntry returned tile %02X instead of 3F", oamEntry.GetTileIndex())
+	}
+	if oamEntry.GetAttributesFlag() != 0b00110000 || !oamEntry.GetDMGPalette() || !oamEntry.GetXFlip() {
+		t.Errorf("NewOamEntry returned attributes %08b instead of 00110000", oamEntry.GetAttributesFlag())
+	}
+}
@@ -0,0 +1,34 @@
+package ppu
+
+// dmgColors are the four DMG shades: white, light grey, dark grey and black.
+var dmgColors = [4]uint32{0xFFFFFFFF, 0xFFAAAAAA, 0xFF555555, 0xFF000000}
+
+// dmgShade translates a color index through a DMG palette register (BGP, OBP0 or OBP1).
+func dmgShade(palette byte, colorIndex byte) byte {
+	return palette >> (colorIndex * 2) & 0b11
+}
+
+// DmgColor returns the color of a color index translated through a DMG palette register (BGP, OBP0 or OBP1).
+func DmgColor(palette byte, colorIndex byte) uint32 {
+	return dmgColors[dmgShade(palette, colorIndex)]
+}
+
+// CgbPaletteColor returns the color of a CGB palette given a function to read palette RAM.
+func CgbPaletteColor(readPaletteRam func(index byte) byte, palette byte, colorIndex byte) uint32 {
+	index := palette*8 + colorIndex*2
+	return CgbColorToARGB(uint16(readPaletteRam(index+1))<<8 | uint16(readPaletteRam(index)))
+}
+
+// CgbColorToARGB converts a CGB RGB555 color (Bit 0-4 red, Bit 5-9 green, Bit 10-14 blue) to 0xAARRGGBB.
+func CgbColorToARGB(color uint16) uint32 {
+	r := uint32(color & 0x1F)
+	g := uint32(color >> 5 & 0x1F)
+	b := uint32(color >> 10 & 0x1F)
+
+	// Scale 5 bits to 8 bits
+	r = r<<3 | r>>2
+	g = g<<3 | g>>2
+	b = b<<3 | b>>2
+
+	return 0xFF000000 | r<<16 | g<<8 | b
+}
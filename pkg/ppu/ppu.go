@@ -3,16 +3,227 @@ package ppu
 import (
 	"github.com/mikeletux/goboy/pkg/bus"
 	"github.com/mikeletux/goboy/pkg/log"
+	"sync"
+	"time"
+)
+
+const (
+	ScreenWidth  = 160
+	ScreenHeight = 144
+
+	// FrameCycles is the number of T-cycles the PPU takes to draw a frame at normal speed.
+	FrameCycles uint64 = scanlineDots * linesPerFrame
+	// FrameDuration is how long the PPU takes to draw a frame at 4194304Hz, a bit less than 1/60 seconds.
+	FrameDuration = time.Second * time.Duration(FrameCycles) / 4194304
+)
+
+// Register addresses
+const (
+	lcdControlRegisterAddr    uint16 = 0xFF40
+	lcdStatusRegisterAddr     uint16 = 0xFF41
+	scyRegisterAddr           uint16 = 0xFF42
+	scxRegisterAddr           uint16 = 0xFF43
+	lyRegisterAddr            uint16 = 0xFF44
+	lycRegisterAddr           uint16 = 0xFF45
+	bgpRegisterAddr           uint16 = 0xFF47
+	obp0RegisterAddr          uint16 = 0xFF48
+	obp1RegisterAddr          uint16 = 0xFF49
+	wyRegisterAddr            uint16 = 0xFF4A
+	wxRegisterAddr            uint16 = 0xFF4B
+	interruptFlagRegisterAddr uint16 = 0xFF0F
+)
+
+const (
+	vblankInterruptFlag  byte = 0x1
+	lcdStatInterruptFlag byte = 0x2
+)
+
+// PPU modes as reported in STAT bits 0-1
+const (
+	modeHBlank  byte = 0
+	modeVBlank  byte = 1
+	modeOamScan byte = 2
+	modeDrawing byte = 3
+)
+
+// Timings in dots
+const (
+	oamScanDots   = 80
+	drawingDots   = 172
+	scanlineDots  = 456
+	vblankLine    = ScreenHeight
+	linesPerFrame = 154
+)
+
+// STAT register bits
+const (
+	lycEqualsLyStatBitPos       = 2
+	hblankInterruptStatBitPos   = 3
+	vblankInterruptStatBitPos   = 4
+	oamScanInterruptStatBitPos  = 5
+	lycEqualsLyInterruptStatBit = 6
 )
 
 type PPU struct {
 	bus    bus.DataBusInterface
 	logger log.Logger
+
+	mode       byte
+	dot        int
+	ly         byte
+	windowLine byte // Internal window line counter, only increased when the window is drawn
+	statLine   bool // STAT interrupt is requested on the rising edge of this line
+
+	// backBuffer is where the current frame is being drawn whilst frontBuffer holds the last complete frame.
+	// Pixels are stored as 0xAARRGGBB.
+	backBuffer  [ScreenWidth * ScreenHeight]uint32
+	frontBuffer [ScreenWidth * ScreenHeight]uint32
+	// backShades and frontShades hold the DMG shade (0-3) of every pixel. They are used by the SGB to colorize
+	// the screen.
+	backShades  [ScreenWidth * ScreenHeight]byte
+	frontShades [ScreenWidth * ScreenHeight]byte
+	frameMutex  sync.Mutex
+	frames      uint64
 }
 
 func Init(bus bus.DataBusInterface, logger log.Logger) *PPU {
 	return &PPU{
 		bus:    bus,
 		logger: logger,
+		mode:   modeOamScan,
+	}
+}
+
+// Tick advances the PPU one dot.
+func (p *PPU) Tick() {
+	lcdc := p.bus.Peek(lcdControlRegisterAddr)
+	if lcdc&lcdcEnableBit == 0 {
+		if p.ly != 0 || p.dot != 0 || p.mode != modeHBlank { // LCD has just been turned off
+			p.ly, p.dot, p.windowLine = 0, 0, 0
+			p.setMode(modeHBlank)
+			p.bus.SetLcdLy(0)
+		}
+		return
+	}
+
+	p.dot++
+
+	switch p.mode {
+	case modeOamScan:
+		if p.dot == oamScanDots {
+			p.setMode(modeDrawing)
+		}
+
+	case modeDrawing:
+		if p.dot == oamScanDots+drawingDots {
+			p.renderScanline(lcdc)
+			p.setMode(modeHBlank)
+			p.bus.HdmaHBlank()
+		}
+
+	case modeHBlank:
+		if p.dot == scanlineDots {
+			p.dot = 0
+			p.setLy(p.ly + 1)
+
+			if p.ly == vblankLine {
+				p.setMode(modeVBlank)
+				p.requestInterrupt(vblankInterruptFlag)
+				p.swapBuffers()
+			} else {
+				p.setMode(modeOamScan)
+			}
+		}
+
+	case modeVBlank:
+		if p.dot == scanlineDots {
+			p.dot = 0
+
+			if p.ly+1 == linesPerFrame {
+				p.windowLine = 0
+				p.setLy(0)
+				p.setMode(modeOamScan)
+			} else {
+				p.setLy(p.ly + 1)
+			}
+		}
 	}
 }
+
+// Framebuffer returns a copy of the last complete frame. Each pixel is stored as 0xAARRGGBB.
+func (p *PPU) Framebuffer() []uint32 {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	frame := make([]uint32, len(p.frontBuffer))
+	copy(frame, p.frontBuffer[:])
+	return frame
+}
+
+// ShadeBuffer returns a copy of the DMG shades (0-3) of the last complete frame.
+func (p *PPU) ShadeBuffer() []byte {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	shades := make([]byte, len(p.frontShades))
+	copy(shades, p.frontShades[:])
+	return shades
+}
+
+// FrameCount returns how many frames have been completed since the PPU was initialized.
+func (p *PPU) FrameCount() uint64 {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	return p.frames
+}
+
+func (p *PPU) swapBuffers() {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	p.frontBuffer = p.backBuffer
+	p.frontShades = p.backShades
+	p.frames++
+}
+
+func (p *PPU) setLy(ly byte) {
+	p.ly = ly
+	p.bus.SetLcdLy(ly)
+	p.updateStat()
+}
+
+func (p *PPU) setMode(mode byte) {
+	p.mode = mode
+	p.updateStat()
+}
+
+// updateStat refreshes the read only bits from STAT and requests a STAT interrupt if any of the
+// enabled sources has just become active.
+func (p *PPU) updateStat() {
+	stat := p.bus.Peek(lcdStatusRegisterAddr)&^0b111 | p.mode
+	lycEqualsLy := p.ly == p.bus.Peek(lycRegisterAddr)
+	if lycEqualsLy {
+		stat |= 1 << lycEqualsLyStatBitPos
+	}
+	p.bus.SetLcdStatus(stat)
+
+	statLine := (lycEqualsLy && getBit(stat, lycEqualsLyInterruptStatBit)) ||
+		(p.mode == modeHBlank && getBit(stat, hblankInterruptStatBitPos)) ||
+		(p.mode == modeVBlank && getBit(stat, vblankInterruptStatBitPos)) ||
+		(p.mode == modeOamScan && getBit(stat, oamScanInterruptStatBitPos))
+
+	if statLine && !p.statLine {
+		p.requestInterrupt(lcdStatInterruptFlag)
+	}
+	p.statLine = statLine
+}
+
+func (p *PPU) requestInterrupt(interruptType byte) {
+	interrupts := p.bus.Peek(interruptFlagRegisterAddr)
+	p.bus.Poke(interruptFlagRegisterAddr, interrupts|interruptType)
+}
+
+func getBit(value byte, position int) bool {
+	return (value>>position)&1 == 1
+}
@@ -0,0 +1,225 @@
+package ppu
+
+import "github.com/mikeletux/goboy/pkg/bus"
+
+// LCDC register bits
+const (
+	lcdcBgWindowEnableBit   byte = 1 << 0 // In CGB mode it is the BG and window master priority instead
+	lcdcObjEnableBit        byte = 1 << 1
+	lcdcObjSizeBit          byte = 1 << 2
+	lcdcBgTileMapBit        byte = 1 << 3
+	lcdcBgWindowTileDataBit byte = 1 << 4
+	lcdcWindowEnableBit     byte = 1 << 5
+	lcdcWindowTileMapBit    byte = 1 << 6
+	lcdcEnableBit           byte = 1 << 7
+)
+
+const (
+	tileMap0Addr          uint16 = 0x9800
+	tileMap1Addr          uint16 = 0x9C00
+	tileData0Addr         uint16 = 0x8000
+	tileData2Addr         uint16 = 0x9000 // Base address for signed tile indexes
+	tileSize              uint16 = 16
+	maxObjectsPerScanline        = 10
+	objectsInOam                 = 40
+)
+
+// scanline holds the line being drawn along with the background information needed to resolve objects priority.
+type scanline struct {
+	line   []uint32
+	shades []byte // Only used in DMG mode
+
+	bgColorIndex [ScreenWidth]byte
+	bgPriority   [ScreenWidth]bool // CGB BG map attribute priority bit
+}
+
+// renderScanline draws the current line into the back buffer.
+func (p *PPU) renderScanline(lcdc byte) {
+	if p.ly >= ScreenHeight {
+		return
+	}
+
+	cgb := p.bus.IsCgbMode()
+	start, end := int(p.ly)*ScreenWidth, (int(p.ly)+1)*ScreenWidth
+	info := &scanline{
+		line:   p.backBuffer[start:end],
+		shades: p.backShades[start:end],
+	}
+
+	p.renderBackground(lcdc, cgb, info)
+
+	if lcdc&lcdcObjEnableBit != 0 {
+		p.renderObjects(lcdc, cgb, info)
+	}
+}
+
+func (p *PPU) renderBackground(lcdc byte, cgb bool, info *scanline) {
+	if !cgb && lcdc&lcdcBgWindowEnableBit == 0 { // On DMG BG and window are blank
+		for x := range info.line {
+			info.setDmgPixel(x, 0)
+		}
+		return
+	}
+
+	scx := p.bus.Peek(scxRegisterAddr)
+	scy := p.bus.Peek(scyRegisterAddr)
+	wy := p.bus.Peek(wyRegisterAddr)
+	wx := int(p.bus.Peek(wxRegisterAddr)) - 7
+	bgp := p.bus.Peek(bgpRegisterAddr)
+
+	windowVisible := lcdc&lcdcWindowEnableBit != 0 && wy <= p.ly && wx < ScreenWidth
+
+	for x := 0; x < ScreenWidth; x++ {
+		var mapAddr uint16
+		var pixelX, pixelY byte
+
+		if windowVisible && x >= wx {
+			mapAddr = tileMap0Addr
+			if lcdc&lcdcWindowTileMapBit != 0 {
+				mapAddr = tileMap1Addr
+			}
+			pixelX, pixelY = byte(x-wx), p.windowLine
+		} else {
+			mapAddr = tileMap0Addr
+			if lcdc&lcdcBgTileMapBit != 0 {
+				mapAddr = tileMap1Addr
+			}
+			pixelX, pixelY = byte(x)+scx, p.ly+scy
+		}
+
+		mapAddr += uint16(pixelY/8)*32 + uint16(pixelX/8)
+		tileIndex := p.bus.ReadVRamBank(0, mapAddr)
+
+		var attributes BgMapAttributes
+		if cgb {
+			attributes = NewBgMapAttributes(p.bus.ReadVRamBank(1, mapAddr))
+		}
+
+		tileX, tileY := pixelX%8, pixelY%8
+		if attributes.GetXFlip() {
+			tileX = 7 - tileX
+		}
+		if attributes.GetYFlip() {
+			tileY = 7 - tileY
+		}
+
+		colorIndex := p.tilePixel(attributes.GetVramBank(), BgTileAddress(lcdc, tileIndex), tileX, tileY)
+		info.bgColorIndex[x] = colorIndex
+		info.bgPriority[x] = attributes.GetPriority()
+
+		if cgb {
+			info.line[x] = CgbPaletteColor(p.bus.ReadBgPaletteRam, attributes.GetCGBPalette(), colorIndex)
+		} else {
+			info.setDmgPixel(x, dmgShade(bgp, colorIndex))
+		}
+	}
+
+	if windowVisible {
+		p.windowLine++
+	}
+}
+
+func (p *PPU) renderObjects(lcdc byte, cgb bool, info *scanline) {
+	height := byte(8)
+	if lcdc&lcdcObjSizeBit != 0 {
+		height = 16
+	}
+
+	objects := p.scanObjects(height, cgb)
+	obp0 := p.bus.Peek(obp0RegisterAddr)
+	obp1 := p.bus.Peek(obp1RegisterAddr)
+	bgMasterPriority := !cgb || lcdc&lcdcBgWindowEnableBit != 0
+
+	for x := 0; x < ScreenWidth; x++ {
+		for _, object := range objects { // Objects are sorted by priority, first opaque pixel wins
+			objectX := int(object.x) - 8
+			if x < objectX || x >= objectX+8 {
+				continue
+			}
+
+			tileX := byte(x - objectX)
+			tileY := p.ly + 16 - object.y
+			if object.GetXFlip() {
+				tileX = 7 - tileX
+			}
+			if object.GetYFlip() {
+				tileY = height - 1 - tileY
+			}
+
+			tileIndex := object.tileIndex
+			if height == 16 {
+				tileIndex &= 0xFE
+			}
+
+			var bank byte
+			if cgb {
+				bank = object.GetCGBVramBank()
+			}
+
+			colorIndex := p.tilePixel(bank, tileData0Addr+uint16(tileIndex)*tileSize, tileX, tileY)
+			if colorIndex == 0 { // Color 0 is transparent for objects
+				continue
+			}
+
+			bgOverObject := object.GetPriority() || (cgb && info.bgPriority[x])
+			if !(bgMasterPriority && bgOverObject && info.bgColorIndex[x] != 0) {
+				if cgb {
+					info.line[x] = CgbPaletteColor(p.bus.ReadObjPaletteRam, object.GetCGBPalette(), colorIndex)
+				} else if object.GetDMGPalette() {
+					info.setDmgPixel(x, dmgShade(obp1, colorIndex))
+				} else {
+					info.setDmgPixel(x, dmgShade(obp0, colorIndex))
+				}
+			}
+			break
+		}
+	}
+}
+
+func (s *scanline) setDmgPixel(x int, shade byte) {
+	s.shades[x] = shade
+	s.line[x] = dmgColors[shade]
+}
+
+// scanObjects returns the objects that are visible in the current line sorted by priority.
+func (p *PPU) scanObjects(height byte, cgb bool) []OamEntry {
+	objects := make([]OamEntry, 0, maxObjectsPerScanline)
+
+	for i := uint16(0); i < objectsInOam && len(objects) < maxObjectsPerScanline; i++ {
+		address := bus.OamStart + i*4
+		object := NewOamEntry(p.bus.Peek(address), p.bus.Peek(address+1), p.bus.Peek(address+2),
+			p.bus.Peek(address+3))
+
+		if p.ly+16 >= object.y && p.ly+16 < object.y+height {
+			objects = append(objects, object)
+		}
+	}
+
+	if !cgb { // On DMG the object with smaller X has priority, OAM order is used on ties
+		for i := 1; i < len(objects); i++ {
+			for j := i; j > 0 && objects[j].x < objects[j-1].x; j-- {
+				objects[j], objects[j-1] = objects[j-1], objects[j]
+			}
+		}
+	}
+
+	return objects
+}
+
+// BgTileAddress returns the tile address for BG and window depending on the addressing mode set in LCDC.
+func BgTileAddress(lcdc byte, tileIndex byte) uint16 {
+	if lcdc&lcdcBgWindowTileDataBit != 0 {
+		return tileData0Addr + uint16(tileIndex)*tileSize
+	}
+	return uint16(int(tileData2Addr) + int(int8(tileIndex))*int(tileSize))
+}
+
+// tilePixel returns the color index of a pixel inside a tile. Each tile row takes two bytes, the first
+// one holds the least significant bit of each pixel color index.
+func (p *PPU) tilePixel(bank byte, tileAddress uint16, x, y byte) byte {
+	low := p.bus.ReadVRamBank(bank, tileAddress+uint16(y)*2)
+	high := p.bus.ReadVRamBank(bank, tileAddress+uint16(y)*2+1)
+	bit := 7 - x
+
+	return (high>>bit&1)<<1 | low>>bit&1
+}
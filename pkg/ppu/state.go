@@ -0,0 +1,52 @@
+package ppu
+
+// State is a snapshot of the PPU, used for save states.
+type State struct {
+	Mode       byte
+	Dot        int
+	Ly         byte
+	WindowLine byte
+	StatLine   bool
+
+	BackBuffer  [ScreenWidth * ScreenHeight]uint32
+	FrontBuffer [ScreenWidth * ScreenHeight]uint32
+	BackShades  [ScreenWidth * ScreenHeight]byte
+	FrontShades [ScreenWidth * ScreenHeight]byte
+	Frames      uint64
+}
+
+// State returns a snapshot of the PPU.
+func (p *PPU) State() State {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	return State{
+		Mode:        p.mode,
+		Dot:         p.dot,
+		Ly:          p.ly,
+		WindowLine:  p.windowLine,
+		StatLine:    p.statLine,
+		BackBuffer:  p.backBuffer,
+		FrontBuffer: p.frontBuffer,
+		BackShades:  p.backShades,
+		FrontShades: p.frontShades,
+		Frames:      p.frames,
+	}
+}
+
+// LoadState restores a snapshot taken with State.
+func (p *PPU) LoadState(state State) {
+	p.frameMutex.Lock()
+	defer p.frameMutex.Unlock()
+
+	p.mode = state.Mode
+	p.dot = state.Dot
+	p.ly = state.Ly
+	p.windowLine = state.WindowLine
+	p.statLine = state.StatLine
+	p.backBuffer = state.BackBuffer
+	p.frontBuffer = state.FrontBuffer
+	p.backShades = state.BackShades
+	p.frontShades = state.FrontShades
+	p.frames = state.Frames
+}
@@ -0,0 +1,121 @@
+package printer
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+const (
+	paperWidth  = 160
+	tilesPerRow = paperWidth / 8
+	tileSize    = 16
+
+	// feedHeight is the number of blank pixel lines for every line feed in the margins.
+	feedHeight = 16
+
+	// defaultPalette is used when the game sends a 0 palette, which some games do.
+	defaultPalette byte = 0xE4
+)
+
+// paperShades maps the 4 printer shades to gray levels, from white to black.
+var paperShades = [4]byte{0xFF, 0xAA, 0x55, 0x00}
+
+// paper is a printout. Consecutive PRINT commands without margins between them end up on the same paper,
+// since games print long images in several chunks.
+type paper struct {
+	file   string
+	pixels []byte // Gray levels, paperWidth pixels per line
+}
+
+func (p *paper) height() int {
+	return len(p.pixels) / paperWidth
+}
+
+func (p *paper) feed(lines int) {
+	for i := 0; i < lines*feedHeight*paperWidth; i++ {
+		p.pixels = append(p.pixels, paperShades[0])
+	}
+}
+
+// draw appends the image stored as tiles in data. Tiles are laid out in rows of 20 and use the same 2bpp
+// format than VRAM.
+func (p *paper) draw(data []byte, palette byte) {
+	tileRows := len(data) / (tilesPerRow * tileSize)
+	start := len(p.pixels)
+	p.pixels = append(p.pixels, make([]byte, tileRows*8*paperWidth)...)
+
+	for tile := 0; tile < tileRows*tilesPerRow; tile++ {
+		tileX := (tile % tilesPerRow) * 8
+		tileY := (tile / tilesPerRow) * 8
+
+		for y := 0; y < 8; y++ {
+			low := data[tile*tileSize+y*2]
+			high := data[tile*tileSize+y*2+1]
+
+			for x := 0; x < 8; x++ {
+				bit := 7 - x
+				colorIndex := (high>>bit&1)<<1 | low>>bit&1
+				shade := palette >> (colorIndex * 2) & 0x3
+				p.pixels[start+(tileY+y)*paperWidth+tileX+x] = paperShades[shade]
+			}
+		}
+	}
+}
+
+func (p *paper) save() error {
+	if p.height() == 0 {
+		return nil
+	}
+
+	img := image.NewGray(image.Rect(0, 0, paperWidth, p.height()))
+	copy(img.Pix, p.pixels)
+
+	f, err := os.Create(p.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}
+
+// print prints the image in the buffer. margins holds the line feeds before the image in the high nibble
+// and after it in the low nibble. palette maps color indexes to shades the same way BGP does.
+func (p *Printer) print(margins, palette byte) {
+	before := int(margins >> 4)
+	after := int(margins & 0xF)
+
+	if palette == 0 {
+		palette = defaultPalette
+	}
+
+	if p.paper == nil || before > 0 {
+		p.paper = &paper{file: p.nextFileName()}
+	}
+
+	p.paper.feed(before)
+	p.paper.draw(p.buffer, palette)
+	p.paper.feed(after)
+
+	if err := p.paper.save(); err != nil {
+		p.logger.Debugf("error saving printout %s: %s", p.paper.file, err)
+	}
+
+	if after > 0 { // Paper is cut
+		p.paper = nil
+	}
+}
+
+// nextFileName returns the first printout file name that doesn't exist yet in the output directory.
+func (p *Printer) nextFileName() string {
+	for {
+		p.printouts++
+		file := filepath.Join(p.outputDir, fmt.Sprintf("printout_%03d.png", p.printouts))
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return file
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package printer emulates the Game Boy Printer. It is plugged into the serial port and every printout is
+// written as a PNG image.
+package printer
+
+import (
+	"github.com/mikeletux/goboy/pkg/log"
+)
+
+// Packet commands
+const (
+	cmdInit   byte = 0x01
+	cmdPrint  byte = 0x02
+	cmdData   byte = 0x04
+	cmdBreak  byte = 0x08
+	cmdStatus byte = 0x0F
+)
+
+// Status bits reported at the end of every packet
+const (
+	statusChecksumError byte = 0x01
+	statusPrinting      byte = 0x02
+	statusImageFull     byte = 0x04
+	statusUnprocessed   byte = 0x08
+	statusPacketError   byte = 0x10
+)
+
+const (
+	magic1 byte = 0x88
+	magic2 byte = 0x33
+
+	// aliveByte is sent back by the printer to let the game know that it is connected.
+	aliveByte byte = 0x81
+
+	// bufferSize is the printer memory, 9 bands of 2 tile rows.
+	bufferSize = 0x2000
+
+	// printingPolls is the number of STATUS packets the printer reports being busy after a PRINT.
+	printingPolls = 4
+)
+
+// Packet reception states, in the order the bytes are received
+const (
+	stateMagic1 = iota
+	stateMagic2
+	stateCommand
+	stateCompression
+	stateLengthLow
+	stateLengthHigh
+	stateData
+	stateChecksumLow
+	stateChecksumHigh
+	stateAlive
+	stateStatus
+)
+
+// Printer emulates the Game Boy Printer. It implements bus.SerialDevice so it can be plugged into the bus.
+type Printer struct {
+	logger    log.Logger
+	outputDir string
+
+	// Packet reception
+	state      int
+	command    byte
+	compressed bool
+	length     uint16
+	data       []byte
+	sum        uint16 // Checksum computed from the received bytes
+	checksum   uint16 // Checksum sent by the game
+
+	status   byte
+	printing int    // STATUS packets left until the print is done
+	buffer   []byte // Tile data received through DATA packets
+
+	paper     *paper
+	printouts int
+}
+
+// New returns a printer that writes its printouts into outputDir.
+func New(outputDir string, logger log.Logger) *Printer {
+	return &Printer{
+		logger:    logger,
+		outputDir: outputDir,
+	}
+}
+
+// Transfer receives a byte of a packet from the Game Boy and returns the printer answer.
+func (p *Printer) Transfer(out byte) byte {
+	switch p.state {
+	case stateMagic1:
+		if out == magic1 {
+			p.state = stateMagic2
+		}
+
+	case stateMagic2:
+		p.state = stateMagic1
+		if out == magic2 {
+			p.state = stateCommand
+			p.sum = 0
+			p.data = p.data[:0]
+		}
+
+	case stateCommand:
+		p.command = out
+		p.sum += uint16(out)
+		p.state = stateCompression
+
+	case stateCompression:
+		p.compressed = out&0x1 != 0
+		p.sum += uint16(out)
+		p.state = stateLengthLow
+
+	case stateLengthLow:
+		p.length = uint16(out)
+		p.sum += uint16(out)
+		p.state = stateLengthHigh
+
+	case stateLengthHigh:
+		p.length |= uint16(out) << 8
+		p.sum += uint16(out)
+		p.state = stateData
+		if p.length == 0 {
+			p.state = stateChecksumLow
+		}
+
+	case stateData:
+		p.data = append(p.data, out)
+		p.sum += uint16(out)
+		if len(p.data) == int(p.length) {
+			p.state = stateChecksumLow
+		}
+
+	case stateChecksumLow:
+		p.checksum = uint16(out)
+		p.state = stateChecksumHigh
+
+	case stateChecksumHigh:
+		p.checksum |= uint16(out) << 8
+		p.state = stateAlive
+		p.handlePacket()
+
+	case stateAlive:
+		p.state = stateStatus
+		return aliveByte
+
+	case stateStatus:
+		p.state = stateMagic1
+		return p.status
+	}
+
+	return 0x00
+}
+
+// Tick always returns false since the printer never drives the serial clock.
+func (p *Printer) Tick(out byte, waiting bool) (byte, bool) {
+	return 0, false
+}
+
+func (p *Printer) handlePacket() {
+	if p.sum != p.checksum {
+		p.status |= statusChecksumError
+		return
+	}
+	p.status &^= statusChecksumError
+
+	switch p.command {
+	case cmdInit, cmdBreak:
+		p.buffer = p.buffer[:0]
+		p.printing = 0
+		p.status = 0
+
+	case cmdData:
+		data := p.data
+		if p.compressed {
+			data = decompress(data)
+		}
+
+		if len(p.buffer)+len(data) > bufferSize {
+			p.status |= statusPacketError
+			return
+		}
+		p.buffer = append(p.buffer, data...)
+
+		if len(data) > 0 {
+			p.status |= statusUnprocessed
+		} else { // An empty DATA packet marks the end of the image
+			p.status |= statusImageFull
+		}
+
+	case cmdPrint:
+		if len(p.data) != 4 {
+			p.status |= statusPacketError
+			return
+		}
+
+		p.print(p.data[1], p.data[2])
+		p.buffer = p.buffer[:0]
+		p.printing = printingPolls
+		p.status = p.status&^statusUnprocessed | statusPrinting | statusImageFull
+
+	case cmdStatus:
+		if p.printing > 0 {
+			p.printing--
+			if p.printing == 0 {
+				p.status &^= statusPrinting | statusImageFull
+			}
+		}
+
+	default:
+		p.status |= statusPacketError
+	}
+}
+
+// decompress expands the run length encoding used by DATA packets. A control byte with bit 7 set repeats
+// the next byte (control & 0x7F) + 2 times, otherwise the next (control + 1) bytes are copied as they are.
+func decompress(data []byte) []byte {
+	var out []byte
+
+	for i := 0; i < len(data); {
+		control := data[i]
+		i++
+
+		if control&0x80 != 0 {
+			if i >= len(data) {
+				break
+			}
+			for n := 0; n < int(control&0x7F)+2; n++ {
+				out = append(out, data[i])
+			}
+			i++
+			continue
+		}
+
+		end := i + int(control) + 1
+		if end > len(data) {
+			end = len(data)
+		}
+		out = append(out, data[i:end]...)
+		i = end
+	}
+
+	return out
+}
@@ -0,0 +1,124 @@
+package printer
+
+import (
+	"github.com/mikeletux/goboy/pkg/log"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sendPacket transfers a whole packet the same way games do and returns the alive and status bytes.
+func sendPacket(p *Printer, command byte, compressed bool, data []byte) (byte, byte) {
+	var compression byte
+	if compressed {
+		compression = 1
+	}
+
+	packet := []byte{magic1, magic2, command, compression, byte(len(data)), byte(len(data) >> 8)}
+	packet = append(packet, data...)
+
+	var sum uint16
+	for _, v := range packet[2:] {
+		sum += uint16(v)
+	}
+	packet = append(packet, byte(sum), byte(sum>>8))
+
+	for _, v := range packet {
+		if answer := p.Transfer(v); answer != 0 {
+			panic("printer answered before the end of the packet")
+		}
+	}
+
+	return p.Transfer(0), p.Transfer(0)
+}
+
+func TestDecompress(t *testing.T) {
+	compressed := []byte{0x81, 0xAA, 0x01, 0x01, 0x02}
+	expected := []byte{0xAA, 0xAA, 0xAA, 0x01, 0x02}
+
+	got := decompress(compressed)
+	if string(got) != string(expected) {
+		t.Errorf("expected %v got %v", expected, got)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	p := New(t.TempDir(), &log.NilLogger{})
+
+	if alive, status := sendPacket(p, cmdInit, false, nil); alive != aliveByte || status != 0 {
+		t.Errorf("INIT: expected alive %X status 0, got %X %X", aliveByte, alive, status)
+	}
+
+	if _, status := sendPacket(p, cmdData, false, make([]byte, 0x280)); status != statusUnprocessed {
+		t.Errorf("DATA: expected status %X got %X", statusUnprocessed, status)
+	}
+
+	p.Transfer(magic1)
+	p.Transfer(magic2)
+	for _, v := range []byte{cmdStatus, 0, 0, 0, 0xFF, 0xFF} { // Wrong checksum
+		p.Transfer(v)
+	}
+	p.Transfer(0)
+	if status := p.Transfer(0); status&statusChecksumError == 0 {
+		t.Errorf("expected checksum error, got status %X", status)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	dir := t.TempDir()
+	p := New(dir, &log.NilLogger{})
+
+	// One band of 2 tile rows. The first tile is color 3, the rest color 0. Compressed as runs of 128 bytes.
+	band := []byte{0x8E, 0xFF}
+	for i := 0; i < (0x280-16)/128; i++ {
+		band = append(band, 0xFE, 0x00)
+	}
+	band = append(band, 0xEE, 0x00) // Remaining 112 bytes
+
+	sendPacket(p, cmdInit, false, nil)
+	sendPacket(p, cmdData, true, band)
+	sendPacket(p, cmdData, false, nil)
+	_, status := sendPacket(p, cmdPrint, false, []byte{1, 0x01, 0xE4, 0x40})
+	if status&statusPrinting == 0 {
+		t.Errorf("expected printing status, got %X", status)
+	}
+
+	for i := 0; i < printingPolls; i++ {
+		_, status = sendPacket(p, cmdStatus, false, nil)
+	}
+	if status != 0 {
+		t.Errorf("expected printer to be done, got status %X", status)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "printout_001.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bounds := img.Bounds()
+	if bounds.Dx() != paperWidth || bounds.Dy() != 16+feedHeight {
+		t.Fatalf("expected %dx%d image, got %dx%d", paperWidth, 16+feedHeight, bounds.Dx(), bounds.Dy())
+	}
+
+	testCases := []struct {
+		x, y     int
+		expected uint32
+	}{
+		{x: 0, y: 0, expected: 0x0000},  // Color 3 is black
+		{x: 8, y: 0, expected: 0xFFFF},  // Color 0 is white
+		{x: 0, y: 20, expected: 0xFFFF}, // Margin after the image
+	}
+
+	for _, v := range testCases {
+		if r, _, _, _ := img.At(v.x, v.y).RGBA(); r != v.expected {
+			t.Errorf("pixel (%d,%d): expected %X got %X", v.x, v.y, v.expected, r)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package script
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/overlay"
+	lua "github.com/yuin/gopher-lua"
+	"strings"
+)
+
+// joypadKeys are the names of the buttons in the tables of joypad.set and joypad.get, as in FCEUX.
+var joypadKeys = []string{"A", "B", "select", "start", "up", "down", "left", "right"}
+
+func (s *Script) registerApi() {
+	L := s.state
+
+	memory := map[string]lua.LGFunction{
+		"readbyte":       s.readByte,
+		"readbytesigned": s.readByteSigned,
+		"readword":       s.readWord,
+		"writebyte":      s.writeByte,
+		"writeword":      s.writeWord,
+		"registerread":   s.registerCallback(s.readCallbacks),
+		"registerwrite":  s.registerCallback(s.writeCallbacks),
+		"registerexec":   s.registerCallback(s.execCallbacks),
+	}
+	// Aliases of BizHawk and the short names
+	memory["read"], memory["read_u8"], memory["read_s8"] = s.readByte, s.readByte, s.readByteSigned
+	memory["read_u16_le"] = s.readWord
+	memory["write"], memory["write_u8"], memory["write_u16_le"] = s.writeByte, s.writeByte, s.writeWord
+	L.SetGlobal("memory", L.SetFuncs(L.NewTable(), memory))
+
+	L.SetGlobal("joypad", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"set":   s.joypadSet,
+		"write": s.joypadSet,
+		"get":   s.joypadGet,
+		"read":  s.joypadGet,
+	}))
+
+	L.SetGlobal("emu", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"frameadvance": s.frameAdvance,
+		"framecount":   s.frameCount,
+		"getregister":  s.getRegister,
+		"setregister":  s.setRegister,
+		"getregisters": s.getRegisters,
+		"print":        s.print,
+	}))
+
+	L.SetGlobal("gui", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"text":      s.guiText,
+		"drawtext":  s.guiText,
+		"box":       s.guiBox,
+		"drawbox":   s.guiBox,
+		"line":      s.guiLine,
+		"drawline":  s.guiLine,
+		"pixel":     s.guiPixel,
+		"drawpixel": s.guiPixel,
+		"clear":     s.guiClear,
+	}))
+
+	L.SetGlobal("print", L.NewFunction(s.print))
+}
+
+func checkAddress(L *lua.LState, n int) uint16 {
+	address := L.CheckInt(n)
+	if address < 0 || address > 0xFFFF {
+		L.ArgError(n, fmt.Sprintf("address %X out of range", address))
+	}
+	return uint16(address)
+}
+
+// readByte reads memory without triggering the read callbacks.
+func (s *Script) readByte(L *lua.LState) int {
+	L.Push(lua.LNumber(s.machine.Bus().Peek(checkAddress(L, 1))))
+	return 1
+}
+
+func (s *Script) readByteSigned(L *lua.LState) int {
+	L.Push(lua.LNumber(int8(s.machine.Bus().Peek(checkAddress(L, 1)))))
+	return 1
+}
+
+// readWord reads a little endian 16 bit value.
+func (s *Script) readWord(L *lua.LState) int {
+	address := checkAddress(L, 1)
+	b := s.machine.Bus()
+	L.Push(lua.LNumber(uint16(b.Peek(address)) | uint16(b.Peek(address+1))<<8))
+	return 1
+}
+
+// writeByte writes memory as the CPU would, so writes to ROM addresses go to the cartridge controller.
+func (s *Script) writeByte(L *lua.LState) int {
+	s.machine.Bus().BusWrite(checkAddress(L, 1), byte(L.CheckInt(2)))
+	return 0
+}
+
+func (s *Script) writeWord(L *lua.LState) int {
+	address, value := checkAddress(L, 1), L.CheckInt(2)
+	s.machine.Bus().BusWrite(address, byte(value))
+	s.machine.Bus().BusWrite(address+1, byte(value>>8))
+	return 0
+}
+
+// registerCallback returns memory.register*(address, [size,] fn). Read and write callbacks get the address and
+// the value, exec callbacks the address. A nil fn removes the callbacks of the range.
+func (s *Script) registerCallback(callbacks map[uint16]*lua.LFunction) lua.LGFunction {
+	return func(L *lua.LState) int {
+		address, size, fnArg := checkAddress(L, 1), 1, 2
+		if L.Get(2).Type() == lua.LTNumber {
+			size, fnArg = L.CheckInt(2), 3
+		}
+		fn := L.OptFunction(fnArg, nil)
+
+		for i := 0; i < size && int(address)+i <= 0xFFFF; i++ {
+			if fn == nil {
+				delete(callbacks, address+uint16(i))
+			} else {
+				callbacks[address+uint16(i)] = fn
+			}
+		}
+		s.updateHooks()
+		return 0
+	}
+}
+
+// joypadSet changes the buttons of the next frame: true presses a button, false releases it and nil leaves the
+// player's input. The player number of FCEUX is accepted and ignored.
+func (s *Script) joypadSet(L *lua.LState) int {
+	n := 1
+	if L.Get(1).Type() == lua.LTNumber {
+		n = 2
+	}
+
+	L.CheckTable(n).ForEach(func(key, value lua.LValue) {
+		button, err := goboy.ParseButtons(key.String())
+		if err != nil {
+			L.ArgError(n, err.Error())
+		}
+		switch value {
+		case lua.LTrue:
+			s.pressed |= button
+			s.released &^= button
+		case lua.LFalse:
+			s.released |= button
+			s.pressed &^= button
+		}
+	})
+	return 0
+}
+
+// joypadGet returns the buttons pressed in the current frame.
+func (s *Script) joypadGet(L *lua.LState) int {
+	table := L.NewTable()
+	for _, key := range joypadKeys {
+		button, _ := goboy.ParseButtons(key)
+		table.RawSetString(key, lua.LBool(s.buttons&button != 0))
+	}
+	L.Push(table)
+	return 1
+}
+
+func (s *Script) frameAdvance(L *lua.LState) int {
+	if L != s.thread {
+		L.RaiseError("emu.frameadvance can only be called by the main script, not by callbacks")
+	}
+	return L.Yield()
+}
+
+func (s *Script) frameCount(L *lua.LState) int {
+	L.Push(lua.LNumber(s.machine.FrameCount()))
+	return 1
+}
+
+// register reads and writes a CPU register.
+type register struct {
+	get func(r *cpu.Registers) uint16
+	set func(r *cpu.Registers, value uint16)
+}
+
+// registerNames are the registers returned by emu.getregisters, in order.
+var registerNames = []string{"a", "f", "b", "c", "d", "e", "h", "l", "af", "bc", "de", "hl", "sp", "pc"}
+
+var registers = map[string]register{
+	"a":  {func(r *cpu.Registers) uint16 { return uint16(r.A) }, func(r *cpu.Registers, v uint16) { r.A = byte(v) }},
+	"f":  {func(r *cpu.Registers) uint16 { return uint16(r.F) }, func(r *cpu.Registers, v uint16) { r.F = byte(v) }},
+	"b":  {func(r *cpu.Registers) uint16 { return uint16(r.B) }, func(r *cpu.Registers, v uint16) { r.B = byte(v) }},
+	"c":  {func(r *cpu.Registers) uint16 { return uint16(r.C) }, func(r *cpu.Registers, v uint16) { r.C = byte(v) }},
+	"d":  {func(r *cpu.Registers) uint16 { return uint16(r.D) }, func(r *cpu.Registers, v uint16) { r.D = byte(v) }},
+	"e":  {func(r *cpu.Registers) uint16 { return uint16(r.E) }, func(r *cpu.Registers, v uint16) { r.E = byte(v) }},
+	"h":  {func(r *cpu.Registers) uint16 { return uint16(r.H) }, func(r *cpu.Registers, v uint16) { r.H = byte(v) }},
+	"l":  {func(r *cpu.Registers) uint16 { return uint16(r.L) }, func(r *cpu.Registers, v uint16) { r.L = byte(v) }},
+	"af": {(*cpu.Registers).GetAF, (*cpu.Registers).SetAF},
+	"bc": {(*cpu.Registers).GetBC, (*cpu.Registers).SetBC},
+	"de": {(*cpu.Registers).GetDE, (*cpu.Registers).SetDE},
+	"hl": {(*cpu.Registers).GetHL, (*cpu.Registers).SetHL},
+	"sp": {func(r *cpu.Registers) uint16 { return r.SP }, func(r *cpu.Registers, v uint16) { r.SP = v }},
+	"pc": {func(r *cpu.Registers) uint16 { return r.PC }, func(r *cpu.Registers, v uint16) { r.PC = v }},
+}
+
+func checkRegister(L *lua.LState, n int) register {
+	name := L.CheckString(n)
+	reg, ok := registers[strings.ToLower(name)]
+	if !ok {
+		L.ArgError(n, fmt.Sprintf("unknown register %q", name))
+	}
+	return reg
+}
+
+func (s *Script) getRegister(L *lua.LState) int {
+	reg := checkRegister(L, 1)
+	L.Push(lua.LNumber(reg.get(s.machine.CPU().Registers())))
+	return 1
+}
+
+// setRegister changes a register straight away, even in the middle of a frame from a callback.
+func (s *Script) setRegister(L *lua.LState) int {
+	reg := checkRegister(L, 1)
+	reg.set(s.machine.CPU().Registers(), uint16(L.CheckInt(2)))
+	return 0
+}
+
+func (s *Script) getRegisters(L *lua.LState) int {
+	table := L.NewTable()
+	for _, name := range registerNames {
+		table.RawSetString(name, lua.LNumber(registers[name].get(s.machine.CPU().Registers())))
+	}
+	L.Push(table)
+	return 1
+}
+
+// print writes its arguments separated by tabs, as the Lua print does, to the output of the script.
+func (s *Script) print(L *lua.LState) int {
+	args := make([]string, L.GetTop())
+	for i := range args {
+		args[i] = L.ToStringMeta(L.Get(i + 1)).String()
+	}
+	fmt.Fprintln(s.out, strings.Join(args, "\t"))
+	return 0
+}
+
+// checkColor returns the color argument n, or defaultColor if it is nil.
+func checkColor(L *lua.LState, n int, defaultColor uint32) uint32 {
+	switch value := L.Get(n).(type) {
+	case *lua.LNilType:
+		return defaultColor
+	case lua.LNumber:
+		return colorFromNumber(float64(value))
+	case lua.LString:
+		color, err := colorFromString(string(value))
+		if err != nil {
+			L.ArgError(n, err.Error())
+		}
+		return color
+	default:
+		L.ArgError(n, "expected a color")
+		return 0
+	}
+}
+
+// The gui functions record what to draw, which is drawn on the next frames until the script resumes.
+
+func (s *Script) guiText(L *lua.LState) int {
+	x, y, text := L.CheckInt(1), L.CheckInt(2), L.ToStringMeta(L.Get(3)).String()
+	color, background := checkColor(L, 4, defaultTextColor), checkColor(L, 5, defaultTextBackground)
+	s.drawings = append(s.drawings, func(c *overlay.Canvas) { c.Text(x, y, text, color, background) })
+	return 0
+}
+
+func (s *Script) guiBox(L *lua.LState) int {
+	x1, y1, x2, y2 := L.CheckInt(1), L.CheckInt(2), L.CheckInt(3), L.CheckInt(4)
+	fill, outline := checkColor(L, 5, defaultBoxFill), checkColor(L, 6, defaultBoxOutlineColor)
+	s.drawings = append(s.drawings, func(c *overlay.Canvas) { c.Box(x1, y1, x2, y2, fill, outline) })
+	return 0
+}
+
+func (s *Script) guiLine(L *lua.LState) int {
+	x1, y1, x2, y2 := L.CheckInt(1), L.CheckInt(2), L.CheckInt(3), L.CheckInt(4)
+	color := checkColor(L, 5, defaultShapeColor)
+	s.drawings = append(s.drawings, func(c *overlay.Canvas) { c.Line(x1, y1, x2, y2, color) })
+	return 0
+}
+
+func (s *Script) guiPixel(L *lua.LState) int {
+	x, y := L.CheckInt(1), L.CheckInt(2)
+	color := checkColor(L, 3, defaultShapeColor)
+	s.drawings = append(s.drawings, func(c *overlay.Canvas) { c.Set(x, y, color) })
+	return 0
+}
+
+func (s *Script) guiClear(L *lua.LState) int {
+	s.drawings = s.drawings[:0]
+	return 0
+}
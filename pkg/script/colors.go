@@ -0,0 +1,57 @@
+package script
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Colors are 0xAARRGGBB. Scripts can give them as numbers, 0xRRGGBB being opaque, as names or as "#RRGGBB" and
+// "#AARRGGBB" strings.
+var colorNames = map[string]uint32{
+	"clear":  0x00000000,
+	"white":  0xFFFFFFFF,
+	"black":  0xFF000000,
+	"gray":   0xFF808080,
+	"red":    0xFFFF0000,
+	"green":  0xFF00FF00,
+	"blue":   0xFF0000FF,
+	"yellow": 0xFFFFFF00,
+	"orange": 0xFFFF8000,
+	"purple": 0xFF8000FF,
+}
+
+const (
+	defaultTextColor       uint32 = 0xFFFFFFFF
+	defaultTextBackground  uint32 = 0xC0000000
+	defaultShapeColor      uint32 = 0xFFFFFFFF
+	defaultBoxFill         uint32 = 0x00000000
+	defaultBoxOutlineColor uint32 = 0xFFFFFFFF
+)
+
+func colorFromNumber(value float64) uint32 {
+	color := uint32(value)
+	if color <= 0xFFFFFF {
+		color |= 0xFF000000
+	}
+	return color
+}
+
+func colorFromString(s string) (uint32, error) {
+	if color, ok := colorNames[strings.ToLower(s)]; ok {
+		return color, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == len(s) || (len(hex) != 6 && len(hex) != 8) {
+		return 0, fmt.Errorf("invalid color %q", s)
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid color %q", s)
+	}
+	if len(hex) == 6 {
+		value |= 0xFF000000
+	}
+	return uint32(value), nil
+}
@@ -0,0 +1,219 @@
+// Package script runs Lua scripts alongside the emulator, for bots, auto-splitters and TAS tools. The API is
+// modelled on the ones of FCEUX and BizHawk so their scripts port easily:
+//
+//	memory.readbyte, memory.readword, memory.writebyte, memory.writeword, also as read_u8, read_u16_le...
+//	memory.registerwrite, memory.registerread and memory.registerexec
+//	joypad.set, joypad.get
+//	emu.frameadvance, emu.framecount, emu.getregister, emu.setregister, emu.getregisters
+//	gui.text, gui.box, gui.line, gui.pixel, gui.clear
+//
+// The script runs until it calls emu.frameadvance, then the emulator runs a frame and the script resumes. Its
+// callbacks keep being called after it returns.
+package script
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"github.com/mikeletux/goboy/pkg/overlay"
+	lua "github.com/yuin/gopher-lua"
+	"io"
+)
+
+// Script is a Lua script attached to a machine. It is not safe for concurrent use.
+type Script struct {
+	machine *goboy.Machine
+	state   *lua.LState
+	main    *lua.LFunction
+	thread  *lua.LState // Runs main, nil once it returns
+	out     io.Writer
+	err     error
+
+	pressed  goboy.Buttons // Forced by joypad.set for the next frame
+	released goboy.Buttons
+	buttons  goboy.Buttons // Given to the machine for the current frame
+
+	drawings []func(c *overlay.Canvas)
+
+	readCallbacks  map[uint16]*lua.LFunction
+	writeCallbacks map[uint16]*lua.LFunction
+	execCallbacks  map[uint16]*lua.LFunction
+	hookID         int
+	tracer         *execTracer // Set whilst there are exec callbacks
+}
+
+// New loads a script for the machine. name is used in error messages and print writes to out. The script
+// doesn't start until the first call to Frame.
+func New(machine *goboy.Machine, name string, source io.Reader, out io.Writer) (*Script, error) {
+	s := &Script{
+		machine:        machine,
+		state:          lua.NewState(),
+		out:            out,
+		readCallbacks:  make(map[uint16]*lua.LFunction),
+		writeCallbacks: make(map[uint16]*lua.LFunction),
+		execCallbacks:  make(map[uint16]*lua.LFunction),
+	}
+	s.registerApi()
+
+	main, err := s.state.Load(source, name)
+	if err != nil {
+		s.state.Close()
+		return nil, err
+	}
+	s.main = main
+	s.thread, _ = s.state.NewThread()
+	return s, nil
+}
+
+// Frame runs the script until it calls emu.frameadvance. It returns the buttons to press in the next frame:
+// the ones given, which are usually the player's, changed by joypad.set.
+func (s *Script) Frame(buttons goboy.Buttons) goboy.Buttons {
+	s.pressed, s.released = 0, 0
+	s.drawings = s.drawings[:0] // Also drawn by callbacks, which keep running after the script returns or fails
+
+	if s.thread != nil && s.err == nil {
+		state, err, _ := s.state.Resume(s.thread, s.main)
+		if err != nil {
+			s.fail(err)
+		} else if state == lua.ResumeOK {
+			s.thread = nil
+		}
+	}
+
+	s.buttons = (buttons | s.pressed) &^ s.released
+	return s.buttons
+}
+
+// Overlay returns the frame with what the script drew on it. The frame is not modified.
+func (s *Script) Overlay(frame []uint32, width, height int) []uint32 {
+	if len(s.drawings) == 0 {
+		return frame
+	}
+
+	c := &overlay.Canvas{Pixels: append([]uint32(nil), frame...), Width: width, Height: height}
+	for _, draw := range s.drawings {
+		draw(c)
+	}
+	return c.Pixels
+}
+
+// Err returns the error that stopped the script, if any.
+func (s *Script) Err() error {
+	return s.err
+}
+
+// Close stops the script and removes its callbacks. It can be called more than once.
+func (s *Script) Close() {
+	if s.state.IsClosed() {
+		return
+	}
+	s.stop()
+	s.state.Close()
+}
+
+// fail stops the script after an error. The game keeps running.
+func (s *Script) fail(err error) {
+	s.err = err
+	fmt.Fprintf(s.out, "script error: %s\n", err)
+	s.stop()
+}
+
+func (s *Script) stop() {
+	s.thread = nil
+	s.readCallbacks = make(map[uint16]*lua.LFunction)
+	s.writeCallbacks = make(map[uint16]*lua.LFunction)
+	s.execCallbacks = make(map[uint16]*lua.LFunction)
+	s.updateHooks()
+}
+
+// call calls a callback. An error stops the script.
+func (s *Script) call(fn *lua.LFunction, args ...lua.LValue) {
+	if s.err != nil {
+		return
+	}
+	if err := s.state.CallByParam(lua.P{Fn: fn, Protect: true}, args...); err != nil {
+		s.fail(err)
+	}
+}
+
+// updateHooks registers the bus hook and the exec tracer only whilst there are callbacks, so the emulator runs
+// at full speed otherwise.
+func (s *Script) updateHooks() {
+	b := s.machine.Bus()
+	needed := len(s.readCallbacks) > 0 || len(s.writeCallbacks) > 0
+	switch {
+	case needed && s.hookID == 0:
+		b.SetHookContext(s.machine.CPU())
+		s.hookID = b.AddHook(s.onAccess)
+	case !needed && s.hookID != 0:
+		b.RemoveHook(s.hookID)
+		s.hookID = 0
+	}
+
+	c := s.machine.CPU()
+	switch {
+	case len(s.execCallbacks) > 0 && s.tracer == nil:
+		s.tracer = &execTracer{script: s, next: c.Tracer()}
+		c.SetTracer(s.tracer)
+	case len(s.execCallbacks) == 0 && s.tracer != nil:
+		if c.Tracer() == s.tracer {
+			c.SetTracer(s.tracer.next)
+		}
+		s.tracer = nil
+	}
+}
+
+// onAccess is the bus hook calling the read and write callbacks.
+func (s *Script) onAccess(access bus.Access) {
+	callbacks := s.readCallbacks
+	if access.Type == bus.AccessWrite {
+		callbacks = s.writeCallbacks
+	}
+
+	if fn, ok := callbacks[access.Address]; ok {
+		s.call(fn, lua.LNumber(access.Address), lua.LNumber(access.Value))
+	}
+}
+
+// execTracer calls the exec callbacks before their instruction is executed. It passes every instruction on to
+// the tracer that was set before, like the one writing --trace.
+type execTracer struct {
+	script *Script
+	next   cpu.Tracer
+}
+
+func (t *execTracer) Trace(c *cpu.CPU) {
+	if t.next != nil {
+		t.next.Trace(c)
+	}
+	if fn, ok := t.script.execCallbacks[c.InstructionPC()]; ok {
+		t.script.call(fn, lua.LNumber(c.InstructionPC()))
+	}
+}
+
+// Wrap returns a frontend that runs the script before each frame and draws its overlay on the frames drawn by f.
+// Closing it closes the script too.
+func (s *Script) Wrap(f frontend.Frontend) frontend.Frontend {
+	return &scriptFrontend{Frontend: f, script: s}
+}
+
+type scriptFrontend struct {
+	frontend.Frontend
+	script *Script
+}
+
+func (f *scriptFrontend) Poll() (goboy.Buttons, bool) {
+	buttons, quit := f.Frontend.Poll()
+	return f.script.Frame(buttons), quit
+}
+
+func (f *scriptFrontend) Draw(frame []uint32, width, height int) {
+	f.Frontend.Draw(f.script.Overlay(frame, width, height), width, height)
+}
+
+func (f *scriptFrontend) Close() error {
+	f.script.Close()
+	return f.Frontend.Close()
+}
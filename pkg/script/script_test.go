@@ -0,0 +1,150 @@
+package script
+
+import (
+	"bytes"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/test"
+	lua "github.com/yuin/gopher-lua"
+	"strings"
+	"testing"
+)
+
+func newTestScript(t *testing.T, source string) (*Script, *goboy.Machine, *bytes.Buffer) {
+	machine, err := goboy.New(test.Rom("COUNTER", test.CounterProgram), goboy.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	s, err := New(machine, "test.lua", strings.NewReader(source), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s, machine, &out
+}
+
+// runFrames runs the script and the machine as frontend.Run does.
+func runFrames(t *testing.T, s *Script, machine *goboy.Machine, frames int) {
+	for i := 0; i < frames; i++ {
+		machine.SetButtons(s.Frame(0))
+		if err := machine.RunFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFrameAdvance(t *testing.T) {
+	s, machine, out := newTestScript(t, `
+		for _, input in ipairs({{A = true}, {A = true, start = true}, {start = false}}) do
+			print("frame", emu.framecount())
+			joypad.set(input)
+			emu.frameadvance()
+		end
+		print("done")
+	`)
+
+	if buttons := s.Frame(goboy.ButtonStart); buttons != goboy.ButtonStart|goboy.ButtonA {
+		t.Errorf("expected the player's Start and the script's A got %08b", buttons)
+	}
+	machine.RunFrame()
+	if buttons := s.Frame(goboy.ButtonB); buttons != goboy.ButtonB|goboy.ButtonA|goboy.ButtonStart {
+		t.Errorf("expected B, A and Start got %08b", buttons)
+	}
+	machine.RunFrame()
+	if buttons := s.Frame(goboy.ButtonStart); buttons != 0 {
+		t.Errorf("expected the script to release Start got %08b", buttons)
+	}
+	machine.RunFrame()
+	if buttons := s.Frame(goboy.ButtonB); buttons != goboy.ButtonB {
+		t.Errorf("expected the player's buttons once the script returns got %08b", buttons)
+	}
+
+	if expected := "frame\t0\nframe\t1\nframe\t2\ndone\n"; out.String() != expected {
+		t.Errorf("expected output %q got %q", expected, out.String())
+	}
+}
+
+func TestMemory(t *testing.T) {
+	s, machine, _ := newTestScript(t, `
+		writes, execs = 0, 0
+		memory.registerwrite(0xC000, function(address, value) writes = writes + 1 end)
+		memory.registerexec(0x0153, function(address) execs = execs + 1 end)
+		memory.writeword(0xC100, 0x1234)
+		while true do
+			emu.frameadvance()
+			counter = memory.readbyte(0xC000)
+			word = memory.read_u16_le(0xC100)
+			pc = emu.getregister("PC")
+		end
+	`)
+	runFrames(t, s, machine, 2)
+	s.Frame(0)
+
+	get := func(name string) int {
+		value, ok := s.state.GetGlobal(name).(lua.LNumber)
+		if !ok {
+			t.Fatalf("%s is not set, script error: %v", name, s.Err())
+		}
+		return int(value)
+	}
+	if get("writes") == 0 || get("writes") != get("execs") {
+		t.Errorf("expected a write per INC (HL), got %d writes and %d execs", get("writes"), get("execs"))
+	}
+	if get("counter") != int(machine.Bus().Peek(0xC000)) || get("word") != 0x1234 {
+		t.Errorf("unexpected memory values %02X and %04X", get("counter"), get("word"))
+	}
+	if pc := get("pc"); pc < 0x150 || pc > 0x155 {
+		t.Errorf("expected PC in the program got %04X", pc)
+	}
+
+	s.Close()
+	if machine.CPU().Tracer() != nil {
+		t.Error("expected the exec tracer to be removed")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	s, _, _ := newTestScript(t, `
+		gui.box(1, 1, 3, 3, "red", 0x0000FF)
+		gui.text(5, 1, "Hi", "#00FF00", "clear")
+	`)
+	s.Frame(0)
+
+	frame := make([]uint32, 16*8)
+	overlay := s.Overlay(frame, 16, 8)
+	if frame[1*16+1] != 0 {
+		t.Error("expected the frame not to be modified")
+	}
+	if overlay[1*16+1] != 0xFF0000FF || overlay[2*16+2] != 0xFFFF0000 {
+		t.Errorf("expected a red box with blue outline got %08X and %08X", overlay[1*16+1], overlay[2*16+2])
+	}
+	if overlay[1*16+5] != 0xFF00FF00 || overlay[1*16+6] != 0 {
+		t.Errorf("expected the top of the H got %08X %08X", overlay[1*16+5], overlay[1*16+6])
+	}
+
+	// The script has returned, what it drew is cleared in the next frame
+	s.Frame(0)
+	if overlay := s.Overlay(frame, 16, 8); overlay[1*16+1] != 0 {
+		t.Errorf("expected the drawings to be cleared got %08X", overlay[1*16+1])
+	}
+}
+
+func TestError(t *testing.T) {
+	s, machine, out := newTestScript(t, `
+		memory.registerwrite(0xC000, function() error("boom") end)
+		emu.frameadvance()
+	`)
+	runFrames(t, s, machine, 2)
+
+	if s.Err() == nil || !strings.Contains(out.String(), "boom") {
+		t.Errorf("expected the callback error to stop the script, output %q", out.String())
+	}
+	if counter := machine.Bus().Peek(0xC000); counter == 0 {
+		t.Error("expected the game to keep running")
+	}
+
+	if _, err := New(machine, "broken.lua", strings.NewReader("if then"), out); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
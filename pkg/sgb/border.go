@@ -0,0 +1,96 @@
+package sgb
+
+import "github.com/mikeletux/goboy/pkg/ppu"
+
+const (
+	borderTilesWidth  = BorderWidth / 8
+	borderTilesHeight = BorderHeight / 8
+)
+
+// Render returns the SGB output given the DMG shades (0-3) of the game screen: the colorized game screen
+// surrounded by the border. Pixels are stored as 0xAARRGGBB.
+func (s *SGB) Render(shades []byte) []uint32 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	frame := make([]uint32, BorderWidth*BorderHeight)
+	backdrop := ppu.CgbColorToARGB(s.palettes[0][0])
+	for i := range frame {
+		frame[i] = backdrop
+	}
+
+	s.renderScreen(frame, shades)
+	s.renderBorder(frame)
+
+	return frame
+}
+
+func (s *SGB) renderScreen(frame []uint32, shades []byte) {
+	switch s.mask {
+	case maskBlack:
+		for y := 0; y < screenHeight; y++ {
+			for x := 0; x < screenWidth; x++ {
+				frame[(screenY+y)*BorderWidth+screenX+x] = 0xFF000000
+			}
+		}
+		return
+	case maskColor0: // Backdrop has already been drawn
+		return
+	case maskFreeze:
+		if s.frozenShades == nil {
+			s.frozenShades = append([]byte(nil), shades...)
+		}
+		shades = s.frozenShades
+	}
+
+	for y := 0; y < screenHeight; y++ {
+		for x := 0; x < screenWidth; x++ {
+			palette := s.attributes[(y/8)*cellsWidth+x/8]
+			color := s.palettes[palette][shades[y*screenWidth+x]&0x3]
+			frame[(screenY+y)*BorderWidth+screenX+x] = ppu.CgbColorToARGB(color)
+		}
+	}
+}
+
+// renderBorder draws the border over the frame. Color 0 is transparent so the game screen and backdrop
+// are visible through it.
+func (s *SGB) renderBorder(frame []uint32) {
+	for tileY := 0; tileY < borderTilesHeight; tileY++ {
+		for tileX := 0; tileX < borderTilesWidth; tileX++ {
+			entry := s.borderMap[tileY*32+tileX]
+			tile := &s.borderTiles[entry&0xFF]
+			palette := &s.borderPalettes[(entry>>10)&0x3] // Border uses SGB palettes 4-7
+			xFlip, yFlip := entry&0x4000 != 0, entry&0x8000 != 0
+
+			for y := 0; y < 8; y++ {
+				row := y
+				if yFlip {
+					row = 7 - y
+				}
+
+				for x := 0; x < 8; x++ {
+					bit := 7 - x
+					if xFlip {
+						bit = x
+					}
+
+					colorIndex := snesTilePixel(tile, row, bit)
+					if colorIndex == 0 {
+						continue
+					}
+
+					frame[(tileY*8+y)*BorderWidth+tileX*8+x] = ppu.CgbColorToARGB(palette[colorIndex])
+				}
+			}
+		}
+	}
+}
+
+// snesTilePixel returns the color index of a pixel of a SNES 4bpp tile. Bitplanes 0 and 1 are stored
+// interleaved in the first 16 bytes and bitplanes 2 and 3 in the last 16 bytes.
+func snesTilePixel(tile *[32]byte, row int, bit int) byte {
+	return tile[row*2]>>bit&1 |
+		(tile[row*2+1]>>bit&1)<<1 |
+		(tile[16+row*2]>>bit&1)<<2 |
+		(tile[16+row*2+1]>>bit&1)<<3
+}
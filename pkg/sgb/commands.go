@@ -0,0 +1,321 @@
+package sgb
+
+// Command codes
+const (
+	cmdPal01    byte = 0x00
+	cmdPal23    byte = 0x01
+	cmdPal03    byte = 0x02
+	cmdPal12    byte = 0x03
+	cmdAttrBlk  byte = 0x04
+	cmdAttrLin  byte = 0x05
+	cmdAttrDiv  byte = 0x06
+	cmdAttrChr  byte = 0x07
+	cmdPalSet   byte = 0x0A
+	cmdPalTrn   byte = 0x0B
+	cmdMltReq   byte = 0x11
+	cmdChrTrn   byte = 0x13
+	cmdPctTrn   byte = 0x14
+	cmdAttrTrn  byte = 0x15
+	cmdAttrSet  byte = 0x16
+	cmdMaskEn   byte = 0x17
+	vramTrnSize      = 0x1000
+)
+
+// Register addresses used to locate the data displayed on screen for VRAM transfers
+const (
+	lcdControlRegisterAddr uint16 = 0xFF40
+
+	lcdcBgTileMapBit        byte = 1 << 3
+	lcdcBgWindowTileDataBit byte = 1 << 4
+)
+
+func (s *SGB) execute(data []byte) {
+	command := data[0] >> 3
+
+	switch command {
+	case cmdPal01:
+		s.setPalettes(0, 1, data)
+	case cmdPal23:
+		s.setPalettes(2, 3, data)
+	case cmdPal03:
+		s.setPalettes(0, 3, data)
+	case cmdPal12:
+		s.setPalettes(1, 2, data)
+	case cmdAttrBlk:
+		s.attrBlk(data)
+	case cmdAttrLin:
+		s.attrLin(data)
+	case cmdAttrDiv:
+		s.attrDiv(data)
+	case cmdAttrChr:
+		s.attrChr(data)
+	case cmdPalSet:
+		s.palSet(data)
+	case cmdPalTrn:
+		s.palTrn()
+	case cmdMltReq:
+		s.mltReq(data)
+	case cmdChrTrn:
+		s.chrTrn(data)
+	case cmdPctTrn:
+		s.pctTrn()
+	case cmdAttrTrn:
+		s.attrTrn()
+	case cmdAttrSet:
+		s.attrSet(data)
+	case cmdMaskEn:
+		s.setMask(data[1] & 0x3)
+	default:
+		s.logger.Debugf("SGB command 0x%X not supported", command)
+	}
+}
+
+// setPalettes handles PAL01, PAL23, PAL03 and PAL12. Color 0 is shared by all palettes.
+func (s *SGB) setPalettes(first, second int, data []byte) {
+	color0 := readColor(data, 1)
+	for i := range s.palettes {
+		s.palettes[i][0] = color0
+	}
+
+	for i := 1; i < 4; i++ {
+		s.palettes[first][i] = readColor(data, 1+i*2)
+		s.palettes[second][i] = readColor(data, 7+i*2)
+	}
+}
+
+// attrBlk sets the palette of the cells inside, on the border and outside of a number of rectangles.
+func (s *SGB) attrBlk(data []byte) {
+	sets := int(data[1])
+	for i := 0; i < sets && 2+i*6+5 < len(data); i++ {
+		set := data[2+i*6 : 2+i*6+6]
+		control, palettes := set[0]&0x7, set[1]
+		x1, y1, x2, y2 := int(set[2]), int(set[3]), int(set[4]), int(set[5])
+
+		inside, border, outside := palettes&0x3, palettes>>2&0x3, palettes>>4&0x3
+		if control == 0x1 { // Only inside is set, border takes the same palette
+			control, border = 0x3, inside
+		} else if control == 0x4 { // Only outside is set, border takes the same palette
+			control, border = 0x6, outside
+		}
+
+		for y := 0; y < cellsHeight; y++ {
+			for x := 0; x < cellsWidth; x++ {
+				switch {
+				case x > x1 && x < x2 && y > y1 && y < y2:
+					if control&0x1 != 0 {
+						s.attributes[y*cellsWidth+x] = inside
+					}
+				case x >= x1 && x <= x2 && y >= y1 && y <= y2:
+					if control&0x2 != 0 {
+						s.attributes[y*cellsWidth+x] = border
+					}
+				default:
+					if control&0x4 != 0 {
+						s.attributes[y*cellsWidth+x] = outside
+					}
+				}
+			}
+		}
+	}
+}
+
+// attrLin sets the palette of whole rows or columns.
+func (s *SGB) attrLin(data []byte) {
+	sets := int(data[1])
+	for i := 0; i < sets && 2+i < len(data); i++ {
+		set := data[2+i]
+		line, palette := int(set&0x1F), set>>5&0x3
+
+		if set&0x80 != 0 { // Horizontal line
+			for x := 0; x < cellsWidth && line < cellsHeight; x++ {
+				s.attributes[line*cellsWidth+x] = palette
+			}
+		} else { // Vertical line
+			for y := 0; y < cellsHeight && line < cellsWidth; y++ {
+				s.attributes[y*cellsWidth+line] = palette
+			}
+		}
+	}
+}
+
+// attrDiv splits the screen in two halves and the line in between.
+func (s *SGB) attrDiv(data []byte) {
+	after, before, on := data[1]&0x3, data[1]>>2&0x3, data[1]>>4&0x3
+	horizontal := data[1]&0x40 != 0
+	division := int(data[2])
+
+	for y := 0; y < cellsHeight; y++ {
+		for x := 0; x < cellsWidth; x++ {
+			position := x
+			if horizontal {
+				position = y
+			}
+
+			switch {
+			case position < division:
+				s.attributes[y*cellsWidth+x] = before
+			case position == division:
+				s.attributes[y*cellsWidth+x] = on
+			default:
+				s.attributes[y*cellsWidth+x] = after
+			}
+		}
+	}
+}
+
+// attrChr sets the palette of consecutive cells starting at a given position.
+func (s *SGB) attrChr(data []byte) {
+	x, y := int(data[1]), int(data[2])
+	sets := int(data[3]) | int(data[4])<<8
+	vertical := data[5] == 1
+
+	for i := 0; i < sets && 6+i/4 < len(data); i++ {
+		if x >= cellsWidth || y >= cellsHeight {
+			return
+		}
+
+		s.attributes[y*cellsWidth+x] = data[6+i/4] >> (6 - (i%4)*2) & 0x3
+
+		if vertical {
+			if y++; y == cellsHeight {
+				y, x = 0, x+1
+			}
+		} else {
+			if x++; x == cellsWidth {
+				x, y = 0, y+1
+			}
+		}
+	}
+}
+
+// palSet copies four palettes from the system palettes and optionally applies an attribute file.
+func (s *SGB) palSet(data []byte) {
+	for i := range s.palettes {
+		palette := int(data[1+i*2]) | int(data[2+i*2]&0x1)<<8
+		s.palettes[i] = s.systemPalettes[palette]
+	}
+
+	color0 := s.palettes[0][0]
+	for i := range s.palettes {
+		s.palettes[i][0] = color0
+	}
+
+	if data[9]&0x80 != 0 {
+		s.applyAttributeFile(data[9] & 0x3F)
+	}
+	if data[9]&0x40 != 0 {
+		s.setMask(maskCancel)
+	}
+}
+
+// palTrn transfers the 512 system palettes from VRAM.
+func (s *SGB) palTrn() {
+	transfer := s.readVramTransfer()
+	for i := range s.systemPalettes {
+		for color := range s.systemPalettes[i] {
+			s.systemPalettes[i][color] = readColor(transfer, i*8+color*2)
+		}
+	}
+}
+
+// mltReq enables multiplayer mode for 1, 2 or 4 players.
+func (s *SGB) mltReq(data []byte) {
+	switch data[1] & 0x3 {
+	case 1:
+		s.players = 2
+	case 3:
+		s.players = 4
+	default:
+		s.players = 1
+	}
+	s.controller = 0
+}
+
+// chrTrn transfers half of the border tiles from VRAM.
+func (s *SGB) chrTrn(data []byte) {
+	transfer := s.readVramTransfer()
+	first := 0
+	if data[1]&0x1 != 0 {
+		first = 0x80
+	}
+
+	for i := 0; i < 0x80; i++ {
+		copy(s.borderTiles[first+i][:], transfer[i*32:])
+	}
+}
+
+// pctTrn transfers the border tile map and its palettes from VRAM.
+func (s *SGB) pctTrn() {
+	transfer := s.readVramTransfer()
+	for i := range s.borderMap {
+		s.borderMap[i] = uint16(transfer[i*2]) | uint16(transfer[i*2+1])<<8
+	}
+
+	for palette := range s.borderPalettes {
+		for color := range s.borderPalettes[palette] {
+			s.borderPalettes[palette][color] = readColor(transfer, 0x800+palette*32+color*2)
+		}
+	}
+}
+
+// attrTrn transfers the 45 attribute files from VRAM.
+func (s *SGB) attrTrn() {
+	transfer := s.readVramTransfer()
+	for i := range s.attributeFiles {
+		copy(s.attributeFiles[i][:], transfer[i*attributeFile:])
+	}
+}
+
+func (s *SGB) attrSet(data []byte) {
+	s.applyAttributeFile(data[1] & 0x3F)
+	if data[1]&0x40 != 0 {
+		s.setMask(maskCancel)
+	}
+}
+
+func (s *SGB) applyAttributeFile(file byte) {
+	if int(file) >= attributeFiles {
+		return
+	}
+
+	for i := range s.attributes {
+		s.attributes[i] = s.attributeFiles[file][i/4] >> (6 - (i%4)*2) & 0x3
+	}
+}
+
+func (s *SGB) setMask(mask byte) {
+	s.mask = mask
+	s.frozenShades = nil
+}
+
+// readVramTransfer returns the 4 KiB displayed on screen. Games send data to the SGB by drawing it in the
+// first 256 tiles of the background, 20 tiles per row.
+func (s *SGB) readVramTransfer() []byte {
+	lcdc := s.bus.Peek(lcdControlRegisterAddr)
+
+	mapAddr := uint16(0x9800)
+	if lcdc&lcdcBgTileMapBit != 0 {
+		mapAddr = 0x9C00
+	}
+
+	transfer := make([]byte, vramTrnSize)
+	for tile := 0; tile < vramTrnSize/16; tile++ {
+		tileIndex := s.bus.ReadVRamBank(0, mapAddr+uint16(tile/cellsWidth)*32+uint16(tile%cellsWidth))
+
+		tileAddr := uint16(0x8000) + uint16(tileIndex)*16
+		if lcdc&lcdcBgWindowTileDataBit == 0 {
+			tileAddr = uint16(0x9000 + int(int8(tileIndex))*16)
+		}
+
+		for i := uint16(0); i < 16; i++ {
+			transfer[tile*16+int(i)] = s.bus.ReadVRamBank(0, tileAddr+i)
+		}
+	}
+
+	return transfer
+}
+
+// readColor reads a little endian RGB555 color.
+func readColor(data []byte, index int) uint16 {
+	return uint16(data[index]) | uint16(data[index+1])<<8
+}
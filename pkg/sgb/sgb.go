@@ -0,0 +1,163 @@
+package sgb
+
+import (
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/log"
+	"sync"
+)
+
+const (
+	// BorderWidth and BorderHeight are the size of the SGB output, the game screen is placed in the middle.
+	BorderWidth  = 256
+	BorderHeight = 224
+
+	screenX      = 48
+	screenY      = 40
+	screenWidth  = 160
+	screenHeight = 144
+
+	packetSize     = 16
+	packetBits     = packetSize * 8
+	cellsWidth     = screenWidth / 8
+	cellsHeight    = screenHeight / 8
+	attributeFiles = 45
+	attributeFile  = cellsWidth * cellsHeight / 4 // 2 bits per cell
+	systemPalettes = 512
+)
+
+// P1 values written by the game to send packets. A packet starts with a reset pulse and every bit is
+// followed by P1 going back to 0x30.
+const (
+	p1Reset byte = 0x00
+	p1One   byte = 0x10
+	p1Zero  byte = 0x20
+	p1Idle  byte = 0x30
+)
+
+// Mask modes set by MASK_EN
+const (
+	maskCancel byte = iota
+	maskFreeze
+	maskBlack
+	maskColor0
+)
+
+// defaultPalette is used for all palettes until the game sets its own. Colors are RGB555.
+var defaultPalette = [4]uint16{0x7FFF, 0x56B5, 0x294A, 0x0000}
+
+// SGB emulates the Super Game Boy functions that games can use through command packets sent by P1 register.
+// It implements bus.JoypadDevice so it can be attached to the bus.
+type SGB struct {
+	bus    bus.DataBusInterface
+	logger log.Logger
+	mutex  sync.Mutex // Packets are received from the CPU goroutine whilst frames are rendered from the UI
+
+	// Packet reception
+	lastP1      byte
+	receiving   bool
+	bitCount    int
+	packet      [packetSize]byte
+	command     []byte
+	packetsLeft int
+
+	// Multiplayer
+	players    byte
+	controller byte
+
+	palettes       [4][4]uint16
+	systemPalettes [systemPalettes][4]uint16
+	attributes     [cellsWidth * cellsHeight]byte
+	attributeFiles [attributeFiles][attributeFile]byte
+	mask           byte
+	frozenShades   []byte
+
+	// Border
+	borderTiles    [256][32]byte // SNES 4bpp tiles
+	borderMap      [32 * 28]uint16
+	borderPalettes [4][16]uint16 // SGB palettes 4-7
+}
+
+// New returns an SGB that uses the bus to read VRAM when transferring data.
+func New(bus bus.DataBusInterface, logger log.Logger) *SGB {
+	s := &SGB{
+		bus:     bus,
+		logger:  logger,
+		lastP1:  p1Idle,
+		players: 1,
+	}
+
+	for i := range s.palettes {
+		s.palettes[i] = defaultPalette
+	}
+
+	return s
+}
+
+// JoypadWrite decodes command packets from the values written to P1.
+func (s *SGB) JoypadWrite(value byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p1 := value & 0x30
+	previous := s.lastP1
+	s.lastP1 = p1
+
+	if p1 == previous {
+		return
+	}
+
+	// In multiplayer mode the controller is switched every time P15 goes high
+	if !s.receiving && s.players > 1 && previous&0x20 == 0 && p1&0x20 != 0 {
+		s.controller = (s.controller + 1) % s.players
+	}
+
+	switch p1 {
+	case p1Reset:
+		s.receiving = true
+		s.bitCount = 0
+		s.packet = [packetSize]byte{}
+
+	case p1One, p1Zero:
+		if !s.receiving || previous != p1Idle {
+			return
+		}
+
+		if s.bitCount == packetBits { // Stop bit
+			s.receiving = false
+			s.receivePacket()
+			return
+		}
+
+		if p1 == p1One {
+			s.packet[s.bitCount/8] |= 1 << (s.bitCount % 8)
+		}
+		s.bitCount++
+	}
+}
+
+// ControllerID returns the controller currently selected when multiplayer is enabled with MLT_REQ.
+func (s *SGB) ControllerID() byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.controller
+}
+
+// receivePacket stores the packet just received and executes the command once all its packets arrive.
+// The first byte of a command is the command code (bits 3-7) and the number of packets (bits 0-2).
+func (s *SGB) receivePacket() {
+	if s.packetsLeft == 0 {
+		s.packetsLeft = int(s.packet[0] & 0x7)
+		if s.packetsLeft == 0 {
+			s.packetsLeft = 1
+		}
+		s.command = s.command[:0]
+	}
+
+	s.command = append(s.command, s.packet[:]...)
+	s.packetsLeft--
+
+	if s.packetsLeft == 0 {
+		s.execute(s.command)
+	}
+}
@@ -0,0 +1,116 @@
+package sgb
+
+import (
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/log"
+	"testing"
+)
+
+// sendPacket bit bangs a packet through P1 the same way games do
+func sendPacket(s *SGB, packet [packetSize]byte) {
+	s.JoypadWrite(p1Reset)
+	s.JoypadWrite(p1Idle)
+
+	for i := 0; i < packetBits; i++ {
+		if packet[i/8]>>(i%8)&1 == 1 {
+			s.JoypadWrite(p1One)
+		} else {
+			s.JoypadWrite(p1Zero)
+		}
+		s.JoypadWrite(p1Idle)
+	}
+
+	// Stop bit
+	s.JoypadWrite(p1Zero)
+	s.JoypadWrite(p1Idle)
+}
+
+func TestPal01(t *testing.T) {
+	s := New(bus.NewMapMock(), &log.NilLogger{})
+
+	sendPacket(s, [packetSize]byte{
+		cmdPal01<<3 | 1,
+		0x1F, 0x00, // Color 0
+		0x01, 0x00, 0x02, 0x00, 0x03, 0x00, // Palette 0 colors 1-3
+		0x04, 0x00, 0x05, 0x00, 0x06, 0x00, // Palette 1 colors 1-3
+	})
+
+	expected := [2][4]uint16{
+		{0x001F, 0x0001, 0x0002, 0x0003},
+		{0x001F, 0x0004, 0x0005, 0x0006},
+	}
+	for i, palette := range expected {
+		if s.palettes[i] != palette {
+			t.Errorf("palette %d: expected %v got %v", i, palette, s.palettes[i])
+		}
+	}
+
+	if s.palettes[3][0] != 0x001F {
+		t.Errorf("color 0 should be shared by all palettes, got %X", s.palettes[3][0])
+	}
+}
+
+func TestAttrBlk(t *testing.T) {
+	s := New(bus.NewMapMock(), &log.NilLogger{})
+
+	sendPacket(s, [packetSize]byte{
+		cmdAttrBlk<<3 | 1,
+		1,          // One data set
+		0x7,        // Change inside, border and outside
+		0b11_10_01, // Outside palette 3, border palette 2, inside palette 1
+		2, 2, 5, 5, // Rectangle from (2,2) to (5,5)
+	})
+
+	testCases := []struct {
+		x, y    int
+		palette byte
+	}{
+		{x: 3, y: 3, palette: 1},
+		{x: 2, y: 4, palette: 2},
+		{x: 5, y: 5, palette: 2},
+		{x: 0, y: 0, palette: 3},
+		{x: 19, y: 17, palette: 3},
+	}
+
+	for _, v := range testCases {
+		got := s.attributes[v.y*cellsWidth+v.x]
+		if got != v.palette {
+			t.Errorf("cell (%d,%d): expected palette %d got %d", v.x, v.y, v.palette, got)
+		}
+	}
+}
+
+func TestMltReq(t *testing.T) {
+	s := New(bus.NewMapMock(), &log.NilLogger{})
+
+	sendPacket(s, [packetSize]byte{cmdMltReq<<3 | 1, 0x1}) // Two players
+	if s.ControllerID() != 0 {
+		t.Errorf("expected controller 0 got %d", s.ControllerID())
+	}
+
+	s.JoypadWrite(p1One) // P15 low
+	s.JoypadWrite(p1Idle)
+	if s.ControllerID() != 1 {
+		t.Errorf("expected controller 1 got %d", s.ControllerID())
+	}
+
+	s.JoypadWrite(p1One)
+	s.JoypadWrite(p1Idle)
+	if s.ControllerID() != 0 {
+		t.Errorf("expected controller 0 got %d", s.ControllerID())
+	}
+}
+
+func TestState(t *testing.T) {
+	s := New(bus.NewMapMock(), &log.NilLogger{})
+	sendPacket(s, [packetSize]byte{cmdPal01<<3 | 1, 0x1F, 0x00, 0x01, 0x00})
+	sendPacket(s, [packetSize]byte{cmdMltReq<<3 | 1, 0x01}) // Two players
+	state := s.State()
+
+	loaded := New(bus.NewMapMock(), &log.NilLogger{})
+	loaded.LoadState(state)
+	if loaded.palettes != s.palettes || loaded.players != 2 {
+		t.Errorf("expected palettes %v and 2 players after loading, got %v and %d", s.palettes, loaded.palettes,
+			loaded.players)
+	}
+}
@@ -0,0 +1,78 @@
+package sgb
+
+// State is a snapshot of the SGB, used for save states.
+type State struct {
+	LastP1      byte
+	Receiving   bool
+	BitCount    int
+	Packet      [packetSize]byte
+	Command     []byte
+	PacketsLeft int
+
+	Players    byte
+	Controller byte
+
+	Palettes       [4][4]uint16
+	SystemPalettes [systemPalettes][4]uint16
+	Attributes     [cellsWidth * cellsHeight]byte
+	AttributeFiles [attributeFiles][attributeFile]byte
+	Mask           byte
+	FrozenShades   []byte
+
+	BorderTiles    [256][32]byte
+	BorderMap      [32 * 28]uint16
+	BorderPalettes [4][16]uint16
+}
+
+// State returns a snapshot of the SGB.
+func (s *SGB) State() State {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return State{
+		LastP1:         s.lastP1,
+		Receiving:      s.receiving,
+		BitCount:       s.bitCount,
+		Packet:         s.packet,
+		Command:        append([]byte(nil), s.command...),
+		PacketsLeft:    s.packetsLeft,
+		Players:        s.players,
+		Controller:     s.controller,
+		Palettes:       s.palettes,
+		SystemPalettes: s.systemPalettes,
+		Attributes:     s.attributes,
+		AttributeFiles: s.attributeFiles,
+		Mask:           s.mask,
+		FrozenShades:   append([]byte(nil), s.frozenShades...),
+		BorderTiles:    s.borderTiles,
+		BorderMap:      s.borderMap,
+		BorderPalettes: s.borderPalettes,
+	}
+}
+
+// LoadState restores a snapshot taken with State.
+func (s *SGB) LoadState(state State) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastP1 = state.LastP1
+	s.receiving = state.Receiving
+	s.bitCount = state.BitCount
+	s.packet = state.Packet
+	s.command = append([]byte(nil), state.Command...)
+	s.packetsLeft = state.PacketsLeft
+	s.players = state.Players
+	s.controller = state.Controller
+	s.palettes = state.Palettes
+	s.systemPalettes = state.SystemPalettes
+	s.attributes = state.Attributes
+	s.attributeFiles = state.AttributeFiles
+	s.mask = state.Mask
+	s.frozenShades = nil
+	if len(state.FrozenShades) > 0 {
+		s.frozenShades = append([]byte(nil), state.FrozenShades...)
+	}
+	s.borderTiles = state.BorderTiles
+	s.borderMap = state.BorderMap
+	s.borderPalettes = state.BorderPalettes
+}
@@ -0,0 +1,152 @@
+// Package symbols loads the symbol files emitted by RGBDS (rgblink -n), so addresses can be shown as labels.
+package symbols
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	romxStart uint16 = 0x4000
+	romxEnd   uint16 = 0x7FFF
+)
+
+// Symbol is a label placed at a bank and address.
+type Symbol struct {
+	Name    string
+	Bank    int
+	Address uint16
+}
+
+func (s Symbol) String() string {
+	return fmt.Sprintf("%02X:%04X %s", s.Bank, s.Address, s.Name)
+}
+
+type location struct {
+	bank    int
+	address uint16
+}
+
+// Table holds the symbols of a ROM.
+type Table struct {
+	byLocation map[location]Symbol
+	byName     map[string]Symbol
+}
+
+// Load reads a .sym file.
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+// LoadForRom loads the symbol file that RGBDS leaves next to the ROM, e.g. game.sym for game.gb. It returns a
+// nil table if there's no such file.
+func LoadForRom(romPath string) (*Table, error) {
+	path := strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".sym"
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil
+	}
+
+	return Load(path)
+}
+
+// Parse reads symbols in the RGBDS format, one "bank:address name" per line. Anything after ; is a comment.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{
+		byLocation: make(map[location]Symbol),
+		byName:     make(map[string]Symbol),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		symbol, err := parseSymbol(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		t.Add(symbol)
+	}
+
+	return t, scanner.Err()
+}
+
+func parseSymbol(fields []string) (Symbol, error) {
+	if len(fields) != 2 {
+		return Symbol{}, fmt.Errorf("expected \"bank:address name\", got %q", strings.Join(fields, " "))
+	}
+
+	bankField, addressField, ok := strings.Cut(fields[0], ":")
+	if !ok {
+		return Symbol{}, fmt.Errorf("missing bank in %q", fields[0])
+	}
+
+	bank, err := strconv.ParseUint(bankField, 16, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("invalid bank %q", bankField)
+	}
+
+	address, err := strconv.ParseUint(addressField, 16, 16)
+	if err != nil {
+		return Symbol{}, fmt.Errorf("invalid address %q", addressField)
+	}
+
+	return Symbol{Name: fields[1], Bank: int(bank), Address: uint16(address)}, nil
+}
+
+// Add adds a symbol to the table. If there are several symbols at the same place, the first one is used as
+// the label for that place.
+func (t *Table) Add(symbol Symbol) {
+	key := t.key(symbol.Bank, symbol.Address)
+	if _, ok := t.byLocation[key]; !ok {
+		t.byLocation[key] = symbol
+	}
+	t.byName[symbol.Name] = symbol
+}
+
+// key only keeps the bank for the switchable ROM area, the rest of the memory is matched by address.
+func (t *Table) key(bank int, address uint16) location {
+	if address < romxStart || address > romxEnd {
+		bank = 0
+	}
+	return location{bank: bank, address: address}
+}
+
+// Lookup returns the label at address, given the ROM bank mapped at 0x4000-0x7FFF. A nil table has no
+// symbols, so callers don't need to check whether a symbol file was loaded.
+func (t *Table) Lookup(bank int, address uint16) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+
+	symbol, ok := t.byLocation[t.key(bank, address)]
+	return symbol.Name, ok
+}
+
+// Find returns the symbol with the given name.
+func (t *Table) Find(name string) (Symbol, bool) {
+	if t == nil {
+		return Symbol{}, false
+	}
+
+	symbol, ok := t.byName[name]
+	return symbol, ok
+}
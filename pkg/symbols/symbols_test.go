@@ -0,0 +1,83 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const symFile = `; File generated by rgblink
+00:0150 Main
+00:0150 EntryPoint
+01:4a20 Bank1Label
+02:4a20 Bank2Label
+00:c0a0 wPlayerHealth ; comment
+`
+
+func TestParse(t *testing.T) {
+	table, err := Parse(strings.NewReader(symFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		bank     int
+		address  uint16
+		expected string
+		found    bool
+	}{
+		{bank: 1, address: 0x0150, expected: "Main", found: true}, // First symbol wins
+		{bank: 1, address: 0x4A20, expected: "Bank1Label", found: true},
+		{bank: 2, address: 0x4A20, expected: "Bank2Label", found: true},
+		{bank: 3, address: 0x4A20, found: false},
+		{bank: 5, address: 0xC0A0, expected: "wPlayerHealth", found: true}, // Bank only matters for ROMX
+	}
+
+	for _, testCase := range testCases {
+		name, found := table.Lookup(testCase.bank, testCase.address)
+		if name != testCase.expected || found != testCase.found {
+			t.Errorf("%02X:%04X: got %q (%t) expected %q (%t)", testCase.bank, testCase.address, name, found,
+				testCase.expected, testCase.found)
+		}
+	}
+
+	symbol, ok := table.Find("Bank2Label")
+	if !ok || symbol.Bank != 2 || symbol.Address != 0x4A20 {
+		t.Errorf("unexpected symbol %v found by name", symbol)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	if _, err := Parse(strings.NewReader("0150 Main\n")); err == nil {
+		t.Error("expected error parsing a symbol without bank")
+	}
+}
+
+func TestNilTable(t *testing.T) {
+	var table *Table
+	if _, ok := table.Lookup(0, 0x150); ok {
+		t.Error("expected nil table to have no symbols")
+	}
+}
+
+func TestLoadForRom(t *testing.T) {
+	dir := t.TempDir()
+
+	table, err := LoadForRom(filepath.Join(dir, "game.gb"))
+	if err != nil || table != nil {
+		t.Fatalf("expected no table and no error without a symbol file, got %v %v", table, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "game.sym"), []byte(symFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err = LoadForRom(filepath.Join(dir, "game.gb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := table.Lookup(0, 0x150); !ok || name != "Main" {
+		t.Errorf("expected Main at 0150 got %q", name)
+	}
+}
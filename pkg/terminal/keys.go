@@ -0,0 +1,72 @@
+package terminal
+
+import "github.com/mikeletux/goboy"
+
+// key is a key read from the terminal: a button, a hotkey or quit.
+type key struct {
+	button goboy.Buttons
+	hotkey hotkey
+	quit   bool
+}
+
+type hotkey int
+
+const (
+	noHotkey hotkey = iota
+	hotkeyPause
+	hotkeyFrameAdvance
+	hotkeySpeedUp
+	hotkeySpeedDown
+	hotkeyFastForward
+	hotkeyScreenshot
+	hotkeyRecord
+)
+
+// singleKeys maps the keys sent as a single byte.
+var singleKeys = map[byte]key{
+	'x':  {button: goboy.ButtonA},
+	'X':  {button: goboy.ButtonA},
+	'z':  {button: goboy.ButtonB},
+	'Z':  {button: goboy.ButtonB},
+	'\r': {button: goboy.ButtonStart},
+	'\n': {button: goboy.ButtonStart},
+	0x7F: {button: goboy.ButtonSelect}, // Backspace
+	0x08: {button: goboy.ButtonSelect}, // Ctrl-H, sent as backspace by some terminals
+	'p':  {hotkey: hotkeyPause},
+	'n':  {hotkey: hotkeyFrameAdvance},
+	'+':  {hotkey: hotkeySpeedUp},
+	'=':  {hotkey: hotkeySpeedUp}, // + without shift
+	'-':  {hotkey: hotkeySpeedDown},
+	'\t': {hotkey: hotkeyFastForward},
+	'c':  {hotkey: hotkeyScreenshot},
+	'v':  {hotkey: hotkeyRecord},
+	'q':  {quit: true},
+	0x03: {quit: true}, // Ctrl-C, which doesn't raise SIGINT in raw mode
+}
+
+// arrowKeys maps the last byte of the arrow key sequences, ESC [ A or ESC O A.
+var arrowKeys = map[byte]goboy.Buttons{
+	'A': goboy.ButtonUp,
+	'B': goboy.ButtonDown,
+	'C': goboy.ButtonRight,
+	'D': goboy.ButtonLeft,
+}
+
+// parseKeys returns the keys in the bytes read from the terminal. Unknown keys are skipped.
+func parseKeys(data []byte) []key {
+	var keys []key
+	for i := 0; i < len(data); i++ {
+		if data[i] == 0x1B && i+2 < len(data) && (data[i+1] == '[' || data[i+1] == 'O') {
+			if button, ok := arrowKeys[data[i+2]]; ok {
+				keys = append(keys, key{button: button})
+			}
+			i += 2
+			continue
+		}
+
+		if k, ok := singleKeys[data[i]]; ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,118 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// upperHalfBlock draws the upper pixel of a cell with the foreground colour and the lower one with the
+// background colour, so each character cell shows two pixels.
+const upperHalfBlock = "▀"
+
+const resetColors = "\x1b[0m"
+
+// cellWriter writes half-block cells in 24-bit colour, only emitting the colour codes that change.
+type cellWriter struct {
+	w          *bufio.Writer
+	foreground uint32
+	background uint32
+	colorsSet  bool
+}
+
+// cell writes a cell with the top and bottom pixels, stored as 0xAARRGGBB.
+func (c *cellWriter) cell(top, bottom uint32) {
+	top, bottom = top&0xFFFFFF, bottom&0xFFFFFF
+	if !c.colorsSet || top != c.foreground {
+		fmt.Fprintf(c.w, "\x1b[38;2;%d;%d;%dm", byte(top>>16), byte(top>>8), byte(top))
+	}
+	if !c.colorsSet || bottom != c.background {
+		fmt.Fprintf(c.w, "\x1b[48;2;%d;%d;%dm", byte(bottom>>16), byte(bottom>>8), byte(bottom))
+	}
+	c.foreground, c.background, c.colorsSet = top, bottom, true
+	c.w.WriteString(upperHalfBlock)
+}
+
+// reset restores the default colours.
+func (c *cellWriter) reset() {
+	c.w.WriteString(resetColors)
+	c.colorsSet = false
+}
+
+// pixelAt returns the pixel of a frame at x, y, or black below the last line.
+func pixelAt(frame []uint32, width, height, x, y int) uint32 {
+	if y >= height {
+		return 0
+	}
+	return frame[y*width+x]
+}
+
+// WriteFrame writes a frame of width x height pixels, stored row by row as 0xAARRGGBB, as lines of half-block
+// characters. It is meant for logs and terminals that are not in raw mode.
+func WriteFrame(w io.Writer, frame []uint32, width, height int) error {
+	buffered := bufio.NewWriter(w)
+	cells := cellWriter{w: buffered}
+
+	for y := 0; y < height; y += 2 {
+		for x := 0; x < width; x++ {
+			cells.cell(pixelAt(frame, width, height, x, y), pixelAt(frame, width, height, x, y+1))
+		}
+		cells.reset()
+		buffered.WriteString("\n")
+	}
+	return buffered.Flush()
+}
+
+// WriteImage writes an image like WriteFrame.
+func WriteImage(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	frame := make([]uint32, 0, bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			frame = append(frame, 0xFF000000|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+	return WriteFrame(w, frame, bounds.Dx(), bounds.Dy())
+}
+
+// screen draws frames on a terminal in raw mode, only updating the cells that changed since the last frame.
+type screen struct {
+	previous []uint32
+	width    int
+	height   int
+}
+
+// draw writes the escape codes that turn the previous frame into this one.
+func (s *screen) draw(w *bufio.Writer, frame []uint32, width, height int) {
+	redraw := s.previous == nil || width != s.width || height != s.height
+	if redraw {
+		w.WriteString("\x1b[2J") // Clear the screen
+		s.previous = make([]uint32, len(frame))
+		s.width, s.height = width, height
+	}
+
+	cells := cellWriter{w: w}
+	for y := 0; y < height; y += 2 {
+		cursorInPlace := false
+		for x := 0; x < width; x++ {
+			top, bottom := pixelAt(frame, width, height, x, y), pixelAt(frame, width, height, x, y+1)
+			if !redraw && top == pixelAt(s.previous, width, height, x, y) &&
+				bottom == pixelAt(s.previous, width, height, x, y+1) {
+				cursorInPlace = false
+				continue
+			}
+
+			if !cursorInPlace {
+				fmt.Fprintf(w, "\x1b[%d;%dH", y/2+1, x+1) // Rows and columns start at 1
+				cursorInPlace = true
+			}
+			cells.cell(top, bottom)
+		}
+	}
+	cells.reset()
+
+	copy(s.previous, frame)
+}
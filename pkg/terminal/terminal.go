@@ -0,0 +1,170 @@
+// Package terminal is a frontend that draws the screen on a terminal with half-block characters and 24-bit
+// colours, and reads the joypad from the keyboard. It is pure Go, so it works through SSH and on machines
+// without SDL.
+package terminal
+
+import (
+	"bufio"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultHoldDuration is how long a button is held after its key is pressed by default. Terminals don't report
+// key releases, but they repeat the keys held down, which extends it. The first repeat comes after the key
+// repeat delay, 500 ms in most systems and 660 ms in X11, so the button has to be held for longer than that.
+const DefaultHoldDuration = 700 * time.Millisecond
+
+// Terminal is the terminal frontend. It implements frontend.Frontend. There's no sound.
+type Terminal struct {
+	in       *os.File
+	out      *bufio.Writer
+	rawState *unix.Termios // State to restore on Close, nil if the input is not a terminal
+	hold     time.Duration
+
+	screen      screen
+	keys        chan []key
+	released    [8]time.Time // When each button is released
+	fastForward time.Time    // When fast-forward is released
+	hotkeys     frontend.Hotkeys
+	quit        bool
+}
+
+// New puts the input terminal in raw mode and prepares the output to draw the screen. If in is not a terminal,
+// like in CI, keys are still read from it but its mode is left alone. hold is how long a button is held after
+// its key is pressed, DefaultHoldDuration if 0.
+func New(in *os.File, out io.Writer, hold time.Duration) (*Terminal, error) {
+	if hold == 0 {
+		hold = DefaultHoldDuration
+	}
+
+	t := &Terminal{
+		in:   in,
+		out:  bufio.NewWriterSize(out, 64*1024),
+		hold: hold,
+		keys: make(chan []key, 16),
+	}
+
+	if fd := int(in.Fd()); isTerminal(fd) {
+		state, err := makeRaw(fd)
+		if err != nil {
+			return nil, err
+		}
+		t.rawState = state
+	}
+
+	t.out.WriteString("\x1b[?1049h\x1b[?25l") // Switch to the alternate screen and hide the cursor
+	if err := t.out.Flush(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go t.readKeys()
+	return t, nil
+}
+
+// readKeys reads the input until it is closed.
+func (t *Terminal) readKeys() {
+	buffer := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buffer)
+		if n > 0 {
+			t.keys <- parseKeys(buffer[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Draw draws the frame, updating only the cells that changed.
+func (t *Terminal) Draw(frame []uint32, width, height int) {
+	t.screen.draw(t.out, frame, width, height)
+	t.out.Flush()
+}
+
+// Play drops the samples, terminals can't play sound.
+func (t *Terminal) Play(samples []int16) {}
+
+// handleKeys handles the keys read since the last call. Buttons and fast-forward are held for t.hold.
+func (t *Terminal) handleKeys(now time.Time) {
+	for {
+		select {
+		case keys := <-t.keys:
+			for _, k := range keys {
+				t.handleKey(k, now)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (t *Terminal) handleKey(k key, now time.Time) {
+	t.quit = t.quit || k.quit
+	for i := range t.released {
+		if k.button&(1<<i) != 0 {
+			t.released[i] = now.Add(t.hold)
+		}
+	}
+
+	switch k.hotkey {
+	case hotkeyPause:
+		t.hotkeys.Pause = true
+	case hotkeyFrameAdvance:
+		t.hotkeys.FrameAdvance = true
+	case hotkeySpeedUp:
+		t.hotkeys.SpeedUp = true
+	case hotkeySpeedDown:
+		t.hotkeys.SpeedDown = true
+	case hotkeyFastForward:
+		t.fastForward = now.Add(t.hold)
+	case hotkeyScreenshot:
+		t.hotkeys.Screenshot = true
+	case hotkeyRecord:
+		t.hotkeys.Record = true
+	}
+}
+
+// Poll returns the buttons held and whether q or Ctrl-C was pressed.
+func (t *Terminal) Poll() (goboy.Buttons, bool) {
+	now := time.Now()
+	t.handleKeys(now)
+
+	var buttons goboy.Buttons
+	for i, released := range t.released {
+		if now.Before(released) {
+			buttons |= 1 << i
+		}
+	}
+	return buttons, t.quit
+}
+
+// Hotkeys returns the hotkeys pressed since the last call: P pauses, N advances a frame, + and - change the
+// speed, Tab fast-forwards, C takes a screenshot and V starts or stops recording.
+func (t *Terminal) Hotkeys() frontend.Hotkeys {
+	now := time.Now()
+	t.handleKeys(now)
+
+	hotkeys := t.hotkeys
+	t.hotkeys = frontend.Hotkeys{}
+	hotkeys.FastForward = now.Before(t.fastForward)
+	hotkeys.Quit = t.quit
+	return hotkeys
+}
+
+// Close restores the terminal.
+func (t *Terminal) Close() error {
+	t.out.WriteString(resetColors + "\x1b[?25h\x1b[?1049l") // Show the cursor and leave the alternate screen
+	err := t.out.Flush()
+
+	if t.rawState != nil {
+		if restoreErr := restore(int(t.in.Fd()), t.rawState); restoreErr != nil {
+			return restoreErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("x\x1b[A\x1bOD\x1b[5~z\r\x7fp\tq"))
+	expected := []key{{button: goboy.ButtonA}, {button: goboy.ButtonUp}, {button: goboy.ButtonLeft},
+		{button: goboy.ButtonB}, {button: goboy.ButtonStart}, {button: goboy.ButtonSelect},
+		{hotkey: hotkeyPause}, {hotkey: hotkeyFastForward}, {quit: true}}
+
+	if len(keys) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, keys)
+	}
+	for i := range keys {
+		if keys[i] != expected[i] {
+			t.Errorf("key %d: expected %v got %v", i, expected[i], keys[i])
+		}
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	frame := []uint32{
+		0xFFFFFFFF, 0xFF000000,
+		0xFF102030, 0xFF000000,
+		0xFFFFFFFF, 0xFFFFFFFF,
+	}
+
+	var out bytes.Buffer
+	if err := WriteFrame(&out, frame, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines for 3 rows got %d: %q", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "\x1b[38;2;255;255;255m\x1b[48;2;16;32;48m"+upperHalfBlock) {
+		t.Errorf("unexpected first cell %q", lines[0])
+	}
+	// Second cell of the first line only changes the background
+	if !strings.Contains(lines[0], upperHalfBlock+"\x1b[38;2;0;0;0m\x1b[48;2;0;0;0m"+upperHalfBlock) {
+		t.Errorf("unexpected second cell %q", lines[0])
+	}
+	if strings.Count(lines[1], upperHalfBlock) != 2 {
+		t.Errorf("expected the last row to be drawn on a line of its own, got %q", lines[1])
+	}
+}
+
+func TestScreenDrawsChanges(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	s := screen{}
+
+	frame := make([]uint32, 4*4)
+	s.draw(w, frame, 4, 4)
+	w.Flush()
+	if got := strings.Count(out.String(), upperHalfBlock); got != 8 {
+		t.Errorf("expected the first frame to draw 8 cells got %d", got)
+	}
+
+	out.Reset()
+	frame[2*4+3] = 0xFFFFFFFF // Cell at row 2, column 4
+	s.draw(w, frame, 4, 4)
+	w.Flush()
+	if got := strings.Count(out.String(), upperHalfBlock); got != 1 {
+		t.Errorf("expected only the changed cell to be drawn got %d cells", got)
+	}
+	if !strings.HasPrefix(out.String(), "\x1b[2;4H") {
+		t.Errorf("expected the cursor to be moved to the changed cell, got %q", out.String())
+	}
+}
+
+func TestPoll(t *testing.T) {
+	in, keys, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keys.Close()
+
+	var out bytes.Buffer
+	term, err := New(in, &out, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+
+	keys.Write([]byte("x\x1b[C"))
+	expected := goboy.ButtonA | goboy.ButtonRight
+	var buttons goboy.Buttons
+	for deadline := time.Now().Add(time.Second); buttons != expected && time.Now().Before(deadline); {
+		buttons, _ = term.Poll()
+	}
+	if buttons != expected {
+		t.Errorf("expected A and Right to be held got %08b", buttons)
+	}
+
+	keys.Write([]byte("\tp")) // Fast-forward first, so it has been read once pause is
+	var hotkeys frontend.Hotkeys
+	for deadline := time.Now().Add(time.Second); !hotkeys.Pause && time.Now().Before(deadline); {
+		hotkeys = term.Hotkeys()
+	}
+	if !hotkeys.Pause || !hotkeys.FastForward {
+		t.Errorf("expected pause and fast-forward got %+v", hotkeys)
+	}
+	if hotkeys = term.Hotkeys(); hotkeys.Pause || !hotkeys.FastForward {
+		t.Errorf("expected pause to be reported once and fast-forward to be held got %+v", hotkeys)
+	}
+
+	keys.Write([]byte("q"))
+	quit := false
+	for deadline := time.Now().Add(time.Second); !quit && time.Now().Before(deadline); {
+		_, quit = term.Poll()
+	}
+	if !quit {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestHoldDuration(t *testing.T) {
+	term := &Terminal{hold: time.Second}
+	now := time.Now()
+	term.handleKey(key{button: goboy.ButtonA}, now)
+
+	for i, released := range term.released {
+		if goboy.Buttons(1<<i) == goboy.ButtonA && !released.Equal(now.Add(time.Second)) {
+			t.Errorf("expected A to be held for a second, released after %s", released.Sub(now))
+		}
+	}
+}
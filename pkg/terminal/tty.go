@@ -0,0 +1,36 @@
+package terminal
+
+import "golang.org/x/sys/unix"
+
+// makeRaw puts the terminal in raw mode, so keys are received as soon as they are pressed and are not echoed.
+// It returns the previous state, to be restored with restore.
+func makeRaw(fd int) (*unix.Termios, error) {
+	state, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *state
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func restore(fd int, state *unix.Termios) error {
+	return unix.IoctlSetTermios(fd, ioctlSetTermios, state)
+}
+
+// isTerminal returns whether fd is a terminal.
+func isTerminal(fd int) bool {
+	_, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	return err == nil
+}
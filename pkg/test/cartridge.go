@@ -4,3 +4,58 @@ package test
 var NintendoCartridgeLogo []byte = []byte{0xCE, 0xED, 0x66, 0x66, 0xCC, 0x0D, 0x00, 0x0B, 0x03, 0x73, 0x00, 0x83, 0x00, 0x0C, 0x00,
 	0x0D, 0x00, 0x08, 0x11, 0x1F, 0x88, 0x89, 0x00, 0x0E, 0xDC, 0xCC, 0x6E, 0xE6, 0xDD, 0xDD, 0xD9, 0x99, 0xBB, 0xBB, 0x67, 0x63, 0x6E, 0x0E,
 	0xEC, 0xCC, 0xDD, 0xDC, 0x99, 0x9F, 0xBB, 0xB9, 0x33, 0x3E}
+
+// Rom returns a 32 KiB ROM with a valid header checksum, the given title and code at the given addresses.
+func Rom(title string, code map[uint16][]byte) []byte {
+	rom := make([]byte, 0x8000)
+	for address, bytes := range code {
+		copy(rom[address:], bytes)
+	}
+	copy(rom[0x134:], title)
+
+	var checksum byte
+	for address := 0x134; address <= 0x14C; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	rom[0x14D] = checksum
+	return rom
+}
+
+// CounterProgram increments C000 forever: JP 0150 ; LD HL,C000 ; INC (HL) ; JR -3. It is meant to be passed
+// to Rom.
+var CounterProgram = map[uint16][]byte{
+	0x100: {0xC3, 0x50, 0x01},
+	0x150: {0x21, 0x00, 0xC0, 0x34, 0x18, 0xFD},
+}
+
+// RomMock is a 32 KiB cartridge without MBC and with 8 KiB of RAM. Unlike Rom it doesn't need a header, so it
+// can be plugged straight into the bus.
+type RomMock struct {
+	Rom [0x8000]byte
+	Ram [0x2000]byte
+}
+
+// NewRomMock returns a RomMock with the given code at the given addresses, like Rom.
+func NewRomMock(code map[uint16][]byte) *RomMock {
+	r := &RomMock{}
+	for address, bytes := range code {
+		copy(r.Rom[address:], bytes)
+	}
+	return r
+}
+
+func (r *RomMock) CartRead(address uint16) byte {
+	switch {
+	case int(address) < len(r.Rom):
+		return r.Rom[address]
+	case address >= 0xA000 && address <= 0xBFFF:
+		return r.Ram[address-0xA000]
+	}
+	return 0xFF
+}
+
+func (r *RomMock) CartWrite(address uint16, value byte) {
+	if address >= 0xA000 && address <= 0xBFFF {
+		r.Ram[address-0xA000] = value
+	}
+}
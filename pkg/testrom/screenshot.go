@@ -0,0 +1,156 @@
+package testrom
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// diffColor marks the pixels that don't match the reference in diff images.
+var diffColor = color.RGBA{R: 0xFF, A: 0xFF}
+
+// ScreenshotOptions are the options used for screenshot tests: they run until LD B,B, which is how
+// dmg-acid2, cgb-acid2 and the mealybug tests finish, or for 10 seconds.
+var ScreenshotOptions = Options{MaxFrames: 600, StopAtBreakpoint: true}
+
+// ReferencePath returns where the reference screenshot of a ROM is kept: next to it, with a .png extension.
+func ReferencePath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".png"
+}
+
+// DiffPath returns where the diff image of a ROM is written when its screen doesn't match the reference.
+func DiffPath(romPath string) string {
+	return strings.TrimSuffix(romPath, filepath.Ext(romPath)) + ".diff.png"
+}
+
+// frameImage converts a PPU framebuffer, with pixels stored as 0xAARRGGBB, to an image.
+func frameImage(framebuffer []uint32) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, ppu.ScreenWidth, ppu.ScreenHeight))
+	for i, pixel := range framebuffer {
+		img.Pix[i*4] = byte(pixel >> 16)
+		img.Pix[i*4+1] = byte(pixel >> 8)
+		img.Pix[i*4+2] = byte(pixel)
+		img.Pix[i*4+3] = 0xFF
+	}
+	return img
+}
+
+// SavePNG writes an image as a PNG file.
+func SavePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadPNG reads a PNG file.
+func LoadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// Compare compares two images pixel by pixel. It returns the number of pixels that differ and an image of the
+// differences, with the matching pixels faded and the others in red.
+func Compare(got, reference image.Image) (int, *image.RGBA, error) {
+	bounds := got.Bounds()
+	if bounds.Size() != reference.Bounds().Size() {
+		return 0, nil, fmt.Errorf("screen is %v but the reference is %v", bounds.Size(), reference.Bounds().Size())
+	}
+
+	diff := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	offset := reference.Bounds().Min.Sub(bounds.Min)
+	mismatches := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			gotColor := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA)
+			referenceColor := color.RGBAModel.Convert(reference.At(x+offset.X, y+offset.Y)).(color.RGBA)
+
+			if gotColor != referenceColor {
+				mismatches++
+				diff.SetRGBA(x-bounds.Min.X, y-bounds.Min.Y, diffColor)
+				continue
+			}
+
+			gray := color.GrayModel.Convert(gotColor).(color.Gray).Y
+			faded := 0xC0 + gray/4
+			diff.SetRGBA(x-bounds.Min.X, y-bounds.Min.Y, color.RGBA{R: faded, G: faded, B: faded, A: 0xFF})
+		}
+	}
+
+	return mismatches, diff, nil
+}
+
+// CheckScreenshot compares a result screen with the reference image of the ROM. If they differ, the diff image
+// is written next to the ROM and an error describing the mismatch is returned.
+func CheckScreenshot(romPath string, result Result) error {
+	reference, err := LoadPNG(ReferencePath(romPath))
+	if err != nil {
+		return err
+	}
+
+	mismatches, diff, err := Compare(result.Screen, reference)
+	if err != nil {
+		return err
+	}
+	if mismatches == 0 {
+		return nil
+	}
+
+	if err := SavePNG(DiffPath(romPath), diff); err != nil {
+		return fmt.Errorf("%d pixels differ from the reference, error writing the diff: %w", mismatches, err)
+	}
+	return fmt.Errorf("%d pixels differ from the reference, diff written to %s", mismatches, DiffPath(romPath))
+}
+
+// RunScreenshotDir runs every ROM under dir that has a reference screenshot next to it as a subtest, failing
+// the ones whose screen doesn't match. It skips the test if dir doesn't exist.
+func RunScreenshotDir(t *testing.T, dir string, options Options) {
+	t.Helper()
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		t.Skipf("test ROM directory %s doesn't exist", dir)
+	}
+
+	roms, err := FindRoms(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rom := range roms {
+		if _, err := os.Stat(ReferencePath(rom)); err != nil {
+			continue
+		}
+
+		rom := rom
+		name, _ := filepath.Rel(dir, rom)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Run(rom, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := CheckScreenshot(rom, result); err != nil {
+				t.Errorf("%s after %d cycles: %s", result.Status, result.Cycles, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package testrom
+
+import (
+	"github.com/mikeletux/goboy/pkg/test"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScreenshot(t *testing.T) {
+	result, err := RunCartridge(test.NewRomMock(map[uint16][]byte{0x100: {0x40, 0x18, 0xFE}}), // LD B,B ; JR -2
+		ScreenshotOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != Breakpoint {
+		t.Errorf("expected to stop at the breakpoint got %s", result.Status)
+	}
+	if result.Screen == nil || result.Screen.Bounds().Dx() != 160 || result.Screen.Bounds().Dy() != 144 {
+		t.Fatalf("expected a 160x144 screen got %v", result.Screen)
+	}
+
+	rom := filepath.Join(t.TempDir(), "test.gb")
+	if err := SavePNG(ReferencePath(rom), result.Screen); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckScreenshot(rom, result); err != nil {
+		t.Errorf("expected screen to match its own reference: %s", err)
+	}
+
+	result.Screen.SetRGBA(10, 20, color.RGBA{R: 1, G: 2, B: 3, A: 0xFF})
+	if err := CheckScreenshot(rom, result); err == nil {
+		t.Error("expected a mismatch after changing a pixel")
+	}
+
+	diff, err := LoadPNG(DiffPath(rom))
+	if err != nil {
+		t.Fatalf("expected diff image: %s", err)
+	}
+	if got := color.RGBAModel.Convert(diff.At(10, 20)); got != diffColor {
+		t.Errorf("expected the changed pixel to be marked in the diff, got %v", got)
+	}
+	if got := color.RGBAModel.Convert(diff.At(0, 0)); got == diffColor {
+		t.Error("expected the other pixels not to be marked in the diff")
+	}
+}
+
+// TestScreenshots runs the ROMs with a reference screenshot under the directory set in GOBOY_SCREENSHOT_ROMS,
+// like dmg-acid2 and the mealybug tests.
+func TestScreenshots(t *testing.T) {
+	dir := os.Getenv("GOBOY_SCREENSHOT_ROMS")
+	if len(dir) == 0 {
+		t.Skip("GOBOY_SCREENSHOT_ROMS is not set")
+	}
+
+	RunScreenshotDir(t, dir, ScreenshotOptions)
+}
@@ -0,0 +1,60 @@
+package testrom
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// RunDir runs every .gb and .gbc ROM under dir as a subtest named after its path, failing the ones that don't
+// pass. The subtests run in parallel. It skips the test if dir doesn't exist, since test ROMs aren't part of
+// the repository.
+func RunDir(t *testing.T, dir string, options Options) {
+	t.Helper()
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		t.Skipf("test ROM directory %s doesn't exist", dir)
+	}
+
+	roms, err := FindRoms(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rom := range roms {
+		rom := rom
+		name, _ := filepath.Rel(dir, rom)
+		t.Run(filepath.ToSlash(name), func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Run(rom, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != Passed {
+				t.Errorf("%s after %d cycles (%s): %s", result.Status, result.Cycles, result.Source, result.Output)
+			}
+		})
+	}
+}
+
+// FindRoms returns the .gb and .gbc files under dir, sorted by path.
+func FindRoms(dir string) ([]string, error) {
+	var roms []string
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		extension := strings.ToLower(filepath.Ext(path))
+		if !entry.IsDir() && (extension == ".gb" || extension == ".gbc") {
+			roms = append(roms, path)
+		}
+		return nil
+	})
+
+	sort.Strings(roms)
+	return roms, err
+}
@@ -0,0 +1,249 @@
+// Package testrom runs test ROMs without a screen and detects their result. It understands the conventions
+// of the Blargg and Mooneye test suites.
+package testrom
+
+import (
+	"bytes"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cart"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"image"
+	"strings"
+)
+
+// FrameCycles is the number of T-cycles the PPU takes to draw a frame.
+const FrameCycles uint64 = 70224
+
+// checkInterval is the number of instructions executed between checks of the serial output and the RAM
+// signature, which are too slow to check after every instruction.
+const checkInterval = 4096
+
+// Blargg tests that can't use the serial port write their result in the cartridge RAM, after a signature.
+const (
+	blarggStatusAddr    uint16 = 0xA000
+	blarggSignatureAddr uint16 = 0xA001
+	blarggTextAddr      uint16 = 0xA004
+	blarggTextMaxLength        = 0x1000
+
+	blarggRunning byte = 0x80
+)
+
+var blarggSignature = []byte{0xDE, 0xB0, 0x61}
+
+// Mooneye tests execute LD B,B when they are done, with Fibonacci numbers in the registers if they passed.
+const mooneyeBreakpoint byte = 0x40 // LD B,B
+
+var (
+	mooneyePass = [6]byte{3, 5, 8, 13, 21, 34}
+	mooneyeFail = [6]byte{0x42, 0x42, 0x42, 0x42, 0x42, 0x42}
+)
+
+// Status is the outcome of a test ROM.
+type Status int
+
+const (
+	Timeout Status = iota // The budget ran out before the ROM reported a result
+	Passed
+	Failed
+	Breakpoint // The ROM hit LD B,B without a Mooneye result, which is how screenshot tests finish
+)
+
+func (s Status) String() string {
+	switch s {
+	case Passed:
+		return "passed"
+	case Failed:
+		return "failed"
+	case Breakpoint:
+		return "breakpoint"
+	}
+	return "timeout"
+}
+
+// MarshalText makes Status readable in JSON.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Source is where a result was detected.
+type Source string
+
+const (
+	SourceNone    Source = ""
+	SourceSerial  Source = "blargg-serial"
+	SourceRam     Source = "blargg-ram"
+	SourceMooneye Source = "mooneye"
+)
+
+// Options limit how long a ROM is run. When both are set the first one reached stops the ROM.
+type Options struct {
+	MaxCycles uint64 // T-cycles, 0 for no limit
+	MaxFrames uint64 // Frames of FrameCycles T-cycles, 0 for no limit
+
+	// StopAtBreakpoint stops the ROM at any LD B,B, once the frame being drawn is complete
+	StopAtBreakpoint bool
+}
+
+// DefaultOptions gives enough time to the longest Blargg tests.
+var DefaultOptions = Options{MaxFrames: 60 * 120}
+
+func (o Options) cycleBudget() uint64 {
+	budget := o.MaxCycles
+	if frames := o.MaxFrames * FrameCycles; o.MaxFrames > 0 && (budget == 0 || frames < budget) {
+		budget = frames
+	}
+	return budget
+}
+
+// Result is the outcome of running a test ROM.
+type Result struct {
+	Status Status `json:"status"`
+	Source Source `json:"source,omitempty"`
+	Output string `json:"output,omitempty"` // Serial output, or the text written in RAM by Blargg tests
+	Cycles uint64 `json:"cycles"`
+
+	// Registers B, C, D, E, H and L when the Mooneye breakpoint was hit
+	Registers *[6]byte `json:"registers,omitempty"`
+
+	// Screen is the last frame drawn by the PPU
+	Screen *image.RGBA `json:"-"`
+}
+
+// Run loads the ROM at path and runs it until it reports a result or the budget runs out.
+func Run(romPath string, options Options) (Result, error) {
+	cartridge, err := cart.NewCartridge(romPath, &log.PanicLogger{})
+	if err != nil {
+		return Result{}, err
+	}
+	return RunCartridge(cartridge, options)
+}
+
+// RunCartridge runs a cartridge until it reports a result or the budget runs out. Errors that would stop the
+// emulator, like unimplemented instructions, are returned.
+func RunCartridge(cartridge cart.CartridgeInterface, options Options) (result Result, err error) {
+	logger := &log.PanicLogger{}
+	defer log.Recover(&err)
+
+	memoryBus := bus.NewBus(cartridge, logger)
+	var serialOutput bytes.Buffer
+	memoryBus.SetSerialOutput(&serialOutput)
+
+	gbCpu := cpu.Init(memoryBus, logger)
+	gbPpu := ppu.Init(memoryBus, logger)
+	gbCpu.AddTicker(gbPpu)
+
+	detector := &mooneyeDetector{bus: memoryBus}
+	gbCpu.SetTracer(detector)
+
+	finish := func(result Result) (Result, error) {
+		result.Cycles = gbCpu.Cycles()
+		result.Screen = frameImage(gbPpu.Framebuffer())
+		return result, nil
+	}
+
+	budget := options.cycleBudget()
+	for steps := 1; budget == 0 || gbCpu.Cycles() < budget; steps++ {
+		gbCpu.Step()
+
+		if detector.hit {
+			return finish(detector.result())
+		}
+
+		if options.StopAtBreakpoint && detector.breakpoint {
+			// Let the PPU finish the frame, unless the LCD is off
+			frame, limit := gbPpu.FrameCount(), gbCpu.Cycles()+2*FrameCycles
+			for gbPpu.FrameCount() == frame && gbCpu.Cycles() < limit {
+				gbCpu.Step()
+			}
+			return finish(Result{Status: Breakpoint})
+		}
+
+		if steps%checkInterval == 0 {
+			if result, ok := checkBlargg(memoryBus, serialOutput.String()); ok {
+				return finish(result)
+			}
+		}
+	}
+
+	result, _ = checkBlargg(memoryBus, serialOutput.String())
+	result.Status = Timeout
+	return finish(result)
+}
+
+// checkBlargg looks for a Blargg result in the serial output and then in the cartridge RAM. The returned
+// result has the output so far even when there's no result yet.
+func checkBlargg(memoryBus *bus.Bus, serialOutput string) (Result, bool) {
+	if status, ok := serialStatus(serialOutput); ok {
+		return Result{Status: status, Source: SourceSerial, Output: serialOutput}, true
+	}
+
+	for i, b := range blarggSignature {
+		if memoryBus.Peek(blarggSignatureAddr+uint16(i)) != b {
+			return Result{Output: serialOutput}, false
+		}
+	}
+
+	text := ramText(memoryBus)
+	switch code := memoryBus.Peek(blarggStatusAddr); code {
+	case blarggRunning:
+		return Result{Source: SourceRam, Output: text}, false
+	case 0x00:
+		return Result{Status: Passed, Source: SourceRam, Output: text}, true
+	default:
+		return Result{Status: Failed, Source: SourceRam, Output: text}, true
+	}
+}
+
+// serialStatus returns the status printed by Blargg tests through the serial port.
+func serialStatus(output string) (Status, bool) {
+	switch {
+	case strings.Contains(output, "Passed"):
+		return Passed, true
+	case strings.Contains(output, "Failed"):
+		return Failed, true
+	}
+	return Timeout, false
+}
+
+// ramText reads the zero terminated text Blargg tests write after the signature.
+func ramText(memoryBus *bus.Bus) string {
+	var text strings.Builder
+	for i := uint16(0); i < blarggTextMaxLength; i++ {
+		b := memoryBus.Peek(blarggTextAddr + i)
+		if b == 0 {
+			break
+		}
+		text.WriteByte(b)
+	}
+	return text.String()
+}
+
+// mooneyeDetector watches for the LD B,B breakpoint. It implements cpu.Tracer.
+type mooneyeDetector struct {
+	bus        *bus.Bus
+	breakpoint bool // Whether LD B,B has been executed
+	hit        bool // Whether LD B,B has been executed with a Mooneye result in the registers
+	registers  [6]byte
+}
+
+func (m *mooneyeDetector) Trace(c *cpu.CPU) {
+	if m.hit || m.bus.Peek(c.InstructionPC()) != mooneyeBreakpoint {
+		return
+	}
+
+	r := c.Registers()
+	m.breakpoint = true
+	m.registers = [6]byte{r.B, r.C, r.D, r.E, r.H, r.L}
+	m.hit = m.registers == mooneyePass || m.registers == mooneyeFail
+}
+
+func (m *mooneyeDetector) result() Result {
+	registers := m.registers
+	result := Result{Status: Failed, Source: SourceMooneye, Registers: &registers}
+	if m.registers == mooneyePass {
+		result.Status = Passed
+	}
+	return result
+}
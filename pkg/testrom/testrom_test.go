@@ -0,0 +1,107 @@
+package testrom
+
+import (
+	"github.com/mikeletux/goboy/pkg/test"
+	"os"
+	"testing"
+)
+
+// mooneyeProgram loads the given values in B, C, D, E, H and L and hits the LD B,B breakpoint
+func mooneyeProgram(values [6]byte) *test.RomMock {
+	return test.NewRomMock(map[uint16][]byte{0x100: {
+		0x06, values[0], // LD B,d8
+		0x0E, values[1], // LD C,d8
+		0x16, values[2], // LD D,d8
+		0x1E, values[3], // LD E,d8
+		0x26, values[4], // LD H,d8
+		0x2E, values[5], // LD L,d8
+		0x40,       // LD B,B
+		0x18, 0xFE, // JR -2
+	}})
+}
+
+func TestMooneye(t *testing.T) {
+	result, err := RunCartridge(mooneyeProgram(mooneyePass), DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != Passed || result.Source != SourceMooneye {
+		t.Errorf("expected mooneye pass got %+v", result)
+	}
+
+	result, err = RunCartridge(mooneyeProgram(mooneyeFail), DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != Failed || result.Source != SourceMooneye {
+		t.Errorf("expected mooneye failure got %+v", result)
+	}
+}
+
+func TestBlarggRam(t *testing.T) {
+	cartridge := test.NewRomMock(map[uint16][]byte{0x100: {
+		0x3E, 0xDE, 0xEA, 0x01, 0xA0, // LD A,$DE ; LD ($A001),A
+		0x3E, 0xB0, 0xEA, 0x02, 0xA0, // LD A,$B0 ; LD ($A002),A
+		0x3E, 0x61, 0xEA, 0x03, 0xA0, // LD A,$61 ; LD ($A003),A
+		0x3E, 'o', 0xEA, 0x04, 0xA0, // LD A,'o' ; LD ($A004),A
+		0x3E, 'k', 0xEA, 0x05, 0xA0, // LD A,'k' ; LD ($A005),A
+		0xAF,             // XOR A
+		0xEA, 0x06, 0xA0, // LD ($A006),A
+		0xEA, 0x00, 0xA0, // LD ($A000),A
+		0x18, 0xFE, // JR -2
+	}})
+
+	result, err := RunCartridge(cartridge, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != Passed || result.Source != SourceRam || result.Output != "ok" {
+		t.Errorf("expected blargg pass with text ok got %+v", result)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	result, err := RunCartridge(test.NewRomMock(map[uint16][]byte{0x100: {0x18, 0xFE}}), Options{MaxFrames: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != Timeout || result.Cycles < 2*FrameCycles {
+		t.Errorf("expected timeout after 2 frames got %+v", result)
+	}
+}
+
+func TestFatalError(t *testing.T) {
+	// 0xD3 is not an opcode
+	if _, err := RunCartridge(test.NewRomMock(map[uint16][]byte{0x100: {0xD3}}), DefaultOptions); err == nil {
+		t.Error("expected error running an invalid opcode")
+	}
+}
+
+func TestSerialStatus(t *testing.T) {
+	tests := []struct {
+		output string
+		status Status
+		ok     bool
+	}{
+		{"01-special\n\n\nPassed\n", Passed, true},
+		{"02-interrupts\n\n\nEI\nFailed #2\n", Failed, true},
+		{"03-op sp,hl\n\n", Timeout, false},
+	}
+
+	for _, test := range tests {
+		status, ok := serialStatus(test.output)
+		if status != test.status || ok != test.ok {
+			t.Errorf("%q: expected %s %t got %s %t", test.output, test.status, test.ok, status, ok)
+		}
+	}
+}
+
+// TestRoms runs the test ROMs under the directory set in GOBOY_TEST_ROMS, like a checkout of gb-test-roms.
+func TestRoms(t *testing.T) {
+	dir := os.Getenv("GOBOY_TEST_ROMS")
+	if len(dir) == 0 {
+		t.Skip("GOBOY_TEST_ROMS is not set")
+	}
+
+	RunDir(t, dir, DefaultOptions)
+}
@@ -0,0 +1,171 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/disasm"
+	"io"
+	"os"
+	"strings"
+)
+
+// gzipMagic are the first bytes of any gzip file.
+var gzipMagic = []byte{0x1F, 0x8B}
+
+// FieldDiff is a field of a trace line that doesn't match the reference.
+type FieldDiff struct {
+	Name     string
+	Expected string
+	Got      string
+}
+
+// Divergence describes the first instruction whose state doesn't match the reference log.
+type Divergence struct {
+	Line        uint64   // Line of the reference log, starting at 1
+	Context     []string // Reference lines before the divergence, which matched
+	Expected    string
+	Got         string
+	Fields      []FieldDiff
+	Disassembly string // Instruction executed by the emulator at that point
+}
+
+// Differ compares the state before every instruction with a reference log in the Gameboy Doctor format. It
+// stops at the first divergence. It implements cpu.Tracer.
+type Differ struct {
+	reference    *bufio.Scanner
+	bus          *bus.Bus
+	disassembler *disasm.Disassembler
+
+	contextLines int
+	context      []string
+	line         uint64 // Reference lines read
+
+	done       bool
+	divergence *Divergence
+	err        error
+}
+
+// OpenReference opens a reference log, which can be gzip compressed.
+func OpenReference(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(f)
+	magic, _ := reader.Peek(len(gzipMagic))
+	if !bytes.Equal(magic, gzipMagic) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{reader, f}, nil
+	}
+
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gzipReader, f}, nil
+}
+
+// NewDiffer returns a differ reading the reference log from r. contextLines is the number of matching lines
+// kept to show before a divergence.
+func NewDiffer(r io.Reader, b *bus.Bus, contextLines int) *Differ {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, bufferSize), bufferSize)
+
+	return &Differ{
+		reference:    scanner,
+		bus:          b,
+		disassembler: disasm.New(nil),
+		contextLines: contextLines,
+	}
+}
+
+// Trace compares the instruction about to be executed with the next line of the reference log.
+func (d *Differ) Trace(c *cpu.CPU) {
+	if d.done {
+		return
+	}
+
+	expected, ok := d.next()
+	if !ok {
+		d.done = true
+		return
+	}
+
+	got := doctorLine(c, d.bus.Peek)
+	if fields := diffFields(expected, got); len(fields) > 0 {
+		pc := c.InstructionPC()
+		d.divergence = &Divergence{
+			Line:        d.line,
+			Context:     d.context,
+			Expected:    expected,
+			Got:         got,
+			Fields:      fields,
+			Disassembly: d.disassembler.Decode(d.bus.Peek, pc, d.bus.RomBank(disasm.RomxStart)).Text,
+		}
+		d.done = true
+		return
+	}
+
+	if d.contextLines > 0 {
+		if len(d.context) == d.contextLines {
+			d.context = d.context[1:]
+		}
+		d.context = append(d.context, expected)
+	}
+}
+
+// next returns the next reference line, skipping empty lines and comments.
+func (d *Differ) next() (string, bool) {
+	for d.reference.Scan() {
+		d.line++
+
+		line := d.reference.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			return line, true
+		}
+	}
+
+	d.err = d.reference.Err()
+	return "", false
+}
+
+// Done returns whether the comparison is over, because of a divergence or because the reference log ended.
+func (d *Differ) Done() bool {
+	return d.done
+}
+
+// Result returns the divergence found, nil if none, and the number of reference lines read.
+func (d *Differ) Result() (*Divergence, uint64, error) {
+	return d.divergence, d.line, d.err
+}
+
+// diffFields compares two lines made of NAME:VALUE fields, returning the ones that differ in expected order.
+func diffFields(expected, got string) []FieldDiff {
+	gotFields := make(map[string]string)
+	for _, field := range strings.Fields(got) {
+		name, value, _ := strings.Cut(field, ":")
+		gotFields[name] = value
+	}
+
+	var diffs []FieldDiff
+	for _, field := range strings.Fields(expected) {
+		name, value, _ := strings.Cut(field, ":")
+		if gotValue, ok := gotFields[name]; !ok || !strings.EqualFold(gotValue, value) {
+			diffs = append(diffs, FieldDiff{Name: name, Expected: value, Got: gotValue})
+		}
+	}
+	return diffs
+}
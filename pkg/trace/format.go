@@ -0,0 +1,128 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/disasm"
+)
+
+// Format is the layout of the trace lines.
+type Format int
+
+const (
+	// FormatDoctor is the format expected by Gameboy Doctor:
+	// A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,C3,13,02
+	FormatDoctor Format = iota
+	// FormatBGB is the format of the BGB debugger trace logs:
+	// A:01 F:Z-HC BC:0013 DE:00D8 HL:014D SP:FFFE PC:0100 (cy: 0)
+	FormatBGB
+	// FormatJSON writes a JSON object per line with the registers, cycle count, bank and disassembly.
+	FormatJSON
+)
+
+var formatNames = map[Format]string{
+	FormatDoctor: "doctor",
+	FormatBGB:    "bgb",
+	FormatJSON:   "json",
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// ParseFormat returns the format with the given name: doctor, bgb or json.
+func ParseFormat(name string) (Format, error) {
+	for format, formatName := range formatNames {
+		if formatName == name {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown trace format %q, expected doctor, bgb or json", name)
+}
+
+// jsonLine is a trace line in FormatJSON.
+type jsonLine struct {
+	Cycle  uint64 `json:"cycle"` // T-cycles since power on
+	Bank   int    `json:"bank"`  // ROM bank of PC
+	PC     uint16 `json:"pc"`
+	SP     uint16 `json:"sp"`
+	A      byte   `json:"a"`
+	F      byte   `json:"f"`
+	B      byte   `json:"b"`
+	C      byte   `json:"c"`
+	D      byte   `json:"d"`
+	E      byte   `json:"e"`
+	H      byte   `json:"h"`
+	L      byte   `json:"l"`
+	Disasm string `json:"disasm"`
+	Label  string `json:"label,omitempty"`
+}
+
+func (f Format) write(t *Tracer, c *cpu.CPU) error {
+	r := c.Registers()
+	pc := c.InstructionPC()
+
+	switch f {
+	case FormatDoctor:
+		_, err := fmt.Fprintf(t.writer, "%s%s\n", doctorLine(c, t.bus.Peek), t.labelSuffix(pc))
+		return err
+
+	case FormatBGB:
+		flags := []byte("----")
+		for i, set := range []bool{r.GetFZ(), r.GetFN(), r.GetFH(), r.GetFC()} {
+			if set {
+				flags[i] = "ZNHC"[i]
+			}
+		}
+
+		_, err := fmt.Fprintf(t.writer, "A:%02X F:%s BC:%04X DE:%04X HL:%04X SP:%04X PC:%04X (cy: %d)%s\n",
+			r.A, flags, r.GetBC(), r.GetDE(), r.GetHL(), r.SP, pc, c.Cycles(), t.labelSuffix(pc))
+		return err
+
+	case FormatJSON:
+		line := jsonLine{
+			Cycle:  c.Cycles(),
+			Bank:   t.bus.RomBank(pc),
+			PC:     pc,
+			SP:     r.SP,
+			A:      r.A,
+			F:      r.F,
+			B:      r.B,
+			C:      r.C,
+			D:      r.D,
+			E:      r.E,
+			H:      r.H,
+			L:      r.L,
+			Disasm: t.disassembler.Decode(t.bus.Peek, pc, t.bus.RomBank(disasm.RomxStart)).Text,
+		}
+		line.Label, _ = t.label(pc)
+
+		bytes, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		_, err = t.writer.Write(append(bytes, '\n'))
+		return err
+	}
+
+	return fmt.Errorf("unknown trace format %d", f)
+}
+
+// doctorLine returns the state of the CPU in the Gameboy Doctor format.
+func doctorLine(c *cpu.CPU, read disasm.Reader) string {
+	r := c.Registers()
+	pc := c.InstructionPC()
+
+	return fmt.Sprintf("A:%02X F:%02X B:%02X C:%02X D:%02X E:%02X H:%02X L:%02X SP:%04X PC:%04X PCMEM:%02X,%02X,%02X,%02X",
+		r.A, r.F, r.B, r.C, r.D, r.E, r.H, r.L, r.SP, pc, read(pc), read(pc+1), read(pc+2), read(pc+3))
+}
+
+// labelSuffix returns " ; Label" when there's a symbol at address. It goes at the end so the lines can still
+// be compared by prefix with the ones of other emulators.
+func (t *Tracer) labelSuffix(address uint16) string {
+	if label, ok := t.label(address); ok {
+		return " ; " + label
+	}
+	return ""
+}
@@ -0,0 +1,157 @@
+// Package trace writes a line per executed instruction, to compare the emulator against others or find where
+// a game goes wrong. Tracing is enabled by setting a Tracer on the CPU.
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/disasm"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const bufferSize = 1 << 16
+
+// Window selects the instructions that are traced.
+type Window struct {
+	Start  uint64 // Number of instructions executed before tracing starts
+	Count  uint64 // Number of instructions traced, 0 for no limit
+	FromPC uint16 // Only instructions in FromPC-ToPC are traced
+	ToPC   uint16
+}
+
+// FullWindow traces every instruction.
+var FullWindow = Window{FromPC: 0x0000, ToPC: 0xFFFF}
+
+// ParsePCRange parses a range like 0x0150-0x01FF. Addresses are hexadecimal, the 0x prefix is optional.
+func ParsePCRange(s string) (from, to uint16, err error) {
+	fromField, toField, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid PC range %q, expected from-to", s)
+	}
+
+	parse := func(field string) (uint16, error) {
+		value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(field), "0x"), 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("invalid address %q in PC range", field)
+		}
+		return uint16(value), nil
+	}
+
+	if from, err = parse(fromField); err != nil {
+		return 0, 0, err
+	}
+	if to, err = parse(toField); err != nil {
+		return 0, 0, err
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid PC range %q, start is after the end", s)
+	}
+	return from, to, nil
+}
+
+// Tracer writes the instructions executed by the CPU. Output is buffered, so Close must be called to get the
+// last lines written.
+type Tracer struct {
+	format Format
+	window Window
+	bus    *bus.Bus
+	writer *bufio.Writer
+	closer io.Closer // Underlying file, if the tracer opened it
+
+	disassembler *disasm.Disassembler
+	symbols      *symbols.Table
+
+	executed uint64 // Instructions executed so far
+	done     bool   // Whether the window is over
+	err      error  // First write error, tracing stops after it
+}
+
+// New returns a tracer writing to w. Memory is read with bus.Peek so tracing doesn't trigger watchpoints.
+func New(w io.Writer, format Format, window Window, b *bus.Bus) *Tracer {
+	return &Tracer{
+		format:       format,
+		window:       window,
+		bus:          b,
+		writer:       bufio.NewWriterSize(w, bufferSize),
+		disassembler: disasm.New(nil),
+	}
+}
+
+// Create returns a tracer writing to the file at path, which is truncated.
+func Create(path string, format Format, window Window, b *bus.Bus) (*Tracer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := New(f, format, window, b)
+	t.closer = f
+	return t, nil
+}
+
+// SetSymbols sets the symbols used to label the traced instructions.
+func (t *Tracer) SetSymbols(table *symbols.Table) {
+	t.symbols = table
+	t.disassembler.Symbols = table
+}
+
+// Trace writes the instruction the CPU is about to execute if it is in the window. It implements cpu.Tracer.
+func (t *Tracer) Trace(c *cpu.CPU) {
+	if t.done {
+		return
+	}
+
+	index := t.executed
+	t.executed++
+	if index < t.window.Start {
+		return
+	}
+
+	if t.window.Count > 0 && index >= t.window.Start+t.window.Count {
+		t.done = true
+		t.Flush() // Nothing else will be written, so the trace is complete even if the emulator is killed
+		return
+	}
+
+	pc := c.InstructionPC()
+	if pc < t.window.FromPC || pc > t.window.ToPC {
+		return
+	}
+
+	if err := t.format.write(t, c); err != nil {
+		t.err = err
+		t.done = true
+	}
+}
+
+// Flush writes the buffered lines.
+func (t *Tracer) Flush() error {
+	if err := t.writer.Flush(); err != nil && t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// Close flushes the trace and closes the file opened by Create. It returns the first error found writing.
+func (t *Tracer) Close() error {
+	t.done = true
+	err := t.Flush()
+
+	if t.closer != nil {
+		if closeErr := t.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// label returns the symbol at address with the ROM bank currently mapped.
+func (t *Tracer) label(address uint16) (string, bool) {
+	return t.symbols.Lookup(t.bus.RomBank(address), address)
+}
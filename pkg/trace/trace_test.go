@@ -0,0 +1,210 @@
+package trace
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/cart"
+	"github.com/mikeletux/goboy/pkg/cpu"
+	"github.com/mikeletux/goboy/pkg/log"
+	"github.com/mikeletux/goboy/pkg/symbols"
+	"github.com/mikeletux/goboy/pkg/test"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loopProgram runs NOP, INC A and then loops forever: NOP ; INC A ; JR -2
+var loopProgram = map[uint16][]byte{0x100: {0x00, 0x3C, 0x18, 0xFE}}
+
+func runTrace(t *testing.T, format Format, window Window, table *symbols.Table, steps int) []string {
+	memoryBus := bus.NewBus(test.NewRomMock(loopProgram), &log.NilLogger{})
+	gbCpu := cpu.Init(memoryBus, &log.NilLogger{})
+
+	var out bytes.Buffer
+	tracer := New(&out, format, window, memoryBus)
+	tracer.SetSymbols(table)
+	gbCpu.SetTracer(tracer)
+
+	for i := 0; i < steps; i++ {
+		gbCpu.Step()
+	}
+	if err := tracer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestDoctorFormat(t *testing.T) {
+	lines := runTrace(t, FormatDoctor, FullWindow, nil, 3)
+
+	expected := []string{
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0100 PCMEM:00,3C,18,FE",
+		"A:01 F:B0 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0101 PCMEM:3C,18,FE,00",
+		"A:02 F:10 B:00 C:13 D:00 E:D8 H:01 L:4D SP:FFFE PC:0102 PCMEM:18,FE,00,00",
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestBGBFormatWithSymbols(t *testing.T) {
+	table, err := symbols.Parse(strings.NewReader("00:0101 Increment\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := runTrace(t, FormatBGB, FullWindow, table, 2)
+
+	expected := "A:01 F:Z-HC BC:0013 DE:00D8 HL:014D SP:FFFE PC:0101 (cy: 4) ; Increment"
+	if len(lines) != 2 || lines[1] != expected {
+		t.Errorf("expected second line %q, got %q", expected, lines)
+	}
+}
+
+func TestSymbolsInSwitchedBank(t *testing.T) {
+	// 64 KiB MBC1 ROM that switches to bank 2 and jumps to it: LD A,$02 ; LD ($2000),A ; JP $4000
+	rom := make([]byte, 0x10000)
+	copy(rom[0x100:], []byte{0x3E, 0x02, 0xEA, 0x00, 0x20, 0xC3, 0x00, 0x40})
+	copy(rom[0x8000:], []byte{0x18, 0xFE}) // Bank 2: JR -2
+	rom[cart.CartridgeTypeAddr] = 0x01
+	rom[cart.RomSizeAddr] = 0x01
+	var checksum byte
+	for address := cart.TitleAddrStart; address <= cart.MaskRomVersionNumberAddr; address++ {
+		checksum = checksum - rom[address] - 1
+	}
+	rom[cart.HeaderChecksumAddr] = checksum
+
+	cartridge, err := cart.NewCartridgeFromBytes(rom, &log.NilLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := symbols.Parse(strings.NewReader("01:4000 NearLoop\n02:4000 FarLoop\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	memoryBus := bus.NewBus(cartridge, &log.NilLogger{})
+	gbCpu := cpu.Init(memoryBus, &log.NilLogger{})
+	var out bytes.Buffer
+	tracer := New(&out, FormatDoctor, FullWindow, memoryBus)
+	tracer.SetSymbols(table)
+	gbCpu.SetTracer(tracer)
+
+	for i := 0; i < 4; i++ {
+		gbCpu.Step()
+	}
+	if err := tracer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 || !strings.HasSuffix(lines[3], "PC:4000 PCMEM:18,FE,00,00 ; FarLoop") {
+		t.Errorf("expected the last line to be labelled with the bank 2 symbol, got %q", lines)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	lines := runTrace(t, FormatJSON, FullWindow, nil, 3)
+
+	var line jsonLine
+	if err := json.Unmarshal([]byte(lines[2]), &line); err != nil {
+		t.Fatal(err)
+	}
+
+	if line.PC != 0x102 || line.A != 0x02 || line.Cycle != 8 || line.Disasm != "JR $0102" {
+		t.Errorf("unexpected line %+v", line)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	lines := runTrace(t, FormatDoctor, Window{Start: 1, Count: 3, FromPC: 0x0000, ToPC: 0xFFFF}, nil, 10)
+	if len(lines) != 3 || !strings.Contains(lines[0], "PC:0101") {
+		t.Errorf("expected 3 lines starting at 0101, got %q", lines)
+	}
+
+	lines = runTrace(t, FormatDoctor, Window{FromPC: 0x0101, ToPC: 0x0101}, nil, 10)
+	if len(lines) != 1 || !strings.Contains(lines[0], "PC:0101") {
+		t.Errorf("expected only the instruction at 0101, got %q", lines)
+	}
+}
+
+func TestParsePCRange(t *testing.T) {
+	from, to, err := ParsePCRange("0x150-01ff")
+	if err != nil || from != 0x150 || to != 0x1FF {
+		t.Errorf("expected 0150-01FF got %04X-%04X %v", from, to, err)
+	}
+
+	for _, invalid := range []string{"150", "200-100", "150-zz"} {
+		if _, _, err := ParsePCRange(invalid); err == nil {
+			t.Errorf("expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestDiffer(t *testing.T) {
+	reference := runTrace(t, FormatDoctor, FullWindow, nil, 3)
+	reference[2] = strings.Replace(reference[2], "A:02", "A:03", 1)
+
+	// The reference is gzip compressed to check it's detected
+	path := filepath.Join(t.TempDir(), "reference.log.gz")
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(strings.Join(reference, "\n") + "\n"))
+	writer.Close()
+	if err := os.WriteFile(path, compressed.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := OpenReference(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	memoryBus := bus.NewBus(test.NewRomMock(loopProgram), &log.NilLogger{})
+	gbCpu := cpu.Init(memoryBus, &log.NilLogger{})
+	differ := NewDiffer(r, memoryBus, 1)
+	gbCpu.SetTracer(differ)
+
+	for i := 0; i < 10 && !differ.Done(); i++ {
+		gbCpu.Step()
+	}
+
+	divergence, _, err := differ.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if divergence == nil {
+		t.Fatal("expected a divergence")
+	}
+
+	if divergence.Line != 3 || len(divergence.Context) != 1 || divergence.Disassembly != "JR $0102" {
+		t.Errorf("unexpected divergence %+v", divergence)
+	}
+	expected := []FieldDiff{{Name: "A", Expected: "03", Got: "02"}}
+	if len(divergence.Fields) != 1 || divergence.Fields[0] != expected[0] {
+		t.Errorf("expected fields %v got %v", expected, divergence.Fields)
+	}
+}
+
+func TestDifferMatch(t *testing.T) {
+	reference := runTrace(t, FormatDoctor, FullWindow, nil, 5)
+
+	memoryBus := bus.NewBus(test.NewRomMock(loopProgram), &log.NilLogger{})
+	gbCpu := cpu.Init(memoryBus, &log.NilLogger{})
+	differ := NewDiffer(strings.NewReader(strings.Join(reference, "\n")), memoryBus, 5)
+	gbCpu.SetTracer(differ)
+
+	for i := 0; i < 10 && !differ.Done(); i++ {
+		gbCpu.Step()
+	}
+
+	divergence, lines, err := differ.Result()
+	if divergence != nil || lines != 5 || err != nil {
+		t.Errorf("expected 5 matching lines, got %d %+v %v", lines, divergence, err)
+	}
+}
@@ -0,0 +1,352 @@
+package vram
+
+import (
+	"fmt"
+	"github.com/mikeletux/goboy/pkg/bus"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"image"
+	"strings"
+)
+
+const (
+	tilesPerRow   = 16
+	tilesPerBank  = 384
+	bankWidth     = tilesPerRow * 8
+	objectColumns = 4
+	objectWidth   = Width / objectColumns
+	objectHeight  = 25
+	swatchSize    = 12
+	paletteRowTop = 2
+	paletteHeight = swatchSize + 4
+)
+
+// drawTiles draws the 384 tiles of each VRAM bank, 16 per row, with the palette chosen.
+func (v *Viewer) drawTiles() []string {
+	banks := 1
+	if v.memory.IsCgbMode() {
+		banks = 2
+	}
+
+	palettes := v.palettes()
+	if v.palette >= len(palettes) {
+		v.palette = 0
+	}
+	chosen := palettes[v.palette]
+	color := func(colorIndex byte) uint32 { return v.color(chosen, colorIndex) }
+
+	for bank := 0; bank < banks; bank++ {
+		for tile := 0; tile < tilesPerBank; tile++ {
+			x, y := bank*bankWidth+tile%tilesPerRow*8, tile/tilesPerRow*8
+			v.drawTile(x, y, byte(bank), bus.VramStart+uint16(tile)*16, false, false, color)
+		}
+	}
+
+	legendY := tilesPerBank / tilesPerRow * 8
+	v.canvas.Text(2, legendY+contentTop+4, "PALETTE "+chosen.name, textColor, backgroundColor)
+	for i := byte(0); i < 4; i++ {
+		x := 50 + int(i)*(swatchSize+2)
+		v.canvas.Box(x, legendY+contentTop+2, x+swatchSize-1, legendY+contentTop+2+swatchSize-1,
+			v.color(chosen, i), textColor)
+	}
+
+	status := []string{"[ AND ] OR A CLICK IN PALETTES CHANGE THE PALETTE"}
+	point, ok := v.contentHover()
+	if !ok || point.X >= banks*bankWidth || point.Y >= legendY {
+		return status
+	}
+
+	bank, tile := point.X/bankWidth, point.Y/8*tilesPerRow+point.X%bankWidth/8
+	x, y := bank*bankWidth+tile%tilesPerRow*8, tile/tilesPerRow*8
+	v.outline(image.Rect(x, y, x+8, y+8), hoverColor)
+	return []string{fmt.Sprintf("TILE %03X BANK %d ADDR %04X INDEX %02X", tile, bank,
+		bus.VramStart+uint16(tile)*16, byte(tile)), tileUse(tile)}
+}
+
+// tileUse returns which addressing modes can show a tile: objects and LCDC.4=1 use indexes from 0x8000,
+// LCDC.4=0 uses signed indexes from 0x9000.
+func tileUse(tile int) string {
+	switch {
+	case tile < 128:
+		return "OBJ AND BG/WIN WITH LCDC.4=1"
+	case tile < 256:
+		return "OBJ AND BG/WIN WITH EITHER LCDC.4"
+	default:
+		return "BG/WIN WITH LCDC.4=0"
+	}
+}
+
+// drawMap draws a 32x32 tile map as the background would use it, with the viewport and the window.
+func (v *Viewer) drawMap(base uint16) []string {
+	cgb := v.memory.IsCgbMode()
+	lcdc := v.memory.Peek(lcdcRegisterAddr)
+	bgp := v.memory.Peek(bgpRegisterAddr)
+
+	for tile := uint16(0); tile < 32*32; tile++ {
+		address := base + tile
+		index := v.memory.ReadVRamBank(0, address)
+
+		var attributes ppu.BgMapAttributes
+		if cgb {
+			attributes = ppu.NewBgMapAttributes(v.memory.ReadVRamBank(1, address))
+		}
+		color := func(colorIndex byte) uint32 {
+			if cgb {
+				return ppu.CgbPaletteColor(v.memory.ReadBgPaletteRam, attributes.GetCGBPalette(), colorIndex)
+			}
+			return ppu.DmgColor(bgp, colorIndex)
+		}
+
+		v.drawTile(int(tile%32)*8, int(tile/32)*8, attributes.GetVramBank(), ppu.BgTileAddress(lcdc, index),
+			attributes.GetXFlip(), attributes.GetYFlip(), color)
+	}
+
+	scx, scy := int(v.memory.Peek(scxRegisterAddr)), int(v.memory.Peek(scyRegisterAddr))
+	wx, wy := int(v.memory.Peek(wxRegisterAddr))-7, int(v.memory.Peek(wyRegisterAddr))
+	bgMap, windowMap := mapOf(lcdc, lcdcBgTileMapBit), mapOf(lcdc, lcdcWindowTileMapBit)
+	if bgMap == base {
+		v.wrappedOutline(scx, scy, ppu.ScreenWidth, ppu.ScreenHeight, viewportColor)
+	}
+	windowShown := lcdc&lcdcWindowEnableBit != 0 && wx < ppu.ScreenWidth && wy < ppu.ScreenHeight
+	if windowMap == base && windowShown {
+		if wx < 0 {
+			wx = 0
+		}
+		v.wrappedOutline(0, 0, ppu.ScreenWidth-wx, ppu.ScreenHeight-wy, windowColor)
+	}
+
+	var uses []string
+	if bgMap == base {
+		uses = append(uses, "BG")
+	}
+	if windowMap == base {
+		uses = append(uses, "WINDOW")
+	}
+	if len(uses) == 0 {
+		uses = append(uses, "NOTHING")
+	}
+	status := []string{
+		fmt.Sprintf("SCX %02X SCY %02X WX %02X WY %02X LCDC %02X", scx, scy, wx+7, wy, lcdc),
+		"USED BY " + strings.Join(uses, " AND "),
+	}
+
+	point, ok := v.contentHover()
+	if !ok {
+		return status
+	}
+	x, y := point.X/8, point.Y/8
+	v.outline(image.Rect(x*8, y*8, x*8+8, y*8+8), hoverColor)
+
+	address := base + uint16(y*32+x)
+	index := v.memory.ReadVRamBank(0, address)
+	hovered := fmt.Sprintf("X %02d Y %02d ADDR %04X TILE %02X AT %04X", x, y, address, index,
+		ppu.BgTileAddress(lcdc, index))
+	if !cgb {
+		return []string{hovered, status[0]}
+	}
+
+	attributes := ppu.NewBgMapAttributes(v.memory.ReadVRamBank(1, address))
+	details := fmt.Sprintf("ATTR %02X PAL %d BANK %d", v.memory.ReadVRamBank(1, address),
+		attributes.GetCGBPalette(), attributes.GetVramBank())
+	details += flags(attributes.GetXFlip(), attributes.GetYFlip(), attributes.GetPriority(), "ABOVE OBJ")
+	return []string{hovered, details}
+}
+
+// mapOf returns the tile map selected by an LCDC bit.
+func mapOf(lcdc, bit byte) uint16 {
+	if lcdc&bit != 0 {
+		return 0x9C00
+	}
+	return 0x9800
+}
+
+// wrappedOutline draws the outline of a rectangle of a tile map, wrapping around its edges like the PPU does.
+func (v *Viewer) wrappedOutline(x, y, width, height int, color uint32) {
+	set := func(px, py int) {
+		v.canvas.Set(px&0xFF, py&0xFF+contentTop, color)
+	}
+	for i := 0; i < width; i++ {
+		set(x+i, y)
+		set(x+i, y+height-1)
+	}
+	for i := 1; i < height-1; i++ {
+		set(x, y+i)
+		set(x+width-1, y+i)
+	}
+}
+
+// flags returns the names of the flip and priority flags set.
+func flags(xFlip, yFlip, priority bool, priorityName string) string {
+	var names string
+	if xFlip {
+		names += " XFLIP"
+	}
+	if yFlip {
+		names += " YFLIP"
+	}
+	if priority {
+		names += " " + priorityName
+	}
+	return names
+}
+
+// object returns the object number i of OAM.
+func (v *Viewer) object(i int) ppu.OamEntry {
+	address := bus.OamStart + uint16(i)*4
+	return ppu.NewOamEntry(v.memory.Peek(address), v.memory.Peek(address+1), v.memory.Peek(address+2),
+		v.memory.Peek(address+3))
+}
+
+// objectPalette returns the palette an object is drawn with.
+func (v *Viewer) objectPalette(object ppu.OamEntry) palette {
+	if v.memory.IsCgbMode() {
+		return palette{name: "OBJ" + string('0'+object.GetCGBPalette()), object: true, number: object.GetCGBPalette()}
+	}
+	if object.GetDMGPalette() {
+		return palette{name: "OBP1", object: true, register: obp1RegisterAddr}
+	}
+	return palette{name: "OBP0", object: true, register: obp0RegisterAddr}
+}
+
+// drawOam draws the 40 objects of OAM with their position, tile and attributes. Hidden objects are dimmed.
+func (v *Viewer) drawOam() []string {
+	cgb := v.memory.IsCgbMode()
+	tall := v.memory.Peek(lcdcRegisterAddr)&lcdcObjSizeBit != 0
+
+	for i := 0; i < 40; i++ {
+		object := v.object(i)
+		x, y := i%objectColumns*objectWidth, i/objectColumns*objectHeight
+
+		bank := byte(0)
+		if cgb {
+			bank = object.GetCGBVramBank()
+		}
+		p := v.objectPalette(object)
+		color := func(colorIndex byte) uint32 {
+			if colorIndex == 0 {
+				return 0 // Transparent
+			}
+			return v.color(p, colorIndex)
+		}
+
+		v.canvas.Box(x+1, y+1+contentTop, x+10, y+18+contentTop, 0xFF303030, 0xFF303030)
+		tiles := []byte{object.GetTileIndex()}
+		if tall {
+			tiles = []byte{object.GetTileIndex() &^ 1, object.GetTileIndex() | 1}
+			if object.GetYFlip() {
+				tiles[0], tiles[1] = tiles[1], tiles[0]
+			}
+		}
+		for row, tile := range tiles {
+			v.drawTile(x+2, y+2+row*8, bank, bus.VramStart+uint16(tile)*16, object.GetXFlip(), object.GetYFlip(),
+				color)
+		}
+
+		infoColor := textColor
+		if object.GetY() == 0 || object.GetY() >= ppu.ScreenHeight+16 || object.GetX() == 0 ||
+			object.GetX() >= ppu.ScreenWidth+8 {
+			infoColor = dimTextColor
+		}
+		attributes := p.name
+		if cgb {
+			attributes = fmt.Sprintf("P%dB%d", object.GetCGBPalette(), bank)
+		}
+		attributes += flags(object.GetXFlip(), object.GetYFlip(), object.GetPriority(), "B")
+		attributes = strings.ReplaceAll(strings.ReplaceAll(attributes, "XFLIP", "X"), "YFLIP", "Y")
+		text := fmt.Sprintf("%02d T%02X\n%3d,%3d\n%s", i, object.GetTileIndex(), int(object.GetX())-8,
+			int(object.GetY())-16, attributes)
+		v.canvas.Text(x+13, y+2+contentTop, text, infoColor, backgroundColor)
+	}
+
+	status := []string{"OBJECTS " + map[bool]string{false: "8X8", true: "8X16"}[tall], "DIMMED OBJECTS ARE OFF SCREEN"}
+	point, ok := v.contentHover()
+	if !ok || point.Y >= 40/objectColumns*objectHeight {
+		return status
+	}
+
+	i := point.Y/objectHeight*objectColumns + point.X/objectWidth
+	x, y := i%objectColumns*objectWidth, i/objectColumns*objectHeight
+	v.outline(image.Rect(x+1, y+1, x+objectWidth-1, y+objectHeight-1), hoverColor)
+
+	object := v.object(i)
+	details := fmt.Sprintf("ATTR %02X %s", object.GetAttributesFlag(), v.objectPalette(object).name)
+	if cgb {
+		details += fmt.Sprintf(" BANK %d", object.GetCGBVramBank())
+	}
+	details += flags(object.GetXFlip(), object.GetYFlip(), object.GetPriority(), "BEHIND BG")
+	return []string{fmt.Sprintf("OBJ %02d ADDR %04X X %02X Y %02X TILE %02X AT %04X", i,
+		bus.OamStart+uint16(i)*4, object.GetX(), object.GetY(), object.GetTileIndex(),
+		bus.VramStart+uint16(object.GetTileIndex())*16), details}
+}
+
+// paletteRow is where a palette is drawn in the palettes tab.
+type paletteRow struct {
+	palette palette
+	rect    image.Rectangle // In frame pixels, including the name
+}
+
+// paletteRows returns where the palettes are drawn: a column on DMG, BG and object palettes side by side on CGB.
+func (v *Viewer) paletteRows() []paletteRow {
+	var rows []paletteRow
+	for i, p := range v.palettes() {
+		x, y := 2, paletteRowTop+contentTop+i*paletteHeight
+		if i >= 8 {
+			x, y = Width/2+2, paletteRowTop+contentTop+(i-8)*paletteHeight
+		}
+		rows = append(rows, paletteRow{palette: p, rect: image.Rect(x, y, x+30+4*(swatchSize+2), y+swatchSize)})
+	}
+	return rows
+}
+
+// swatch returns the rectangle of a color of a palette row.
+func (r paletteRow) swatch(colorIndex int) image.Rectangle {
+	x := r.rect.Min.X + 30 + colorIndex*(swatchSize+2)
+	return image.Rect(x, r.rect.Min.Y, x+swatchSize, r.rect.Max.Y)
+}
+
+// drawPalettes draws the colors of every palette, the one the tiles are shown with is outlined.
+func (v *Viewer) drawPalettes() []string {
+	cgb := v.memory.IsCgbMode()
+	rows := v.paletteRows()
+
+	var hovered string
+	for i, row := range rows {
+		name := row.palette.name
+		if !cgb {
+			name += fmt.Sprintf("\n%02X", v.memory.Peek(row.palette.register))
+		}
+		v.canvas.Text(row.rect.Min.X+1, row.rect.Min.Y+1, name, textColor, backgroundColor)
+
+		for colorIndex := 0; colorIndex < 4; colorIndex++ {
+			swatch := row.swatch(colorIndex)
+			color := v.color(row.palette, byte(colorIndex))
+			v.canvas.Box(swatch.Min.X, swatch.Min.Y, swatch.Max.X-1, swatch.Max.Y-1, color, color)
+
+			if v.hovering && v.hover.In(swatch) {
+				hovered = fmt.Sprintf("%s COLOR %d", row.palette.name, colorIndex)
+				if cgb {
+					hovered += fmt.Sprintf(" RGB555 %04X", v.rawColor(row.palette, byte(colorIndex)))
+				} else {
+					shade := v.memory.Peek(row.palette.register) >> (colorIndex * 2) & 3
+					hovered += fmt.Sprintf(" SHADE %d", shade)
+				}
+				hovered += fmt.Sprintf(" #%06X", color&0xFFFFFF)
+			}
+		}
+
+		if i == v.palette {
+			first, last := row.swatch(0), row.swatch(3)
+			v.canvas.Box(first.Min.X-2, first.Min.Y-2, last.Max.X+1, last.Max.Y+1, 0, selectedColor)
+		}
+	}
+
+	status := "CLICK A PALETTE TO SHOW THE TILES WITH IT"
+	if hovered != "" {
+		status = hovered
+	}
+	if cgb {
+		return []string{status, fmt.Sprintf("BCPS %02X OCPS %02X", v.memory.Peek(bcpsRegisterAddr),
+			v.memory.Peek(ocpsRegisterAddr))}
+	}
+	return []string{status, fmt.Sprintf("BGP %02X OBP0 %02X OBP1 %02X", v.memory.Peek(bgpRegisterAddr),
+		v.memory.Peek(obp0RegisterAddr), v.memory.Peek(obp1RegisterAddr))}
+}
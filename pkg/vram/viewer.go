@@ -0,0 +1,294 @@
+// Package vram draws what is in video memory for debugging: the tile data of both banks, both tile maps with
+// the viewport, the objects in OAM and the palettes. It only draws frames, so any frontend can show them; the
+// SDL one has it in its debug window.
+package vram
+
+import (
+	"github.com/mikeletux/goboy/pkg/overlay"
+	"github.com/mikeletux/goboy/pkg/ppu"
+	"image"
+	"strings"
+)
+
+// Memory is what the viewer reads. Peek must not have side effects, *bus.Bus implements it.
+type Memory interface {
+	Peek(address uint16) byte
+	IsCgbMode() bool
+	ReadVRamBank(bank byte, address uint16) byte
+	ReadBgPaletteRam(index byte) byte
+	ReadObjPaletteRam(index byte) byte
+}
+
+// Tab is a page of the viewer.
+type Tab int
+
+const (
+	TabTiles Tab = iota
+	TabMap0      // Tile map at 0x9800
+	TabMap1      // Tile map at 0x9C00
+	TabOam
+	TabPalettes
+	tabCount
+)
+
+var tabNames = [tabCount]string{"TILES", "MAP 9800", "MAP 9C00", "OAM", "PALETTES"}
+
+func (t Tab) String() string {
+	return tabNames[t]
+}
+
+const (
+	lineHeight    = overlay.GlyphHeight + 1
+	tabBarHeight  = lineHeight + 4
+	contentTop    = tabBarHeight
+	contentHeight = 256
+	statusTop     = contentTop + contentHeight
+	statusLines   = 2
+)
+
+// Size of the frames drawn.
+const (
+	Width  = 256
+	Height = statusTop + statusLines*lineHeight + 3
+)
+
+const (
+	backgroundColor  uint32 = 0xFF202020
+	textColor        uint32 = 0xFFC0C0C0
+	dimTextColor     uint32 = 0xFF707070
+	selectedColor    uint32 = 0xFFFFFFFF
+	selectedTabColor uint32 = 0xFF505050
+	hoverColor       uint32 = 0xFFFF00FF
+	viewportColor    uint32 = 0xC0FF2020
+	windowColor      uint32 = 0xC02060FF
+)
+
+// Registers read by the viewer.
+const (
+	lcdcRegisterAddr uint16 = 0xFF40
+	scyRegisterAddr  uint16 = 0xFF42
+	scxRegisterAddr  uint16 = 0xFF43
+	bgpRegisterAddr  uint16 = 0xFF47
+	obp0RegisterAddr uint16 = 0xFF48
+	obp1RegisterAddr uint16 = 0xFF49
+	wyRegisterAddr   uint16 = 0xFF4A
+	wxRegisterAddr   uint16 = 0xFF4B
+	bcpsRegisterAddr uint16 = 0xFF68
+	ocpsRegisterAddr uint16 = 0xFF6A
+)
+
+// LCDC register bits used by the viewer.
+const (
+	lcdcObjSizeBit       byte = 1 << 2
+	lcdcBgTileMapBit     byte = 1 << 3
+	lcdcWindowEnableBit  byte = 1 << 5
+	lcdcWindowTileMapBit byte = 1 << 6
+)
+
+// Viewer draws the tabs of the VRAM viewer. It is driven by the mouse: clicking a tab shows it, clicking a
+// palette shows the tiles with it and hovering shows details of what is under the pointer.
+type Viewer struct {
+	memory   Memory
+	canvas   overlay.Canvas
+	tab      Tab
+	palette  int // Index in palettes of the palette the tiles are shown with
+	hover    image.Point
+	hovering bool
+	status   []string // Status lines of the last frame drawn
+}
+
+// New returns a viewer of the memory, showing the tiles.
+func New(memory Memory) *Viewer {
+	return &Viewer{
+		memory: memory,
+		canvas: overlay.Canvas{Pixels: make([]uint32, Width*Height), Width: Width, Height: Height},
+	}
+}
+
+// Tab returns the tab shown.
+func (v *Viewer) Tab() Tab {
+	return v.tab
+}
+
+// SetTab shows a tab.
+func (v *Viewer) SetTab(tab Tab) {
+	if tab >= 0 && tab < tabCount {
+		v.tab = tab
+	}
+}
+
+// NextPalette shows the tiles with the next palette, or the previous one if step is negative.
+func (v *Viewer) NextPalette(step int) {
+	count := len(v.palettes())
+	v.palette = ((v.palette+step)%count + count) % count
+}
+
+// Hover sets where the pointer is, in pixels of the frames drawn.
+func (v *Viewer) Hover(x, y int) {
+	v.hover, v.hovering = image.Pt(x, y), true
+}
+
+// Leave tells that the pointer left the viewer.
+func (v *Viewer) Leave() {
+	v.hovering = false
+}
+
+// Click handles a click, in pixels of the frames drawn.
+func (v *Viewer) Click(x, y int) {
+	point := image.Pt(x, y)
+	for tab, rect := range tabRects() {
+		if point.In(rect) {
+			v.tab = Tab(tab)
+			return
+		}
+	}
+
+	if v.tab == TabPalettes {
+		for i, row := range v.paletteRows() {
+			if point.In(row.rect) {
+				v.palette = i
+			}
+		}
+	}
+}
+
+// contentHover returns the pointer position relative to the content area, and whether it is inside.
+func (v *Viewer) contentHover() (image.Point, bool) {
+	point := v.hover.Sub(image.Pt(0, contentTop))
+	return point, v.hovering && point.In(image.Rect(0, 0, Width, contentHeight))
+}
+
+// Render draws the tab shown and returns the frame, Width x Height pixels stored row by row as 0xAARRGGBB. The
+// frame is reused by the next call.
+func (v *Viewer) Render() []uint32 {
+	for i := range v.canvas.Pixels {
+		v.canvas.Pixels[i] = backgroundColor
+	}
+
+	for tab, rect := range tabRects() {
+		color, background := textColor, backgroundColor
+		if Tab(tab) == v.tab {
+			color, background = selectedColor, selectedTabColor
+		}
+		v.canvas.Box(rect.Min.X, rect.Min.Y, rect.Max.X-1, rect.Max.Y-1, background, background)
+		v.canvas.Text(rect.Min.X+3, rect.Min.Y+2, Tab(tab).String(), color, background)
+	}
+
+	switch v.tab {
+	case TabTiles:
+		v.status = v.drawTiles()
+	case TabMap0:
+		v.status = v.drawMap(0x9800)
+	case TabMap1:
+		v.status = v.drawMap(0x9C00)
+	case TabOam:
+		v.status = v.drawOam()
+	case TabPalettes:
+		v.status = v.drawPalettes()
+	}
+
+	if len(v.status) > statusLines {
+		v.status = v.status[:statusLines]
+	}
+	v.canvas.Text(1, statusTop+2, strings.Join(v.status, "\n"), textColor, backgroundColor)
+	return v.canvas.Pixels
+}
+
+// tabRects returns where the tab names are drawn, in the order of the tabs.
+func tabRects() []image.Rectangle {
+	rects := make([]image.Rectangle, tabCount)
+	x := 0
+	for tab := Tab(0); tab < tabCount; tab++ {
+		width := overlay.TextWidth(tab.String()) + 6
+		rects[tab] = image.Rect(x, 0, x+width, tabBarHeight-1)
+		x += width + 1
+	}
+	return rects
+}
+
+// content returns the point of the content area at x, y.
+func content(x, y int) (int, int) {
+	return x, y + contentTop
+}
+
+// drawTile draws a tile of a VRAM bank with its top left corner at x, y of the content area. color returns
+// the color of each color index, colors with alpha 0 are not drawn.
+func (v *Viewer) drawTile(x, y int, bank byte, address uint16, xFlip, yFlip bool, color func(byte) uint32) {
+	for row := 0; row < 8; row++ {
+		tileRow := row
+		if yFlip {
+			tileRow = 7 - row
+		}
+		low := v.memory.ReadVRamBank(bank, address+uint16(tileRow)*2)
+		high := v.memory.ReadVRamBank(bank, address+uint16(tileRow)*2+1)
+
+		for column := 0; column < 8; column++ {
+			bit := 7 - column
+			if xFlip {
+				bit = column
+			}
+			colorIndex := (high>>bit&1)<<1 | low>>bit&1
+			v.canvas.Set(x+column, y+row+contentTop, color(colorIndex))
+		}
+	}
+}
+
+// outline draws the outline of a rectangle of the content area.
+func (v *Viewer) outline(rect image.Rectangle, color uint32) {
+	x, y := content(rect.Min.X, rect.Min.Y)
+	v.canvas.Box(x-1, y-1, x+rect.Dx(), y+rect.Dy(), 0, color)
+}
+
+// palette is a palette the tiles can be shown with.
+type palette struct {
+	name     string
+	object   bool
+	number   byte   // CGB palette number
+	register uint16 // DMG palette register
+}
+
+// palettes returns the palettes of the current mode: BGP, OBP0 and OBP1 on DMG, the 8 BG and 8 object palettes
+// on CGB.
+func (v *Viewer) palettes() []palette {
+	if !v.memory.IsCgbMode() {
+		return []palette{
+			{name: "BGP", register: bgpRegisterAddr},
+			{name: "OBP0", object: true, register: obp0RegisterAddr},
+			{name: "OBP1", object: true, register: obp1RegisterAddr},
+		}
+	}
+
+	var palettes []palette
+	for _, object := range []bool{false, true} {
+		for number := byte(0); number < 8; number++ {
+			name := "BG"
+			if object {
+				name = "OBJ"
+			}
+			palettes = append(palettes, palette{name: name + string('0'+number), object: object, number: number})
+		}
+	}
+	return palettes
+}
+
+// color returns the color of a color index through a palette.
+func (v *Viewer) color(p palette, colorIndex byte) uint32 {
+	switch {
+	case !v.memory.IsCgbMode():
+		return ppu.DmgColor(v.memory.Peek(p.register), colorIndex)
+	case p.object:
+		return ppu.CgbPaletteColor(v.memory.ReadObjPaletteRam, p.number, colorIndex)
+	default:
+		return ppu.CgbPaletteColor(v.memory.ReadBgPaletteRam, p.number, colorIndex)
+	}
+}
+
+// rawColor returns the RGB555 value of a color index of a CGB palette.
+func (v *Viewer) rawColor(p palette, colorIndex byte) uint16 {
+	read := v.memory.ReadBgPaletteRam
+	if p.object {
+		read = v.memory.ReadObjPaletteRam
+	}
+	index := p.number*8 + colorIndex*2
+	return uint16(read(index+1))<<8 | uint16(read(index))
+}
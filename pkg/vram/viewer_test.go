@@ -0,0 +1,175 @@
+package vram
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeMemory is the memory of a machine that hasn't run, with registers and VRAM set by the tests.
+type fakeMemory struct {
+	registers  map[uint16]byte
+	vram       [2][0x2000]byte
+	cgb        bool
+	bgPalette  [64]byte
+	objPalette [64]byte
+}
+
+func newFakeMemory() *fakeMemory {
+	return &fakeMemory{registers: map[uint16]byte{
+		lcdcRegisterAddr: 0x91,
+		bgpRegisterAddr:  0b11100100,
+		obp0RegisterAddr: 0b11100100,
+		obp1RegisterAddr: 0b00011011,
+	}}
+}
+
+func (m *fakeMemory) Peek(address uint16) byte {
+	if address >= 0x8000 && address < 0xA000 {
+		return m.vram[0][address-0x8000]
+	}
+	return m.registers[address]
+}
+
+func (m *fakeMemory) IsCgbMode() bool {
+	return m.cgb
+}
+
+func (m *fakeMemory) ReadVRamBank(bank byte, address uint16) byte {
+	return m.vram[bank][address-0x8000]
+}
+
+func (m *fakeMemory) ReadBgPaletteRam(index byte) byte {
+	return m.bgPalette[index]
+}
+
+func (m *fakeMemory) ReadObjPaletteRam(index byte) byte {
+	return m.objPalette[index]
+}
+
+// setTileRow sets a row of a tile to the color indexes given, from left to right.
+func (m *fakeMemory) setTileRow(bank byte, address uint16, row int, colors [8]byte) {
+	var low, high byte
+	for i, color := range colors {
+		low |= (color & 1) << (7 - i)
+		high |= (color >> 1) << (7 - i)
+	}
+	m.vram[bank][address-0x8000+uint16(row)*2] = low
+	m.vram[bank][address-0x8000+uint16(row)*2+1] = high
+}
+
+// pixel returns a pixel of the content area.
+func pixel(frame []uint32, x, y int) uint32 {
+	return frame[(y+contentTop)*Width+x]
+}
+
+// status returns the status lines of the frame drawn last.
+func status(v *Viewer) string {
+	return strings.Join(v.status, "\n")
+}
+
+func TestTiles(t *testing.T) {
+	memory := newFakeMemory()
+	memory.setTileRow(0, 0x8010, 0, [8]byte{0, 1, 2, 3, 0, 0, 0, 3}) // Tile 1
+	v := New(memory)
+
+	frame := v.Render()
+	for x, expected := range []uint32{0xFFFFFFFF, 0xFFAAAAAA, 0xFF555555, 0xFF000000} {
+		if got := pixel(frame, 8+x, 0); got != expected {
+			t.Errorf("expected %08X at x %d got %08X", expected, 8+x, got)
+		}
+	}
+
+	v.NextPalette(2) // OBP1 reverses the shades
+	frame = v.Render()
+	if got := pixel(frame, 8, 0); got != 0xFF000000 {
+		t.Errorf("expected color 0 to be black with OBP1 got %08X", got)
+	}
+	v.NextPalette(1)
+	if v.palette != 0 {
+		t.Errorf("expected the palettes to wrap around got %d", v.palette)
+	}
+
+	v.Hover(8*3+2, contentTop+8*17+2)
+	v.Render()
+	if got := status(v); !strings.Contains(got, "TILE 113 BANK 0 ADDR 9130 INDEX 13") {
+		t.Errorf("unexpected status %q", got)
+	}
+}
+
+func TestMap(t *testing.T) {
+	memory := newFakeMemory()
+	memory.registers[scxRegisterAddr] = 200
+	memory.registers[scyRegisterAddr] = 8
+	memory.vram[0][0x9800-0x8000+33] = 2
+	memory.setTileRow(0, 0x8020, 0, [8]byte{3, 3, 3, 3, 3, 3, 3, 3})
+	v := New(memory)
+	v.SetTab(TabMap0)
+
+	frame := v.Render()
+	if got := pixel(frame, 8, 8+1); got != 0xFFFFFFFF {
+		t.Errorf("expected tile 2 at 1, 1 got %08X", got)
+	}
+	if got := pixel(frame, 12, 8); got == 0xFF000000 || got == 0xFFFFFFFF {
+		t.Errorf("expected the viewport over the tile got %08X", got)
+	}
+	if pixel(frame, 103, 100) == 0xFFFFFFFF || pixel(frame, 100, 100) != 0xFFFFFFFF {
+		t.Error("expected the right edge of the viewport to wrap around the map")
+	}
+
+	v.Hover(9, contentTop+9)
+	v.Render()
+	if got := status(v); !strings.HasPrefix(got, "X 01 Y 01 ADDR 9821 TILE 02 AT 8020") {
+		t.Errorf("unexpected status %q", got)
+	}
+
+	v.Leave()
+	v.SetTab(TabMap1)
+	v.Render()
+	if got := status(v); !strings.Contains(got, "USED BY NOTHING") {
+		t.Errorf("expected map 9C00 to be unused got %q", got)
+	}
+}
+
+func TestOam(t *testing.T) {
+	memory := newFakeMemory()
+	memory.registers[0xFE04] = 0x20 // Object 1 Y
+	memory.registers[0xFE05] = 0x18 // X
+	memory.registers[0xFE06] = 0x05 // Tile
+	memory.registers[0xFE07] = 0b10110000
+	v := New(memory)
+	v.SetTab(TabOam)
+
+	v.Hover(objectWidth+5, contentTop+5)
+	v.Render()
+	expected := "OBJ 01 ADDR FE04 X 18 Y 20 TILE 05 AT 8050\nATTR B0 OBP1 XFLIP BEHIND BG"
+	if got := status(v); got != expected {
+		t.Errorf("expected status %q got %q", expected, got)
+	}
+}
+
+func TestPalettes(t *testing.T) {
+	memory := newFakeMemory()
+	memory.cgb = true
+	memory.objPalette[3*8+2], memory.objPalette[3*8+3] = 0x1F, 0x00 // OBJ3 color 1 is red
+	v := New(memory)
+
+	v.Click(tabRects()[TabPalettes].Min.X+1, 1)
+	if v.Tab() != TabPalettes {
+		t.Fatalf("expected a click on the tab to show it got %s", v.Tab())
+	}
+
+	row := v.paletteRows()[8+3]
+	swatch := row.swatch(1)
+	v.Hover(swatch.Min.X+1, swatch.Min.Y+1)
+	v.Click(swatch.Min.X+1, swatch.Min.Y+1)
+	frame := v.Render()
+	if v.palette != 8+3 {
+		t.Errorf("expected a click to select OBJ3 got palette %d", v.palette)
+	}
+	if got := frame[(swatch.Min.Y+1)*Width+swatch.Min.X+1]; got != 0xFFFF0000 {
+		t.Errorf("expected a red swatch got %08X", got)
+	}
+	if got := status(v); !strings.HasPrefix(got, "OBJ3 COLOR 1 RGB555 001F #FF0000") {
+		t.Errorf("unexpected status %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import "encoding/binary"
+
+// Messages sent to the browser start with their type. Numbers are little endian.
+const (
+	// frameMessage is followed by the width and height as uint16 and the pixels that changed since the previous
+	// frame sent, as runs of: uint16 pixels to skip, uint16 pixels to copy and those pixels as RGB bytes.
+	frameMessage byte = 0x01
+	// audioMessage is followed by int16 stereo samples, interleaved left and right.
+	audioMessage byte = 0x02
+)
+
+// maxRun is the longest run of pixels that fits in a uint16.
+const maxRun = 0xFFFF
+
+// encodeFrame returns a frame message with the pixels that differ from previous, or every pixel if previous is
+// nil or has another size. It returns nil if nothing changed.
+func encodeFrame(frame, previous []uint32, width, height int) []byte {
+	if len(previous) != len(frame) {
+		previous = nil
+	}
+
+	message := []byte{frameMessage}
+	message = binary.LittleEndian.AppendUint16(message, uint16(width))
+	message = binary.LittleEndian.AppendUint16(message, uint16(height))
+	header := len(message)
+
+	for i := 0; i < len(frame); {
+		skip := 0
+		for previous != nil && i < len(frame) && frame[i] == previous[i] && skip < maxRun {
+			i++
+			skip++
+		}
+
+		start := i
+		for i < len(frame) && (previous == nil || frame[i] != previous[i]) && i-start < maxRun {
+			i++
+		}
+		if i == start && i == len(frame) {
+			break // Only unchanged pixels left
+		}
+
+		message = binary.LittleEndian.AppendUint16(message, uint16(skip))
+		message = binary.LittleEndian.AppendUint16(message, uint16(i-start))
+		for _, pixel := range frame[start:i] {
+			message = append(message, byte(pixel>>16), byte(pixel>>8), byte(pixel))
+		}
+	}
+
+	if previous != nil && len(message) == header {
+		return nil
+	}
+	return message
+}
+
+// encodeAudio returns an audio message with the samples.
+func encodeAudio(samples []int16) []byte {
+	message := make([]byte, 1, 1+2*len(samples))
+	message[0] = audioMessage
+	for _, sample := range samples {
+		message = binary.LittleEndian.AppendUint16(message, uint16(sample))
+	}
+	return message
+}
@@ -0,0 +1,289 @@
+// Package web is a frontend that serves the emulator to browsers. A small page draws the frames and plays the
+// sound streamed over a WebSocket, and sends the buttons pressed back. Every browser connected sees the same
+// game and can press buttons.
+package web
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/capture"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"net"
+	"net/http"
+	"sync"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+// sendQueueSize is how many messages can be waiting for a slow client. Once full, new frames and samples are
+// dropped for that client.
+const sendQueueSize = 8
+
+// hello is the first message sent to a client, as JSON text.
+type hello struct {
+	SampleRate int `json:"sampleRate"`
+}
+
+// inputEvent is sent by the page when a button or a hotkey is pressed or released.
+type inputEvent struct {
+	Button  string `json:"button,omitempty"`
+	Hotkey  string `json:"hotkey,omitempty"` // pause, advance, faster, slower, fastforward, screenshot or record
+	Pressed bool   `json:"pressed"`
+}
+
+// Server is the web frontend. It implements frontend.Frontend.
+type Server struct {
+	listener   net.Listener
+	http       *http.Server
+	sampleRate int
+
+	mutex   sync.Mutex
+	clients map[*client]struct{}
+	frame   []uint32 // Last frame drawn, served as PNG at /screen.png
+	width   int
+	height  int
+	hotkeys frontend.Hotkeys // Pressed by any client since the last call to Hotkeys
+}
+
+// client is a browser connected through a WebSocket.
+type client struct {
+	conn        *websocketConn
+	send        chan []byte
+	buttons     goboy.Buttons
+	fastForward bool
+	previous    []uint32 // Last frame sent, the next one is encoded as the difference
+}
+
+// New listens on the given address, like "localhost:8080", and serves the page there. sampleRate is the rate of the
+// samples passed to Play.
+func New(address string, sampleRate int) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:   listener,
+		sampleRate: sampleRate,
+		clients:    make(map[*client]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveIndex)
+	mux.HandleFunc("/screen.png", s.serveScreen)
+	mux.HandleFunc("/ws", s.serveWebsocket)
+	s.http = &http.Server{Handler: mux}
+
+	go s.http.Serve(listener)
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+// serveScreen returns the last frame drawn as a PNG image.
+func (s *Server) serveScreen(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	frame, width, height := s.frame, s.width, s.height
+	s.mutex.Unlock()
+
+	if frame == nil {
+		http.Error(w, "no frame drawn yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	capture.WritePNG(w, frame, width, height, 1)
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	c := &client{conn: conn, send: make(chan []byte, sendQueueSize)}
+	greeting, _ := json.Marshal(hello{SampleRate: s.sampleRate})
+	if err := conn.writeMessage(opText, greeting); err != nil {
+		conn.close()
+		return
+	}
+
+	s.mutex.Lock()
+	s.clients[c] = struct{}{}
+	s.mutex.Unlock()
+
+	go s.writeMessages(c)
+	s.readEvents(c)
+}
+
+// writeMessages sends the queued messages to a client until its queue is closed.
+func (s *Server) writeMessages(c *client) {
+	for message := range c.send {
+		if err := c.conn.writeMessage(opBinary, message); err != nil {
+			c.conn.close()
+		}
+	}
+	c.conn.writeMessage(opClose, nil)
+	c.conn.close()
+}
+
+// readEvents reads the input events of a client until it disconnects.
+func (s *Server) readEvents(c *client) {
+	defer s.removeClient(c)
+
+	for {
+		opcode, message, err := c.conn.readMessage()
+		if err != nil {
+			return
+		}
+
+		var event inputEvent
+		if opcode != opText || json.Unmarshal(message, &event) != nil {
+			continue
+		}
+
+		s.mutex.Lock()
+		if event.Hotkey != "" {
+			s.handleHotkey(c, event)
+		} else if button, err := goboy.ParseButtons(event.Button); err == nil {
+			if event.Pressed {
+				c.buttons |= button
+			} else {
+				c.buttons &^= button
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// handleHotkey latches a hotkey until the next call to Hotkeys. Fast-forward lasts whilst it is held. It must
+// be called with the mutex held.
+func (s *Server) handleHotkey(c *client, event inputEvent) {
+	if event.Hotkey == "fastforward" {
+		c.fastForward = event.Pressed
+		return
+	}
+	if !event.Pressed {
+		return
+	}
+
+	switch event.Hotkey {
+	case "pause":
+		s.hotkeys.Pause = true
+	case "advance":
+		s.hotkeys.FrameAdvance = true
+	case "faster":
+		s.hotkeys.SpeedUp = true
+	case "slower":
+		s.hotkeys.SpeedDown = true
+	case "screenshot":
+		s.hotkeys.Screenshot = true
+	case "record":
+		s.hotkeys.Record = true
+	}
+}
+
+func (s *Server) removeClient(c *client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
+
+// queue sends a message to a client unless its queue is full. It must be called with the mutex held.
+func (c *client) queue(message []byte) bool {
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// Draw sends each client the pixels that changed since the last frame it got.
+func (s *Server) Draw(frame []uint32, width, height int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.frame, s.width, s.height = frame, width, height
+	for c := range s.clients {
+		message := encodeFrame(frame, c.previous, width, height)
+		if message != nil && c.queue(message) {
+			c.previous = frame
+		}
+	}
+}
+
+// Play sends the samples to every client.
+func (s *Server) Play(samples []int16) {
+	if len(samples) == 0 {
+		return
+	}
+	message := encodeAudio(samples)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.clients {
+		c.queue(message)
+	}
+}
+
+// Poll returns the buttons pressed by any client. Closing the page doesn't stop the emulator.
+func (s *Server) Poll() (goboy.Buttons, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var buttons goboy.Buttons
+	for c := range s.clients {
+		buttons |= c.buttons
+	}
+	return buttons, false
+}
+
+// Hotkeys returns the hotkeys pressed by any client since the last call.
+func (s *Server) Hotkeys() frontend.Hotkeys {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hotkeys := s.hotkeys
+	s.hotkeys = frontend.Hotkeys{}
+	for c := range s.clients {
+		hotkeys.FastForward = hotkeys.FastForward || c.fastForward
+	}
+	return hotkeys
+}
+
+// Close disconnects the clients and stops the server.
+func (s *Server) Close() error {
+	err := s.http.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.clients {
+		delete(s.clients, c)
+		close(c.send)
+	}
+	return err
+}
@@ -0,0 +1,249 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/mikeletux/goboy"
+	"github.com/mikeletux/goboy/pkg/frontend"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is a minimal WebSocket client.
+type testClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// handshake sends a WebSocket handshake from a page of the given origin, none if empty.
+func handshake(t *testing.T, s *Server, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n", s.Addr(), key)
+	if len(origin) > 0 {
+		fmt.Fprintf(conn, "Origin: %s\r\n", origin)
+	}
+	fmt.Fprint(conn, "\r\n")
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, response
+}
+
+func dial(t *testing.T, s *Server) *testClient {
+	conn, reader, response := handshake(t, s, "http://"+s.Addr().String())
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected status 101 got %d", response.StatusCode)
+	}
+	if accept := response.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected accept key %q", accept)
+	}
+	return &testClient{conn: conn, reader: reader}
+}
+
+func (c *testClient) read(t *testing.T) (byte, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		io.ReadFull(c.reader, extended[:])
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		io.ReadFull(c.reader, extended[:])
+		length = int(binary.BigEndian.Uint64(extended[:]))
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		t.Fatal(err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+// write sends a masked text frame, as browsers do.
+func (c *testClient) write(t *testing.T, text string) {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{finBit | opText, maskBit | byte(len(text))}
+	frame = append(frame, mask[:]...)
+	for i := range text {
+		frame = append(frame, text[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestServer(t *testing.T) *Server {
+	s, err := New("127.0.0.1:0", 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// waitClients waits until the server has registered n clients.
+func waitClients(t *testing.T, s *Server, n int) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		s.mutex.Lock()
+		count := len(s.clients)
+		s.mutex.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d clients", n)
+}
+
+func TestEncodeFrame(t *testing.T) {
+	previous := []uint32{1, 2, 3, 4, 5, 6}
+	frame := []uint32{1, 2, 0xFF102030, 4, 5, 6}
+
+	message := encodeFrame(frame, previous, 3, 2)
+	expected := []byte{frameMessage, 3, 0, 2, 0, 2, 0, 1, 0, 0x10, 0x20, 0x30}
+	if string(message) != string(expected) {
+		t.Errorf("expected % X got % X", expected, message)
+	}
+
+	if message := encodeFrame(frame, frame, 3, 2); message != nil {
+		t.Errorf("expected no message for the same frame got % X", message)
+	}
+
+	if message := encodeFrame(frame, nil, 3, 2); len(message) != 5+4+6*3 {
+		t.Errorf("expected every pixel in a single run got % X", message)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	s := newTestServer(t)
+
+	response, err := http.Get("http://" + s.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || !strings.Contains(string(body), "/ws") {
+		t.Errorf("expected the page got status %d", response.StatusCode)
+	}
+}
+
+func TestScreen(t *testing.T) {
+	s := newTestServer(t)
+	s.Draw([]uint32{0xFF000000, 0xFFFFFFFF}, 2, 1)
+
+	response, err := http.Get("http://" + s.Addr().String() + "/screen.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	img, err := png.Decode(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := img.At(1, 0).RGBA(); img.Bounds().Dx() != 2 || r != 0xFFFF {
+		t.Errorf("unexpected image %v", img.Bounds())
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s := newTestServer(t)
+
+	conn, _, response := handshake(t, s, "http://evil.example")
+	conn.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status 403 for another origin got %d", response.StatusCode)
+	}
+
+	conn, _, response = handshake(t, s, "")
+	conn.Close()
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected status 101 without origin got %d", response.StatusCode)
+	}
+}
+
+func TestStream(t *testing.T) {
+	s := newTestServer(t)
+	c := dial(t, s)
+	defer c.conn.Close()
+
+	opcode, message := c.read(t)
+	var greeting hello
+	if opcode != opText || json.Unmarshal(message, &greeting) != nil || greeting.SampleRate != 48000 {
+		t.Fatalf("unexpected hello %q", message)
+	}
+	waitClients(t, s, 1)
+
+	s.Draw([]uint32{1, 2, 3, 4}, 2, 2)
+	s.Draw([]uint32{1, 2, 3, 4}, 2, 2) // Unchanged, not sent
+	s.Play([]int16{-1, 1})
+
+	if opcode, message := c.read(t); opcode != opBinary || message[0] != frameMessage || len(message) != 5+4+4*3 {
+		t.Errorf("expected a whole frame got % X", message)
+	}
+	if _, message := c.read(t); message[0] != audioMessage || len(message) != 5 {
+		t.Errorf("expected 2 samples got % X", message)
+	}
+
+	c.write(t, `{"button":"start","pressed":true}`)
+	c.write(t, `{"button":"a","pressed":true}`)
+	c.write(t, `{"button":"a","pressed":false}`)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		buttons, quit := s.Poll()
+		if quit {
+			t.Fatal("expected the server not to quit")
+		}
+		if buttons == goboy.ButtonStart {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected Start to be pressed got %08b", buttons)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.write(t, `{"hotkey":"fastforward","pressed":true}`)
+	c.write(t, `{"hotkey":"pause","pressed":true}`)
+	var hotkeys frontend.Hotkeys
+	for deadline := time.Now().Add(5 * time.Second); !hotkeys.Pause && time.Now().Before(deadline); {
+		hotkeys = s.Hotkeys()
+		time.Sleep(time.Millisecond)
+	}
+	if !hotkeys.Pause || !hotkeys.FastForward {
+		t.Errorf("expected pause and fast-forward got %+v", hotkeys)
+	}
+	if hotkeys = s.Hotkeys(); hotkeys.Pause || !hotkeys.FastForward {
+		t.Errorf("expected only fast-forward to be held got %+v", hotkeys)
+	}
+
+	c.conn.Close()
+	waitClients(t, s, 0)
+	if buttons, _ := s.Poll(); buttons != 0 {
+		t.Errorf("expected the buttons to be released when the client leaves got %08b", buttons)
+	}
+}
@@ -0,0 +1,234 @@
+package web
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// websocketGUID is appended to the client key to compute the handshake accept key (RFC 6455 section 4.2.2).
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	opContinuation byte = 0x0
+	opText         byte = 0x1
+	opBinary       byte = 0x2
+	opClose        byte = 0x8
+	opPing         byte = 0x9
+	opPong         byte = 0xA
+)
+
+const (
+	finBit  byte = 0x80
+	maskBit byte = 0x80
+
+	// maxMessageSize limits the messages received. Clients only send small button events.
+	maxMessageSize = 64 * 1024
+)
+
+var errMessageTooBig = errors.New("websocket message too big")
+
+// websocketConn is the server side of a WebSocket connection. Only what the frontend needs is implemented:
+// messages can be written from several goroutines but must be read from a single one.
+type websocketConn struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	writeMutex sync.Mutex
+}
+
+// acceptKey returns the Sec-WebSocket-Accept value for a client key.
+func acceptKey(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// upgrade completes the WebSocket handshake of an HTTP request and takes over its connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*websocketConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") ||
+		len(key) == 0 {
+		http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)
+		return nil, fmt.Errorf("request is not a WebSocket handshake")
+	}
+
+	if !sameOrigin(r) {
+		http.Error(w, "cross origin WebSocket handshakes are not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("WebSocket handshake from origin %q rejected", r.Header.Get("Origin"))
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection can't be upgraded", http.StatusInternalServerError)
+		return nil, fmt.Errorf("response doesn't support hijacking")
+	}
+
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(buffered, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := buffered.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &websocketConn{conn: conn, reader: buffered.Reader}, nil
+}
+
+// sameOrigin returns whether the handshake comes from a page served by this server. Browsers always send the
+// origin, so other sites can't drive the game from the visitor's browser. Clients that are not browsers don't
+// send it and are allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains returns whether a comma separated header has the given token, ignoring case.
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeMessage writes a whole message in a single frame. Server frames are not masked.
+func (c *websocketConn) writeMessage(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	header := make([]byte, 2, 10)
+	header[0] = finBit | opcode
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := c.conn.Write(header); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+// readMessage returns the next text or binary message, joining fragmented ones. Pings are answered and a close
+// frame returns io.EOF.
+func (c *websocketConn) readMessage() (byte, []byte, error) {
+	var opcode byte
+	var message []byte
+
+	for {
+		fin, frameOpcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case opPing:
+			if err := c.writeMessage(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeMessage(opClose, nil)
+			return 0, nil, io.EOF
+		case opText, opBinary:
+			opcode = frameOpcode
+			message = nil
+		case opContinuation:
+		default:
+			return 0, nil, fmt.Errorf("unknown websocket opcode %X", frameOpcode)
+		}
+
+		if len(message)+len(payload) > maxMessageSize {
+			return 0, nil, errMessageTooBig
+		}
+		message = append(message, payload...)
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *websocketConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(c.reader, header[:]); err != nil {
+		return
+	}
+	fin = header[0]&finBit != 0
+	opcode = header[0] & 0x0F
+
+	length := uint64(header[1] &^ maskBit)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err = io.ReadFull(c.reader, extended[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if length > maxMessageSize {
+		err = errMessageTooBig
+		return
+	}
+
+	var mask [4]byte
+	masked := header[1]&maskBit != 0
+	if masked {
+		if _, err = io.ReadFull(c.reader, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.reader, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+func (c *websocketConn) close() error {
+	return c.conn.Close()
+}